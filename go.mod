@@ -5,6 +5,7 @@ go 1.23.0
 toolchain go1.23.11
 
 require (
+	github.com/google/pprof v0.0.0-20250403155104-27863c87afa6
 	golang.org/x/mod v0.26.0
 	golang.org/x/tools v0.35.0
 )
@@ -1,7 +1,453 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Silhouette-sophist/static_parser/service"
+)
 
 func main() {
-	fmt.Println("static parser")
+	if len(os.Args) < 2 {
+		fmt.Println("static parser")
+		return
+	}
+	var err error
+	switch os.Args[1] {
+	case "mockgen":
+		err = runMockgen(os.Args[2:])
+	case "docgen":
+		err = runDocgen(os.Args[2:])
+	case "symbolize":
+		err = runSymbolize(os.Args[2:])
+	case "routes":
+		err = runRoutes(os.Args[2:])
+	case "rpc":
+		err = runRPC(os.Args[2:])
+	case "config":
+		err = runConfig(os.Args[2:])
+	case "sql":
+		err = runSQL(os.Args[2:])
+	case "errors":
+		err = runErrors(os.Args[2:])
+	case "concurrency":
+		err = runConcurrency(os.Args[2:])
+	case "vet":
+		err = runVet(os.Args[2:])
+	default:
+		err = fmt.Errorf("未知的子命令: %s", os.Args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// runMockgen 生成mock或fake，可通过 //go:generate go run github.com/Silhouette-sophist/static_parser mockgen -out mock_xxx.go 调用
+func runMockgen(args []string) error {
+	flagSet := flag.NewFlagSet("mockgen", flag.ExitOnError)
+	dir := flagSet.String("dir", ".", "加载包的工作目录")
+	pkg := flagSet.String("pkg", ".", "接口所在的包")
+	ifaces := flagSet.String("iface", "", "逗号分隔的接口名，为空时生成所有导出接口")
+	style := flagSet.String("style", string(service.MockStyleGomock), "生成风格: gomock或fake")
+	out := flagSet.String("out", "", "输出文件，为空时输出到标准输出")
+	outPkg := flagSet.String("out_pkg", os.Getenv("GOPACKAGE"), "输出文件的包名，默认与接口所在包相同")
+	outPkgPath := flagSet.String("out_pkg_path", "", "输出文件所在包的导入路径")
+	gomockImport := flagSet.String("gomock", "", "gomock的导入路径")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	cfg := &service.MockConfig{
+		Dir:          *dir,
+		Package:      *pkg,
+		Style:        service.MockStyle(*style),
+		OutPackage:   *outPkg,
+		OutPkgPath:   *outPkgPath,
+		GomockImport: *gomockImport,
+		Source:       "static_parser mockgen " + strings.Join(args, " "),
+	}
+	if *ifaces != "" {
+		cfg.Interfaces = strings.Split(*ifaces, ",")
+	}
+	content, err := service.GenerateMocks(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return os.WriteFile(*out, content, 0o644)
+}
+
+// runDocgen 将模块渲染为静态文档站点
+func runDocgen(args []string) error {
+	flagSet := flag.NewFlagSet("docgen", flag.ExitOnError)
+	dir := flagSet.String("dir", ".", "模块目录")
+	out := flagSet.String("out", "docs", "输出目录")
+	format := flagSet.String("format", service.DocFormatMarkdown, "输出格式: markdown或html")
+	sourceURL := flagSet.String("source_url", "", "源码链接模板，例如 https://git.example.com/repo/blob/main/{file}#L{line}")
+	unexported := flagSet.Bool("unexported", false, "是否包含未导出的标识符")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	modInfo, err := service.ParseModule(*dir)
+	if err != nil {
+		return err
+	}
+	graph, err := service.BuildImportGraph([]*service.ModuleInfo{modInfo})
+	if err != nil {
+		return err
+	}
+	pages, err := service.BuildDocSite(modInfo, graph, &service.DocSiteConfig{
+		OutDir:     *out,
+		Format:     *format,
+		SourceURL:  *sourceURL,
+		Unexported: *unexported,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("生成 %d 个文档页面到 %s\n", len(pages), *out)
+	return nil
+}
+
+// runSymbolize 符号化panic堆栈或日志，默认从标准输入读取，指定-http时启动HTTP服务供粘贴堆栈
+func runSymbolize(args []string) error {
+	flagSet := flag.NewFlagSet("symbolize", flag.ExitOnError)
+	dir := flagSet.String("dir", ".", "模块目录")
+	file := flagSet.String("file", "", "堆栈文件，为空时从标准输入读取")
+	sourceURL := flagSet.String("source_url", "", "源码链接模板，例如 https://git.example.com/repo/blob/main/{file}#L{line}")
+	contextLines := flagSet.Int("context", service.DefaultTraceContextLines, "源码片段上下各保留的行数，小于0时不输出")
+	jsonOutput := flagSet.Bool("json", false, "以JSON格式输出")
+	addr := flagSet.String("http", "", "HTTP服务监听地址，例如 :8080")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	modInfo, err := service.ParseModule(*dir)
+	if err != nil {
+		return err
+	}
+	symbolizer, err := service.NewTraceSymbolizer(modInfo, &service.TraceConfig{
+		SourceURL:    *sourceURL,
+		ContextLines: *contextLines,
+	})
+	if err != nil {
+		return err
+	}
+	if *addr != "" {
+		fmt.Printf("监听 %s，POST堆栈文本到 /symbolize\n", *addr)
+		mux := http.NewServeMux()
+		mux.Handle("/symbolize", symbolizer)
+		return http.ListenAndServe(*addr, mux)
+	}
+	var content []byte
+	if *file != "" {
+		content, err = os.ReadFile(*file)
+	} else {
+		content, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+	trace := service.ParseStackTrace(string(content))
+	symbolizer.Symbolize(trace)
+	if *jsonOutput {
+		return writeJSON(trace)
+	}
+	return service.WriteStackTraceText(os.Stdout, trace)
+}
+
+// runRoutes 输出模块中注册的HTTP路由，指定-openapi时同时生成OpenAPI文档骨架
+func runRoutes(args []string) error {
+	flagSet := flag.NewFlagSet("routes", flag.ExitOnError)
+	dir := flagSet.String("dir", ".", "模块目录")
+	jsonOutput := flagSet.Bool("json", false, "以JSON格式输出")
+	openAPIOut := flagSet.String("openapi", "", "OpenAPI文档输出文件")
+	version := flagSet.String("version", "0.0.1", "OpenAPI文档的版本")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	modInfo, err := service.ParseModule(*dir)
+	if err != nil {
+		return err
+	}
+	routes, err := service.DiscoverRoutes(modInfo)
+	if err != nil {
+		return err
+	}
+	if *openAPIOut != "" {
+		content, err := json.MarshalIndent(service.BuildOpenAPISkeleton(routes, modInfo.Path, *version), "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*openAPIOut, append(content, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	if *jsonOutput {
+		return writeJSON(routes)
+	}
+	for _, route := range routes {
+		handler := route.Handler
+		if route.HandlerInfo != nil {
+			handler = route.HandlerPkg + "." + route.HandlerFunc
+		}
+		fmt.Printf("%-7s %s -> %s (%s:%d)", route.Method, route.Path, handler, route.RFilePath, route.Line)
+		if len(route.Middlewares) > 0 {
+			fmt.Printf(" [%s]", strings.Join(route.Middlewares, ", "))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// runRPC 输出模块中的RPC服务及方法的实现，指定-method时只输出该方法
+func runRPC(args []string) error {
+	flagSet := flag.NewFlagSet("rpc", flag.ExitOnError)
+	dir := flagSet.String("dir", ".", "模块目录")
+	jsonOutput := flagSet.Bool("json", false, "以JSON格式输出")
+	methodName := flagSet.String("method", "", "RPC方法，如/helloworld.Greeter/SayHello或Greeter.SayHello")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	modInfo, err := service.ParseModule(*dir)
+	if err != nil {
+		return err
+	}
+	services, err := service.DiscoverRPCServices(modInfo)
+	if err != nil {
+		return err
+	}
+	if *methodName != "" {
+		rpcService, method := service.FindRPCMethod(services, *methodName)
+		if method == nil {
+			return fmt.Errorf("未找到RPC方法: %s", *methodName)
+		}
+		services = []*service.RPCService{{
+			Framework:     rpcService.Framework,
+			Name:          rpcService.Name,
+			Interface:     rpcService.Interface,
+			Methods:       []*service.RPCMethod{method},
+			Registrations: rpcService.Registrations,
+		}}
+	}
+	if *jsonOutput {
+		return writeJSON(services)
+	}
+	for _, rpcService := range services {
+		fmt.Printf("%s %s (%s)\n", rpcService.Framework, rpcService.Name, rpcService.Interface)
+		for _, method := range rpcService.Methods {
+			fmt.Printf("  %s(%s) %s", method.FullName, method.Request, method.Response)
+			if method.Stream != service.RPCStreamNone {
+				fmt.Printf(" [%s stream]", method.Stream)
+			}
+			fmt.Println()
+			for _, impl := range method.Impls {
+				position := ""
+				if impl.FuncInfo.StartPosition != nil {
+					position = fmt.Sprintf(" (%s:%d)", impl.FuncInfo.RFilePath, impl.FuncInfo.StartPosition.Line)
+				}
+				fmt.Printf("    -> %s%s\n", impl.Func, position)
+			}
+		}
+	}
+	return nil
+}
+
+// runConfig 按可执行程序输出读取的环境变量、flag和viper配置
+func runConfig(args []string) error {
+	flagSet := flag.NewFlagSet("config", flag.ExitOnError)
+	dir := flagSet.String("dir", ".", "模块目录")
+	jsonOutput := flagSet.Bool("json", false, "以JSON格式输出")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	modInfo, err := service.ParseModule(*dir)
+	if err != nil {
+		return err
+	}
+	graph, err := service.BuildImportGraph([]*service.ModuleInfo{modInfo})
+	if err != nil {
+		return err
+	}
+	inventory, err := service.BuildConfigInventory(modInfo, graph)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		return writeJSON(inventory)
+	}
+	for _, binary := range inventory.Binaries {
+		fmt.Println(binary.Pkg)
+		for _, line := range service.ConfigKeyLines(binary.Inputs) {
+			fmt.Println("  " + line)
+		}
+	}
+	return nil
+}
+
+// runSQL 输出模块中的SQL，指定-tables时按表汇总使用的函数
+func runSQL(args []string) error {
+	flagSet := flag.NewFlagSet("sql", flag.ExitOnError)
+	dir := flagSet.String("dir", ".", "模块目录")
+	jsonOutput := flagSet.Bool("json", false, "以JSON格式输出")
+	byTable := flagSet.Bool("tables", false, "按表汇总")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	modInfo, err := service.ParseModule(*dir)
+	if err != nil {
+		return err
+	}
+	queries, err := service.ExtractSQLQueries(modInfo)
+	if err != nil {
+		return err
+	}
+	var result any = queries
+	if *byTable {
+		result = service.BuildTableUsage(queries)
+	}
+	if *jsonOutput {
+		return writeJSON(result)
+	}
+	if *byTable {
+		for _, usage := range result.([]*service.TableUsage) {
+			fmt.Printf("%s [%s]\n", usage.Table, strings.Join(usage.Statements, ", "))
+			for _, funcName := range usage.Funcs {
+				fmt.Printf("  %s\n", funcName)
+			}
+		}
+		return nil
+	}
+	for _, query := range queries {
+		fmt.Printf("%s:%d %s %s %s\n", query.RFilePath, query.Line, query.Statement, strings.Join(query.Tables, ","), query.SQL)
+	}
+	return nil
+}
+
+// runErrors 输出错误处理问题以及每个函数可能返回的哨兵错误，-kind 只输出指定类型的问题
+func runErrors(args []string) error {
+	flagSet := flag.NewFlagSet("errors", flag.ExitOnError)
+	dir := flagSet.String("dir", ".", "模块目录")
+	jsonOutput := flagSet.Bool("json", false, "以JSON格式输出")
+	kind := flagSet.String("kind", "", "问题类型：ignored、blank、unwrapped、errorf_no_wrap")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	modInfo, err := service.ParseModule(*dir)
+	if err != nil {
+		return err
+	}
+	report, err := service.AnalyzeErrorFlow(context.Background(), modInfo)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		return writeJSON(report)
+	}
+	for _, flow := range report.Funcs {
+		if *kind == "" && len(flow.Returns) > 0 {
+			fmt.Printf("%s.%s returns %s\n", flow.Pkg, flow.Func, strings.Join(flow.Returns, ", "))
+		}
+		for _, finding := range flow.Findings {
+			if *kind != "" && finding.Kind != *kind {
+				continue
+			}
+			fmt.Printf("%s:%d:%d %s %s %s\n", finding.RFilePath, finding.Line, finding.Column, finding.Kind, finding.Call, finding.Message)
+		}
+	}
+	return nil
+}
+
+// runConcurrency 输出每个函数的并发操作，-warnings 只输出检查出的问题
+func runConcurrency(args []string) error {
+	flagSet := flag.NewFlagSet("concurrency", flag.ExitOnError)
+	dir := flagSet.String("dir", ".", "模块目录")
+	jsonOutput := flagSet.Bool("json", false, "以JSON格式输出")
+	warningsOnly := flagSet.Bool("warnings", false, "只输出问题")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	modInfo, err := service.ParseModule(*dir)
+	if err != nil {
+		return err
+	}
+	inventory, err := service.BuildConcurrencyInventory(context.Background(), modInfo)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		return writeJSON(inventory)
+	}
+	for _, flow := range inventory.Funcs {
+		if !*warningsOnly {
+			fmt.Printf("%s.%s %s:%d\n", flow.Pkg, flow.Func, flow.RFilePath, flow.Line)
+			for _, event := range flow.Events {
+				fmt.Printf("  %d:%d %s %s %s\n", event.Line, event.Column, event.Kind, event.Op, event.Target)
+			}
+		}
+		for _, warning := range flow.Warnings {
+			fmt.Printf("%s:%d:%d %s %s\n", warning.RFilePath, warning.Line, warning.Column, warning.Kind, warning.Message)
+		}
+	}
+	return nil
+}
+
+// runVet 在加载的包上运行go vet的检查和本项目自己的检查，-analyzers 用逗号分隔指定要运行的检查
+func runVet(args []string) error {
+	flagSet := flag.NewFlagSet("vet", flag.ExitOnError)
+	dir := flagSet.String("dir", ".", "模块目录")
+	jsonOutput := flagSet.Bool("json", false, "以JSON格式输出")
+	names := flagSet.String("analyzers", "", "要运行的检查，逗号分隔，默认全部")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	var selected []string
+	if *names != "" {
+		selected = strings.Split(*names, ",")
+	}
+	analyzers, err := service.LookupAnalyzers(service.DefaultAnalyzers(), selected)
+	if err != nil {
+		return err
+	}
+	modInfo, err := service.ParseModule(*dir)
+	if err != nil {
+		return err
+	}
+	report, err := service.RunAnalyzers(context.Background(), modInfo, analyzers)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		return writeJSON(report)
+	}
+	for _, diag := range report.Diagnostics {
+		owner := diag.Func
+		if owner == "" {
+			owner = diag.Struct
+		}
+		fmt.Printf("%s:%d:%d [%s] %s %s\n", diag.Position.RFilePath, diag.Position.Line, diag.Position.Column, diag.Analyzer, owner, diag.Message)
+		for _, fix := range diag.SuggestedFixes {
+			fmt.Printf("  fix: %s\n", fix.Message)
+		}
+	}
+	for _, message := range report.Errors {
+		fmt.Fprintln(os.Stderr, message)
+	}
+	return nil
+}
+
+// writeJSON 以缩进格式将v输出到标准输出，不转义HTML字符
+func writeJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
 }
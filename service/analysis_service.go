@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/analysis/passes/appends"
+	"golang.org/x/tools/go/analysis/passes/asmdecl"
+	"golang.org/x/tools/go/analysis/passes/assign"
+	"golang.org/x/tools/go/analysis/passes/atomic"
+	"golang.org/x/tools/go/analysis/passes/bools"
+	"golang.org/x/tools/go/analysis/passes/buildtag"
+	"golang.org/x/tools/go/analysis/passes/cgocall"
+	"golang.org/x/tools/go/analysis/passes/composite"
+	"golang.org/x/tools/go/analysis/passes/copylock"
+	"golang.org/x/tools/go/analysis/passes/defers"
+	"golang.org/x/tools/go/analysis/passes/directive"
+	"golang.org/x/tools/go/analysis/passes/errorsas"
+	"golang.org/x/tools/go/analysis/passes/framepointer"
+	"golang.org/x/tools/go/analysis/passes/hostport"
+	"golang.org/x/tools/go/analysis/passes/httpresponse"
+	"golang.org/x/tools/go/analysis/passes/ifaceassert"
+	"golang.org/x/tools/go/analysis/passes/loopclosure"
+	"golang.org/x/tools/go/analysis/passes/lostcancel"
+	"golang.org/x/tools/go/analysis/passes/nilfunc"
+	"golang.org/x/tools/go/analysis/passes/printf"
+	"golang.org/x/tools/go/analysis/passes/shift"
+	"golang.org/x/tools/go/analysis/passes/sigchanyzer"
+	"golang.org/x/tools/go/analysis/passes/slog"
+	"golang.org/x/tools/go/analysis/passes/stdmethods"
+	"golang.org/x/tools/go/analysis/passes/stdversion"
+	"golang.org/x/tools/go/analysis/passes/stringintconv"
+	"golang.org/x/tools/go/analysis/passes/structtag"
+	"golang.org/x/tools/go/analysis/passes/testinggoroutine"
+	"golang.org/x/tools/go/analysis/passes/tests"
+	"golang.org/x/tools/go/analysis/passes/timeformat"
+	"golang.org/x/tools/go/analysis/passes/unmarshal"
+	"golang.org/x/tools/go/analysis/passes/unreachable"
+	"golang.org/x/tools/go/analysis/passes/unsafeptr"
+	"golang.org/x/tools/go/analysis/passes/unusedresult"
+	"golang.org/x/tools/go/analysis/passes/waitgroup"
+	"golang.org/x/tools/go/packages"
+)
+
+// VetAnalyzers 与go vet默认启用的检查一致
+var VetAnalyzers = []*analysis.Analyzer{
+	appends.Analyzer,
+	asmdecl.Analyzer,
+	assign.Analyzer,
+	atomic.Analyzer,
+	bools.Analyzer,
+	buildtag.Analyzer,
+	cgocall.Analyzer,
+	composite.Analyzer,
+	copylock.Analyzer,
+	defers.Analyzer,
+	directive.Analyzer,
+	errorsas.Analyzer,
+	framepointer.Analyzer,
+	hostport.Analyzer,
+	httpresponse.Analyzer,
+	ifaceassert.Analyzer,
+	loopclosure.Analyzer,
+	lostcancel.Analyzer,
+	nilfunc.Analyzer,
+	printf.Analyzer,
+	shift.Analyzer,
+	sigchanyzer.Analyzer,
+	slog.Analyzer,
+	stdmethods.Analyzer,
+	stdversion.Analyzer,
+	stringintconv.Analyzer,
+	structtag.Analyzer,
+	testinggoroutine.Analyzer,
+	tests.Analyzer,
+	timeformat.Analyzer,
+	unmarshal.Analyzer,
+	unreachable.Analyzer,
+	unsafeptr.Analyzer,
+	unusedresult.Analyzer,
+	waitgroup.Analyzer,
+}
+
+// ErrorfWrapAnalyzer 检查格式化了error参数却没有使用%w的fmt.Errorf，并建议把对应的动词改为%w
+var ErrorfWrapAnalyzer = &analysis.Analyzer{
+	Name: "errorfwrap",
+	Doc:  "检查格式化了error却没有使用%w的fmt.Errorf\n\n使用%w包装后，errors.Is和errors.As才能识别原始错误。",
+	Run:  runErrorfWrap,
+}
+
+// DefaultAnalyzers go vet的检查加上本项目自己的检查
+func DefaultAnalyzers() []*analysis.Analyzer {
+	analyzers := make([]*analysis.Analyzer, 0, len(VetAnalyzers)+1)
+	analyzers = append(analyzers, VetAnalyzers...)
+	return append(analyzers, ErrorfWrapAnalyzer)
+}
+
+// LookupAnalyzers 按名称从候选检查中选择，names为空时返回全部候选
+func LookupAnalyzers(candidates []*analysis.Analyzer, names []string) ([]*analysis.Analyzer, error) {
+	if len(names) == 0 {
+		return candidates, nil
+	}
+	byName := make(map[string]*analysis.Analyzer, len(candidates))
+	for _, analyzer := range candidates {
+		byName[analyzer.Name] = analyzer
+	}
+	selected := make([]*analysis.Analyzer, 0, len(names))
+	for _, name := range names {
+		analyzer, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的检查: %s", name)
+		}
+		selected = append(selected, analyzer)
+	}
+	return selected, nil
+}
+
+// AnalysisPosition 诊断或修改涉及的源码位置
+type AnalysisPosition struct {
+	RFilePath string `json:"file"`   // 相对模块目录的文件路径，模块外的文件为绝对路径
+	Offset    int    `json:"offset"` // 字节偏移
+	Line      int    `json:"line"`   // 行号
+	Column    int    `json:"column"` // 列号
+}
+
+// AnalysisTextEdit 建议修复中的一处文本替换
+type AnalysisTextEdit struct {
+	Start   *AnalysisPosition `json:"start"`    // 替换起始位置
+	End     *AnalysisPosition `json:"end"`      // 替换结束位置，插入时与起始位置相同
+	NewText string            `json:"new_text"` // 替换后的文本
+}
+
+// AnalysisSuggestedFix 诊断附带的建议修复，同一诊断的多个修复只能应用其一
+type AnalysisSuggestedFix struct {
+	Message string              `json:"message"`
+	Edits   []*AnalysisTextEdit `json:"edits"`
+}
+
+// AnalysisRelated 诊断的相关位置
+type AnalysisRelated struct {
+	Message  string            `json:"message"`
+	Position *AnalysisPosition `json:"position"`
+}
+
+// AnalysisDiagnostic 一条检查结果，并关联到所在的函数或类型
+type AnalysisDiagnostic struct {
+	Analyzer       string                  `json:"analyzer"`                  // 产生诊断的检查名
+	Category       string                  `json:"category,omitempty"`        // 诊断分类
+	Message        string                  `json:"message"`                   // 诊断信息
+	URL            string                  `json:"url,omitempty"`             // 说明文档
+	Pkg            string                  `json:"pkg"`                       // 所在包
+	Position       *AnalysisPosition       `json:"position"`                  // 诊断位置
+	End            *AnalysisPosition       `json:"end,omitempty"`             // 诊断结束位置
+	Func           string                  `json:"func,omitempty"`            // 所在函数，方法为Type.Method，匿名函数为Parent$N
+	Struct         string                  `json:"struct,omitempty"`          // 不在函数中时所在的类型
+	SuggestedFixes []*AnalysisSuggestedFix `json:"suggested_fixes,omitempty"` // 建议修复
+	Related        []*AnalysisRelated      `json:"related,omitempty"`         // 相关位置
+	FuncInfo       *vs.FuncInfo            `json:"-"`
+	StructInfo     *vs.StructInfo          `json:"-"`
+}
+
+// AnalysisReport 模块的检查结果
+type AnalysisReport struct {
+	Module      string                `json:"module"`
+	Analyzers   []string              `json:"analyzers"`        // 运行的检查
+	Counts      map[string]int        `json:"counts"`           // 各检查的诊断数量
+	Diagnostics []*AnalysisDiagnostic `json:"diagnostics"`      // 按位置排序的诊断
+	Errors      []string              `json:"errors,omitempty"` // 检查自身的运行错误，如包存在类型错误时被跳过
+}
+
+// RunAnalyzers 在LoadPackages加载的模块包上运行analysis检查，复用已加载的语法树和类型信息而不是另外执行go vet；
+// 诊断关联到所在的FuncInfo或StructInfo，建议修复原样保留
+func RunAnalyzers(ctx context.Context, modInfo *ModuleInfo, analyzers []*analysis.Analyzer) (*AnalysisReport, error) {
+	pkgs, err := LoadPackages(ctx, &LoadConfig{RepoPath: modInfo.Dir, LoadEnum: LoadCurrentRepo})
+	if err != nil {
+		return nil, err
+	}
+	roots := make([]*packages.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if isModulePkg(modInfo.Path, pkg.PkgPath) {
+			roots = append(roots, pkg)
+		}
+	}
+	graph, err := checker.Analyze(analyzers, roots, nil)
+	if err != nil {
+		return nil, fmt.Errorf("运行检查失败: %w", err)
+	}
+	absDir, err := filepath.Abs(modInfo.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("获取模块绝对路径失败: %w", err)
+	}
+	locator := &analysisLocator{absDir: absDir, fileFuncs: moduleFileFuncs(modInfo), fileStructs: moduleFileStructs(modInfo)}
+	report := &AnalysisReport{
+		Module:      modInfo.Path,
+		Analyzers:   make([]string, 0, len(analyzers)),
+		Counts:      make(map[string]int),
+		Diagnostics: make([]*AnalysisDiagnostic, 0),
+	}
+	for _, analyzer := range analyzers {
+		report.Analyzers = append(report.Analyzers, analyzer.Name)
+	}
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", act, act.Err))
+			continue
+		}
+		for _, diag := range act.Diagnostics {
+			report.Diagnostics = append(report.Diagnostics, locator.diagnostic(act.Analyzer, act.Package, diag))
+			report.Counts[act.Analyzer.Name]++
+		}
+	}
+	sort.Strings(report.Errors)
+	sort.SliceStable(report.Diagnostics, func(i, j int) bool {
+		a, b := report.Diagnostics[i], report.Diagnostics[j]
+		if a.Position.RFilePath != b.Position.RFilePath {
+			return a.Position.RFilePath < b.Position.RFilePath
+		}
+		if a.Position.Offset != b.Position.Offset {
+			return a.Position.Offset < b.Position.Offset
+		}
+		return a.Analyzer < b.Analyzer
+	})
+	return report, nil
+}
+
+// moduleFileStructs 按相对文件路径索引模块内的类型定义
+func moduleFileStructs(modInfo *ModuleInfo) map[string][]*vs.StructInfo {
+	fileStructs := make(map[string][]*vs.StructInfo)
+	for _, structs := range modInfo.PkgStructMap {
+		for _, structInfo := range structs {
+			rFilePath := filepath.ToSlash(structInfo.RFilePath)
+			fileStructs[rFilePath] = append(fileStructs[rFilePath], structInfo)
+		}
+	}
+	return fileStructs
+}
+
+// locateStructInfo 查找包含指定行的类型定义
+func locateStructInfo(structs []*vs.StructInfo, line int) *vs.StructInfo {
+	for _, structInfo := range structs {
+		start, end := structInfo.StartPosition, structInfo.EndPosition
+		if start != nil && end != nil && line >= start.Line && line <= end.Line {
+			return structInfo
+		}
+	}
+	return nil
+}
+
+type analysisLocator struct {
+	absDir      string
+	fileFuncs   map[string][]*vs.FuncInfo
+	fileStructs map[string][]*vs.StructInfo
+}
+
+// diagnostic 转换诊断，并关联到所在的函数，不在函数中时关联到所在的类型
+func (l *analysisLocator) diagnostic(analyzer *analysis.Analyzer, pkg *packages.Package, diag analysis.Diagnostic) *AnalysisDiagnostic {
+	result := &AnalysisDiagnostic{
+		Analyzer: analyzer.Name,
+		Category: diag.Category,
+		Message:  diag.Message,
+		URL:      diag.URL,
+		Pkg:      pkg.PkgPath,
+		Position: l.position(pkg.Fset, diag.Pos),
+	}
+	if diag.End.IsValid() && diag.End != diag.Pos {
+		result.End = l.position(pkg.Fset, diag.End)
+	}
+	position := result.Position
+	result.FuncInfo = LocateFuncInfo(l.fileFuncs[position.RFilePath], position.Line, position.Column)
+	if result.FuncInfo != nil {
+		result.Func = skeletonDisplayName(result.FuncInfo)
+	} else if result.StructInfo = locateStructInfo(l.fileStructs[position.RFilePath], position.Line); result.StructInfo != nil {
+		result.Struct = result.StructInfo.Name
+	}
+	for _, fix := range diag.SuggestedFixes {
+		suggested := &AnalysisSuggestedFix{Message: fix.Message, Edits: make([]*AnalysisTextEdit, 0, len(fix.TextEdits))}
+		for _, edit := range fix.TextEdits {
+			end := edit.End
+			if !end.IsValid() {
+				end = edit.Pos
+			}
+			suggested.Edits = append(suggested.Edits, &AnalysisTextEdit{
+				Start:   l.position(pkg.Fset, edit.Pos),
+				End:     l.position(pkg.Fset, end),
+				NewText: string(edit.NewText),
+			})
+		}
+		result.SuggestedFixes = append(result.SuggestedFixes, suggested)
+	}
+	for _, related := range diag.Related {
+		result.Related = append(result.Related, &AnalysisRelated{Message: related.Message, Position: l.position(pkg.Fset, related.Pos)})
+	}
+	return result
+}
+
+// position 转换为相对模块目录的位置
+func (l *analysisLocator) position(fset *token.FileSet, pos token.Pos) *AnalysisPosition {
+	position := fset.Position(pos)
+	rFilePath := position.Filename
+	if rel, err := filepath.Rel(l.absDir, position.Filename); err == nil && !strings.HasPrefix(rel, "..") {
+		rFilePath = filepath.ToSlash(rel)
+	}
+	return &AnalysisPosition{RFilePath: rFilePath, Offset: position.Offset, Line: position.Line, Column: position.Column}
+}
+
+// runErrorfWrap 报告格式化了error却没有使用%w的fmt.Errorf，格式串为不含转义和%[n]的字面量时建议把对应的%v或%s改为%w
+func runErrorfWrap(pass *analysis.Pass) (any, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			verbs := errorfUnwrappedVerbs(pass.TypesInfo, call)
+			if len(verbs) == 0 {
+				return true
+			}
+			diag := analysis.Diagnostic{
+				Pos:     call.Pos(),
+				End:     call.End(),
+				Message: "fmt.Errorf格式化了error但没有使用%w，errors.Is和errors.As无法识别原始错误",
+			}
+			if format, ok := ast.Unparen(call.Args[0]).(*ast.BasicLit); ok && errorfFixable(format) {
+				// 字面量不含转义时，常量中的偏移加上开头的引号即为源码中的偏移
+				edits := make([]analysis.TextEdit, 0, len(verbs))
+				for _, verb := range verbs {
+					start := format.Pos() + token.Pos(verb.start+1)
+					edits = append(edits, analysis.TextEdit{Pos: start, End: format.Pos() + token.Pos(verb.end+1), NewText: []byte("%w")})
+				}
+				diag.SuggestedFixes = []analysis.SuggestedFix{{Message: "使用%w包装error", TextEdits: edits}}
+			}
+			pass.Report(diag)
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// errorfFixable 判断格式串字面量能否直接按动词偏移修改：不含转义，也没有用%[n]指定参数
+func errorfFixable(format *ast.BasicLit) bool {
+	if format.Kind != token.STRING || strings.Contains(format.Value, "%[") {
+		return false
+	}
+	if strings.HasPrefix(format.Value, "`") {
+		// 原始字符串的值会去掉回车符
+		return !strings.Contains(format.Value, "\r")
+	}
+	return !strings.Contains(format.Value, `\`)
+}
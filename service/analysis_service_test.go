@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunAnalyzers(t *testing.T) {
+	source := `package store
+
+import (
+	"fmt"
+	"os"
+)
+
+type User struct {
+	Name string ` + "`json:name`" + `
+}
+
+func Open(path string) error {
+	_, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开 %s 失败: %v", path, err)
+	}
+	fmt.Printf("opened %d\n", path)
+	return nil
+}
+
+const closeFailed = "关闭 %s 失败: %v"
+
+func Close(path string, err error) error {
+	return fmt.Errorf(closeFailed, path, err)
+}
+
+func Rate(path string, err error) error {
+	if path == "" {
+		return fmt.Errorf("进度 100%% %s: %v", path, err)
+	}
+	return fmt.Errorf("进度 100%% %[2]v: %[1]s", path, err)
+}
+`
+	dir := writeTestModule(t, map[string]string{
+		"go.mod":         "module example.com/app\n\ngo 1.22\n",
+		"store/store.go": source,
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LookupAnalyzers(DefaultAnalyzers(), []string{"nope"}); err == nil {
+		t.Fatal("未知的检查应返回错误")
+	}
+	analyzers, err := LookupAnalyzers(DefaultAnalyzers(), []string{"printf", "structtag", "errorfwrap"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := RunAnalyzers(context.Background(), modInfo, analyzers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Diagnostics) != 6 || len(report.Errors) != 0 {
+		t.Fatalf("期望6条诊断: %+v %v", report.Diagnostics, report.Errors)
+	}
+	tag, wrap, printf, constWrap := report.Diagnostics[0], report.Diagnostics[1], report.Diagnostics[2], report.Diagnostics[3]
+	percentWrap, indexedWrap := report.Diagnostics[4], report.Diagnostics[5]
+	if tag.Analyzer != "structtag" || tag.Struct != "User" || tag.Func != "" || tag.Position.RFilePath != "store/store.go" || tag.Position.Line != 9 {
+		t.Errorf("structtag诊断错误: %+v", tag)
+	}
+	if printf.Analyzer != "printf" || printf.Func != "Open" || printf.FuncInfo == nil || printf.Position.Line != 17 {
+		t.Errorf("printf诊断错误: %+v", printf)
+	}
+	if wrap.Analyzer != "errorfwrap" || wrap.Func != "Open" || len(wrap.SuggestedFixes) != 1 || len(wrap.SuggestedFixes[0].Edits) != 1 {
+		t.Fatalf("errorfwrap诊断错误: %+v", wrap)
+	}
+	if constWrap.Analyzer != "errorfwrap" || constWrap.Func != "Close" || len(constWrap.SuggestedFixes) != 0 {
+		t.Errorf("常量格式串应报告但不建议修复: %+v", constWrap)
+	}
+	// %[n]显式指定参数时只报告，不建议修复
+	if indexedWrap.Analyzer != "errorfwrap" || indexedWrap.Func != "Rate" || len(indexedWrap.SuggestedFixes) != 0 {
+		t.Errorf("显式参数序号的格式串不应建议修复: %+v", indexedWrap)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "store/store.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for diag, want := range map[*AnalysisDiagnostic]string{
+		wrap:        `fmt.Errorf("打开 %s 失败: %w", path, err)`,
+		percentWrap: `fmt.Errorf("进度 100%% %s: %w", path, err)`,
+	} {
+		if len(diag.SuggestedFixes) != 1 || len(diag.SuggestedFixes[0].Edits) != 1 {
+			t.Fatalf("errorfwrap应建议一处修改: %+v", diag)
+		}
+		edit := diag.SuggestedFixes[0].Edits[0]
+		fixed := string(data[:edit.Start.Offset]) + edit.NewText + string(data[edit.End.Offset:])
+		if !strings.Contains(fixed, want) {
+			t.Errorf("建议修复应用后错误:\n%s", fixed)
+		}
+	}
+	if report.Counts["printf"] != 1 || len(report.Analyzers) != 3 {
+		t.Errorf("统计错误: %v %v", report.Counts, report.Analyzers)
+	}
+}
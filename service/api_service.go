@@ -0,0 +1,432 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	APIKindFunc        = "func"
+	APIKindMethod      = "method"
+	APIKindType        = "type"
+	APIKindField       = "field"
+	APIKindIfaceMethod = "interface_method"
+	APIKindConst       = "const"
+	APIKindVar         = "var"
+
+	SemverBumpMajor = "major"
+	SemverBumpMinor = "minor"
+	SemverBumpPatch = "patch"
+)
+
+// APIElement 表示一个导出的API元素
+type APIElement struct {
+	Pkg       string // 包路径
+	Name      string // 元素名，方法和字段为 Type.Name 形式
+	Kind      string // 元素类型
+	Signature string // 完整类型签名
+	Sealed    bool   // 接口是否包含未导出方法，仅对接口方法有效
+}
+
+// APISnapshot 表示某一版本模块的导出API
+type APISnapshot struct {
+	ModulePath string                            // 模块路径
+	Packages   map[string]map[string]*APIElement // 包路径 -> 元素名 -> 元素
+}
+
+// APIChange 表示两个版本之间的一处API变化
+type APIChange struct {
+	Pkg          string // 包路径
+	Name         string // 元素名
+	Kind         string // 元素类型
+	OldSignature string // 旧签名，新增时为空
+	NewSignature string // 新签名，删除时为空
+	Breaking     bool   // 是否为不兼容变化
+	Message      string // 变化描述
+}
+
+// APIDiffReport API对比报告
+type APIDiffReport struct {
+	OldModulePath       string       // 旧版本模块路径
+	NewModulePath       string       // 新版本模块路径
+	Changes             []*APIChange // 所有变化
+	Bump                string       // 建议的semver升级级别
+	SuggestedModulePath string       // 需要升级主版本时建议的模块路径
+	NextVersion         string       // 基于基线版本计算的下一个版本
+}
+
+// HasBreaking 是否存在不兼容变化
+func (r *APIDiffReport) HasBreaking() bool {
+	for _, change := range r.Changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractAPI 加载模块目录下的所有包并提取导出API，internal和main包不计入
+func ExtractAPI(ctx context.Context, dir string) (*APISnapshot, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("读取go.mod失败: %w", err)
+	}
+	modulePath := modfile.ModulePath(data)
+	pkgs, err := LoadPackages(ctx, &LoadConfig{RepoPath: dir, LoadEnum: LoadCurrentRepo})
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &APISnapshot{
+		ModulePath: modulePath,
+		Packages:   make(map[string]map[string]*APIElement),
+	}
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("包 %s 加载失败: %w", pkg.PkgPath, pkg.Errors[0])
+		}
+		if pkg.Types == nil || pkg.Name == "main" || isInternalPkg(pkg.PkgPath) {
+			continue
+		}
+		snapshot.Packages[pkg.PkgPath] = extractPkgAPI(pkg.Types)
+	}
+	return snapshot, nil
+}
+
+// ExtractAPIFromGitRef 导出git仓库中某个ref的源码到临时目录后提取API，moduleDir为模块相对仓库根目录的路径
+func ExtractAPIFromGitRef(ctx context.Context, repoDir, ref, moduleDir string) (*APISnapshot, error) {
+	tmpDir, err := os.MkdirTemp("", "static_parser_api_")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	cmd := exec.CommandContext(ctx, "git", "-C", repoDir, "archive", "--format=tar", ref)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("导出git ref %s 失败: %w, %s", ref, err, stderr.String())
+	}
+	if err := extractTar(bytes.NewReader(out), tmpDir); err != nil {
+		return nil, err
+	}
+	return ExtractAPI(ctx, filepath.Join(tmpDir, moduleDir))
+}
+
+// DiffAPI 对比两个版本的API并给出semver升级建议，baseVersion为旧版本号，可为空；
+// 包按相对模块路径的路径匹配，主版本升级后模块路径变化时仍能对比
+func DiffAPI(oldSnapshot, newSnapshot *APISnapshot, baseVersion string) *APIDiffReport {
+	report := &APIDiffReport{
+		OldModulePath: oldSnapshot.ModulePath,
+		NewModulePath: newSnapshot.ModulePath,
+		Changes:       make([]*APIChange, 0),
+	}
+	oldPkgs, newPkgs := oldSnapshot.relPackages(), newSnapshot.relPackages()
+	for relPkg, oldPkgPath := range oldPkgs {
+		oldElems := oldSnapshot.Packages[oldPkgPath]
+		newPkgPath, ok := newPkgs[relPkg]
+		if !ok {
+			report.Changes = append(report.Changes, &APIChange{
+				Pkg:      oldPkgPath,
+				Kind:     "package",
+				Breaking: true,
+				Message:  "包被删除",
+			})
+			continue
+		}
+		newElems := newSnapshot.Packages[newPkgPath]
+		for name, oldElem := range oldElems {
+			newElem, ok := newElems[name]
+			if !ok {
+				report.Changes = append(report.Changes, &APIChange{
+					Pkg:          newPkgPath,
+					Name:         name,
+					Kind:         oldElem.Kind,
+					OldSignature: oldElem.Signature,
+					Breaking:     true,
+					Message:      oldElem.Kind + " 被删除",
+				})
+			} else if oldSnapshot.relSignature(oldElem.Signature) != newSnapshot.relSignature(newElem.Signature) {
+				report.Changes = append(report.Changes, &APIChange{
+					Pkg:          newPkgPath,
+					Name:         name,
+					Kind:         newElem.Kind,
+					OldSignature: oldElem.Signature,
+					NewSignature: newElem.Signature,
+					Breaking:     true,
+					Message:      oldElem.Kind + " 签名变化",
+				})
+			}
+		}
+	}
+	for relPkg, newPkgPath := range newPkgs {
+		newElems := newSnapshot.Packages[newPkgPath]
+		oldPkgPath, ok := oldPkgs[relPkg]
+		if !ok {
+			report.Changes = append(report.Changes, &APIChange{
+				Pkg:     newPkgPath,
+				Kind:    "package",
+				Message: "新增包",
+			})
+			continue
+		}
+		oldElems := oldSnapshot.Packages[oldPkgPath]
+		for name, newElem := range newElems {
+			if _, ok := oldElems[name]; ok {
+				continue
+			}
+			change := &APIChange{
+				Pkg:          newPkgPath,
+				Name:         name,
+				Kind:         newElem.Kind,
+				NewSignature: newElem.Signature,
+				Message:      newElem.Kind + " 新增",
+			}
+			// 向已存在且外部可实现的接口添加方法会破坏已有实现
+			typeName := name[:strings.Index(name+".", ".")]
+			if _, existed := oldElems[typeName]; existed && newElem.Kind == APIKindIfaceMethod && !newElem.Sealed {
+				change.Breaking = true
+				change.Message = "接口新增方法，已有实现将无法满足接口"
+			}
+			report.Changes = append(report.Changes, change)
+		}
+	}
+	sort.Slice(report.Changes, func(i, j int) bool {
+		if report.Changes[i].Pkg != report.Changes[j].Pkg {
+			return report.Changes[i].Pkg < report.Changes[j].Pkg
+		}
+		return report.Changes[i].Name < report.Changes[j].Name
+	})
+	fillSemverSuggestion(report, baseVersion)
+	return report
+}
+
+// relPackages 返回相对模块路径的包路径到完整包路径的映射，模块根包为"."
+func (s *APISnapshot) relPackages() map[string]string {
+	result := make(map[string]string, len(s.Packages))
+	for pkgPath := range s.Packages {
+		relPkg := "."
+		if pkgPath != s.ModulePath {
+			relPkg = strings.TrimPrefix(pkgPath, s.ModulePath+"/")
+		}
+		result[relPkg] = pkgPath
+	}
+	return result
+}
+
+// relSignature 将签名中引用本模块包的路径替换为占位符，避免模块路径变化被视为签名变化
+func (s *APISnapshot) relSignature(signature string) string {
+	signature = strings.ReplaceAll(signature, s.ModulePath+"/", "{module}/")
+	return strings.ReplaceAll(signature, s.ModulePath+".", "{module}.")
+}
+
+// fillSemverSuggestion 根据变化和模块主版本后缀计算升级建议
+func fillSemverSuggestion(report *APIDiffReport, baseVersion string) {
+	prefix, pathMajor, _ := module.SplitPathVersion(report.OldModulePath)
+	major := 1
+	// gopkg.in的主版本后缀形如.v1，其余形如/v2
+	separator := "/v"
+	if pathMajor != "" {
+		major, _ = strconv.Atoi(strings.TrimPrefix(module.PathMajorPrefix(pathMajor), "v"))
+		separator = pathMajor[:1] + "v"
+	} else if semver.IsValid(baseVersion) && semver.Major(baseVersion) == "v0" {
+		major = 0
+	}
+	switch {
+	case report.HasBreaking() && major >= 1 && report.NewModulePath == report.OldModulePath:
+		report.Bump = SemverBumpMajor
+		report.SuggestedModulePath = fmt.Sprintf("%s%s%d", prefix, separator, major+1)
+	case report.HasBreaking() && major >= 1:
+		// 模块路径已随主版本升级
+		report.Bump = SemverBumpMajor
+	case report.HasBreaking() || len(report.Changes) > 0:
+		// v0版本允许在minor中引入不兼容变化
+		report.Bump = SemverBumpMinor
+	default:
+		report.Bump = SemverBumpPatch
+	}
+	if semver.IsValid(baseVersion) {
+		report.NextVersion = nextSemver(baseVersion, report.Bump)
+	}
+}
+
+func nextSemver(version, bump string) string {
+	parts := strings.SplitN(strings.TrimPrefix(semver.Canonical(version), "v"), ".", 3)
+	nums := make([]int, 3)
+	for i, part := range parts {
+		part, _, _ = strings.Cut(part, "-")
+		part, _, _ = strings.Cut(part, "+")
+		nums[i], _ = strconv.Atoi(part)
+	}
+	switch bump {
+	case SemverBumpMajor:
+		return fmt.Sprintf("v%d.0.0", nums[0]+1)
+	case SemverBumpMinor:
+		return fmt.Sprintf("v%d.%d.0", nums[0], nums[1]+1)
+	default:
+		return fmt.Sprintf("v%d.%d.%d", nums[0], nums[1], nums[2]+1)
+	}
+}
+
+func extractPkgAPI(pkg *types.Package) map[string]*APIElement {
+	elems := make(map[string]*APIElement)
+	qualifier := types.RelativeTo(pkg)
+	add := func(name, kind, signature string, sealed bool) {
+		elems[name] = &APIElement{Pkg: pkg.Path(), Name: name, Kind: kind, Signature: signature, Sealed: sealed}
+	}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch o := obj.(type) {
+		case *types.Func:
+			add(name, APIKindFunc, types.TypeString(o.Type(), qualifier), false)
+		case *types.Const:
+			add(name, APIKindConst, types.TypeString(o.Type(), qualifier)+" = "+o.Val().ExactString(), false)
+		case *types.Var:
+			add(name, APIKindVar, types.TypeString(o.Type(), qualifier), false)
+		case *types.TypeName:
+			extractTypeAPI(o, qualifier, add)
+		}
+	}
+	return elems
+}
+
+func extractTypeAPI(obj *types.TypeName, qualifier types.Qualifier, add func(name, kind, signature string, sealed bool)) {
+	name := obj.Name()
+	underlying := obj.Type().Underlying()
+	signature := typeKindString(underlying, qualifier)
+	if obj.IsAlias() {
+		signature = "= " + types.TypeString(obj.Type(), qualifier)
+	}
+	named, isNamed := obj.Type().(*types.Named)
+	if isNamed && named.TypeParams().Len() > 0 {
+		params := make([]string, 0, named.TypeParams().Len())
+		for i := 0; i < named.TypeParams().Len(); i++ {
+			param := named.TypeParams().At(i)
+			params = append(params, param.Obj().Name()+" "+types.TypeString(param.Constraint(), qualifier))
+		}
+		signature = "[" + strings.Join(params, ", ") + "] " + signature
+	}
+	add(name, APIKindType, signature, false)
+	if obj.IsAlias() {
+		return
+	}
+	switch u := underlying.(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			field := u.Field(i)
+			if !field.Exported() {
+				continue
+			}
+			fieldSig := types.TypeString(field.Type(), qualifier)
+			if field.Embedded() {
+				fieldSig = "embedded " + fieldSig
+			}
+			add(name+"."+field.Name(), APIKindField, fieldSig, false)
+		}
+	case *types.Interface:
+		sealed := false
+		for i := 0; i < u.NumMethods(); i++ {
+			if !u.Method(i).Exported() {
+				sealed = true
+			}
+		}
+		for i := 0; i < u.NumMethods(); i++ {
+			method := u.Method(i)
+			if method.Exported() {
+				add(name+"."+method.Name(), APIKindIfaceMethod, types.TypeString(method.Type(), qualifier), sealed)
+			}
+		}
+	}
+	if !isNamed {
+		return
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		method := named.Method(i)
+		if !method.Exported() {
+			continue
+		}
+		sig := method.Type().(*types.Signature)
+		recv := name
+		if _, ok := sig.Recv().Type().(*types.Pointer); ok {
+			recv = "*" + name
+		}
+		add(name+"."+method.Name(), APIKindMethod, "("+recv+") "+types.TypeString(sig, qualifier), false)
+	}
+}
+
+// typeKindString 返回类型定义的签名，结构体和接口的成员单独记录
+func typeKindString(underlying types.Type, qualifier types.Qualifier) string {
+	switch underlying.(type) {
+	case *types.Struct:
+		return "struct"
+	case *types.Interface:
+		return "interface"
+	default:
+		return types.TypeString(underlying, qualifier)
+	}
+}
+
+func isInternalPkg(pkgPath string) bool {
+	for _, elem := range strings.Split(pkgPath, "/") {
+		if elem == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// extractTar 将tar流解压到目标目录
+func extractTar(r io.Reader, destDir string) error {
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取tar失败: %w", err)
+		}
+		target := filepath.Join(destDir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(destDir)+string(os.PathSeparator)) {
+			return fmt.Errorf("非法的tar路径: %s", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0o777)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(file, tarReader); err != nil {
+				file.Close()
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDiffAPI(t *testing.T) {
+	ctx := context.Background()
+	oldDir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/lib\n\ngo 1.21\n",
+		"lib.go": `package lib
+
+const Version = "1"
+
+type Store interface {
+	Get(key string) string
+}
+
+type Config struct {
+	Name string
+	Size int
+}
+
+func New(name string) *Config { return &Config{Name: name} }
+
+func (c *Config) Describe() string { return c.Name }
+
+func Removed() {}
+`,
+		"internal/hidden/hidden.go": "package hidden\n\nfunc Hidden() {}\n",
+	})
+	newDir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/lib\n\ngo 1.21\n",
+		"lib.go": `package lib
+
+const Version = "1"
+
+type Store interface {
+	Get(key string) string
+	Put(key, value string)
+}
+
+type Config struct {
+	Name  string
+	Size  int64
+	Extra bool
+}
+
+func New(name string, size int) *Config { return &Config{Name: name} }
+
+func (c *Config) Describe() string { return c.Name }
+
+func Added() {}
+`,
+	})
+	oldSnapshot, err := ExtractAPI(ctx, oldDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSnapshot, err := ExtractAPI(ctx, newDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := oldSnapshot.Packages["example.com/lib/internal/hidden"]; ok {
+		t.Errorf("internal package should not be part of the API")
+	}
+	report := DiffAPI(oldSnapshot, newSnapshot, "v1.4.2")
+	changes := make(map[string]*APIChange)
+	for _, change := range report.Changes {
+		changes[change.Name] = change
+	}
+	expected := map[string]bool{
+		"Removed":      true,
+		"New":          true,
+		"Config.Size":  true,
+		"Store.Put":    true,
+		"Added":        false,
+		"Config.Extra": false,
+	}
+	for name, breaking := range expected {
+		change, ok := changes[name]
+		if !ok {
+			t.Errorf("missing change for %s", name)
+			continue
+		}
+		if change.Breaking != breaking {
+			t.Errorf("%s: expected breaking=%v, got %v (%s)", name, breaking, change.Breaking, change.Message)
+		}
+	}
+	if _, ok := changes["Config.Describe"]; ok {
+		t.Errorf("unchanged method reported as change")
+	}
+	if report.Bump != SemverBumpMajor || report.SuggestedModulePath != "example.com/lib/v2" || report.NextVersion != "v2.0.0" {
+		t.Errorf("unexpected semver suggestion: %s %s %s", report.Bump, report.SuggestedModulePath, report.NextVersion)
+	}
+	compatible := DiffAPI(oldSnapshot, oldSnapshot, "v0.3.0")
+	if compatible.Bump != SemverBumpPatch || compatible.NextVersion != "v0.3.1" {
+		t.Errorf("unexpected suggestion for identical api: %s %s", compatible.Bump, compatible.NextVersion)
+	}
+	gopkg := &APIDiffReport{
+		OldModulePath: "gopkg.in/yaml.v2",
+		NewModulePath: "gopkg.in/yaml.v2",
+		Changes:       []*APIChange{{Breaking: true}},
+	}
+	fillSemverSuggestion(gopkg, "v2.4.0")
+	if gopkg.Bump != SemverBumpMajor || gopkg.SuggestedModulePath != "gopkg.in/yaml.v3" || gopkg.NextVersion != "v3.0.0" {
+		t.Errorf("unexpected suggestion for gopkg.in module: %s %s %s", gopkg.Bump, gopkg.SuggestedModulePath, gopkg.NextVersion)
+	}
+}
+
+func TestDiffAPIMajorVersion(t *testing.T) {
+	ctx := context.Background()
+	files := func(modulePath, extra string) map[string]string {
+		return map[string]string{
+			"go.mod":         "module " + modulePath + "\n\ngo 1.21\n",
+			"lib.go":         "package lib\n\nimport \"" + modulePath + "/codec\"\n\nfunc Encode(v any) codec.Data { return nil }\n" + extra,
+			"codec/codec.go": "package codec\n\ntype Data []byte\n",
+		}
+	}
+	oldSnapshot, err := ExtractAPI(ctx, writeTestModule(t, files("example.com/lib", "\nfunc Removed() {}\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSnapshot, err := ExtractAPI(ctx, writeTestModule(t, files("example.com/lib/v2", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := DiffAPI(oldSnapshot, newSnapshot, "v1.4.2")
+	if len(report.Changes) != 1 || report.Changes[0].Name != "Removed" || report.Changes[0].Pkg != "example.com/lib/v2" {
+		t.Fatalf("v1 to v2 diff should only report real changes: %+v", report.Changes)
+	}
+	if report.Bump != SemverBumpMajor || report.SuggestedModulePath != "" || report.NextVersion != "v2.0.0" {
+		t.Errorf("unexpected semver suggestion: %s %s %s", report.Bump, report.SuggestedModulePath, report.NextVersion)
+	}
+}
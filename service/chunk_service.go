@@ -0,0 +1,264 @@
+package service
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+)
+
+const (
+	ChunkKindFunc   = "func"
+	ChunkKindMethod = "method"
+	ChunkKindType   = "type"
+
+	// DefaultChunkMaxLines 单个chunk默认的最大行数
+	DefaultChunkMaxLines = 80
+	// chunkParsePrefix 解析函数片段时补充的包声明
+	chunkParsePrefix = "package p\n"
+)
+
+// Chunk 表示一个面向检索/向量化的代码片段
+type Chunk struct {
+	ID                   string   `json:"id"`                               // 稳定的chunk标识
+	Kind                 string   `json:"kind"`                             // func/method/type
+	Pkg                  string   `json:"pkg"`                              // 所属包路径
+	Name                 string   `json:"name"`                             // 函数、方法或类型名
+	QualifiedName        string   `json:"qualified_name"`                   // 带包路径的全限定名
+	RFilePath            string   `json:"file"`                             // 相对模块根目录的文件路径
+	Doc                  string   `json:"doc,omitempty"`                    // 注释
+	Signature            string   `json:"signature"`                        // 签名
+	Imports              []string `json:"imports,omitempty"`                // 引用到的导入包
+	EnclosingType        string   `json:"enclosing_type,omitempty"`         // 方法所属类型
+	EnclosingTypeContent string   `json:"enclosing_type_content,omitempty"` // 方法所属类型的定义
+	Part                 int      `json:"part"`                             // 第几个分片，从1开始
+	TotalParts           int      `json:"total_parts"`                      // 分片总数
+	StartLine            int      `json:"start_line"`                       // 起始行
+	EndLine              int      `json:"end_line"`                         // 结束行
+	Content              string   `json:"content"`                          // 片段内容
+}
+
+// ChunkConfig 切分配置
+type ChunkConfig struct {
+	MaxLines int // 单个chunk的最大行数，超过则按语句边界切分
+}
+
+// ChunkModule 将模块中的函数、方法和类型切分为检索用的chunk
+func ChunkModule(modInfo *ModuleInfo, cfg *ChunkConfig) []*Chunk {
+	maxLines := DefaultChunkMaxLines
+	if cfg != nil && cfg.MaxLines > 0 {
+		maxLines = cfg.MaxLines
+	}
+	pkgs := make([]string, 0, len(modInfo.PkgFuncMap))
+	for pkg := range modInfo.PkgFuncMap {
+		pkgs = append(pkgs, pkg)
+	}
+	for pkg := range modInfo.PkgStructMap {
+		if _, ok := modInfo.PkgFuncMap[pkg]; !ok {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Strings(pkgs)
+	chunks := make([]*Chunk, 0)
+	idCounts := make(map[string]int)
+	for _, pkg := range pkgs {
+		structMap := make(map[string]*vs.StructInfo)
+		for _, structInfo := range modInfo.PkgStructMap[pkg] {
+			structMap[structInfo.Name] = structInfo
+			chunks = append(chunks, buildTypeChunk(structInfo))
+		}
+		for _, funcInfo := range modInfo.PkgFuncMap[pkg] {
+			// 匿名函数已经包含在外层函数中
+			if strings.Contains(funcInfo.Name, "$") {
+				continue
+			}
+			chunks = append(chunks, buildFuncChunks(funcInfo, structMap, maxLines)...)
+		}
+	}
+	for _, chunk := range chunks {
+		chunk.ID = chunkID(chunk, idCounts)
+	}
+	return chunks
+}
+
+// WriteChunksJSONL 以JSONL格式输出chunk，每行一个
+func WriteChunksJSONL(w io.Writer, chunks []*Chunk) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, chunk := range chunks {
+		if err := encoder.Encode(chunk); err != nil {
+			return fmt.Errorf("写入chunk %s 失败: %w", chunk.ID, err)
+		}
+	}
+	return nil
+}
+
+// chunkID 根据包、全限定名和分片序号生成稳定ID，同名冲突时追加序号
+func chunkID(chunk *Chunk, idCounts map[string]int) string {
+	key := fmt.Sprintf("%s#%s#%d", chunk.RFilePath, chunk.QualifiedName, chunk.Part)
+	idCounts[key]++
+	if idCounts[key] > 1 {
+		key = fmt.Sprintf("%s#%d", key, idCounts[key])
+	}
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+func buildTypeChunk(structInfo *vs.StructInfo) *Chunk {
+	docStart, start, end := typeSpecRange(structInfo)
+	spec := structInfo.Content[start:end]
+	signature := "type " + structInfo.Name
+	if idx := strings.Index(spec, structInfo.Name); idx >= 0 {
+		line := spec[idx:]
+		if end := strings.IndexAny(line, "{\n"); end >= 0 {
+			line = line[:end]
+		}
+		signature = "type " + strings.TrimSpace(line)
+	}
+	content := structInfo.Content[docStart:end]
+	startLine := structInfo.StartPosition.Line + strings.Count(structInfo.Content[:docStart], "\n")
+	return &Chunk{
+		Kind:          ChunkKindType,
+		Pkg:           structInfo.Pkg,
+		Name:          structInfo.Name,
+		QualifiedName: structInfo.Pkg + "." + structInfo.Name,
+		RFilePath:     structInfo.RFilePath,
+		Doc:           structInfo.Doc,
+		Signature:     signature,
+		Imports:       structInfo.Imports,
+		Part:          1,
+		TotalParts:    1,
+		StartLine:     startLine,
+		EndLine:       startLine + strings.Count(content, "\n"),
+		Content:       content,
+	}
+}
+
+// typeSpecRange 返回类型在所属声明内容中的偏移区间：注释起点、TypeSpec起点和终点；
+// 分组声明 type ( ... ) 只截取该类型的TypeSpec，非分组声明或解析失败时返回整个内容
+func typeSpecRange(structInfo *vs.StructInfo) (int, int, int) {
+	content := structInfo.Content
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "", chunkParsePrefix+content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil || len(file.Decls) == 0 {
+		return 0, 0, len(content)
+	}
+	genDecl, ok := file.Decls[0].(*ast.GenDecl)
+	if !ok || !genDecl.Lparen.IsValid() {
+		return 0, 0, len(content)
+	}
+	offset := func(pos token.Pos) int {
+		return fileSet.Position(pos).Offset - len(chunkParsePrefix)
+	}
+	for _, spec := range genDecl.Specs {
+		typeSpec, ok := spec.(*ast.TypeSpec)
+		if !ok || typeSpec.Name.Name != structInfo.Name {
+			continue
+		}
+		docStart := typeSpec.Pos()
+		if typeSpec.Doc != nil {
+			docStart = typeSpec.Doc.Pos()
+		}
+		return offset(docStart), offset(typeSpec.Pos()), offset(typeSpec.End())
+	}
+	return 0, 0, len(content)
+}
+
+// typeSpecSource 返回单个类型的定义，分组声明中的类型补上type关键字
+func typeSpecSource(structInfo *vs.StructInfo) string {
+	_, start, end := typeSpecRange(structInfo)
+	if start == 0 && end == len(structInfo.Content) {
+		return structInfo.Content
+	}
+	return "type " + structInfo.Content[start:end]
+}
+
+func buildFuncChunks(funcInfo *vs.FuncInfo, structMap map[string]*vs.StructInfo, maxLines int) []*Chunk {
+	base := Chunk{
+		Kind:          ChunkKindFunc,
+		Pkg:           funcInfo.Pkg,
+		Name:          funcInfo.Name,
+		QualifiedName: funcInfo.Pkg + "." + funcInfo.Name,
+		RFilePath:     funcInfo.RFilePath,
+		Doc:           funcInfo.Doc,
+		Imports:       funcInfo.Imports,
+	}
+	if funcInfo.Receiver != nil {
+		recvType := funcInfo.Receiver.BaseType
+		base.Kind = ChunkKindMethod
+		base.EnclosingType = recvType
+		base.QualifiedName = funcInfo.Pkg + "." + recvType + "." + funcInfo.Name
+		if structInfo, ok := structMap[recvType]; ok {
+			base.EnclosingTypeContent = typeSpecSource(structInfo)
+		}
+	}
+	signature, bounds := splitFuncContent(funcInfo.Content, maxLines)
+	base.Signature = signature
+	chunks := make([]*Chunk, 0, len(bounds))
+	for i, bound := range bounds {
+		chunk := base
+		chunk.Part = i + 1
+		chunk.TotalParts = len(bounds)
+		chunk.Content = strings.TrimRight(funcInfo.Content[bound[0]:bound[1]], " \t\n")
+		chunk.StartLine = funcInfo.StartPosition.Line + strings.Count(funcInfo.Content[:bound[0]], "\n")
+		chunk.EndLine = chunk.StartLine + strings.Count(chunk.Content, "\n")
+		chunks = append(chunks, &chunk)
+	}
+	return chunks
+}
+
+// splitFuncContent 提取函数签名，并在函数过长时按语句边界计算各分片的偏移区间
+func splitFuncContent(content string, maxLines int) (string, [][2]int) {
+	whole := [][2]int{{0, len(content)}}
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "", chunkParsePrefix+content, parser.ParseComments)
+	if err != nil || len(file.Decls) == 0 {
+		return firstLine(content), whole
+	}
+	funcDecl, ok := file.Decls[0].(*ast.FuncDecl)
+	if !ok || funcDecl.Body == nil {
+		return firstLine(content), whole
+	}
+	offset := func(pos token.Pos) int {
+		return fileSet.Position(pos).Offset - len(chunkParsePrefix)
+	}
+	signature := strings.TrimSpace(content[:offset(funcDecl.Body.Lbrace)])
+	if strings.Count(content, "\n")+1 <= maxLines || len(funcDecl.Body.List) < 2 {
+		return signature, whole
+	}
+	bounds := make([][2]int, 0)
+	start := 0
+	for i, stmt := range funcDecl.Body.List {
+		if i == 0 {
+			continue
+		}
+		stmtStart := lineStart(content, offset(stmt.Pos()))
+		stmtEnd := offset(stmt.End())
+		if strings.Count(content[start:stmtEnd], "\n")+1 > maxLines && stmtStart > start {
+			bounds = append(bounds, [2]int{start, stmtStart})
+			start = stmtStart
+		}
+	}
+	bounds = append(bounds, [2]int{start, len(content)})
+	return signature, bounds
+}
+
+// lineStart 返回偏移所在行的行首偏移，保证分片包含语句前的缩进
+func lineStart(content string, offset int) int {
+	return strings.LastIndex(content[:offset], "\n") + 1
+}
+
+func firstLine(content string) string {
+	if idx := strings.Index(content, "\n"); idx >= 0 {
+		return strings.TrimSpace(content[:idx])
+	}
+	return strings.TrimSpace(content)
+}
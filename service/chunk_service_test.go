@@ -0,0 +1,132 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestModule 在临时目录中生成测试模块，files的key为相对路径
+func writeTestModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestChunkModule(t *testing.T) {
+	var body strings.Builder
+	for i := 0; i < 30; i++ {
+		body.WriteString("\tfmt.Println(\"line\")\n")
+	}
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/demo\n\ngo 1.21\n",
+		"demo/demo.go": `package demo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Greeter 打招呼
+type Greeter struct {
+	Name string
+}
+
+// Hello 返回问候语
+func (g *Greeter) Hello() string {
+	return strings.ToUpper(g.Name)
+}
+
+func Long() {
+` + body.String() + `}
+
+type (
+	// ID 标识
+	ID int
+
+	Pair struct {
+		Key   string
+		Value int
+	}
+)
+`,
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := ChunkModule(modInfo, &ChunkConfig{MaxLines: 10})
+	byName := make(map[string][]*Chunk)
+	for _, chunk := range chunks {
+		byName[chunk.QualifiedName] = append(byName[chunk.QualifiedName], chunk)
+	}
+	method := byName["example.com/demo/demo.Greeter.Hello"]
+	if len(method) != 1 {
+		t.Fatalf("expected one method chunk, got %d", len(method))
+	}
+	if method[0].Kind != ChunkKindMethod || method[0].EnclosingType != "Greeter" {
+		t.Errorf("unexpected method chunk: %+v", method[0])
+	}
+	if method[0].Signature != "func (g *Greeter) Hello() string" {
+		t.Errorf("unexpected signature: %q", method[0].Signature)
+	}
+	if method[0].Doc != "Hello 返回问候语\n" || !strings.Contains(method[0].EnclosingTypeContent, "Name string") {
+		t.Errorf("missing doc or enclosing type: %+v", method[0])
+	}
+	if len(method[0].Imports) != 1 || method[0].Imports[0] != "strings" {
+		t.Errorf("unexpected imports: %v", method[0].Imports)
+	}
+	// 分组声明中的每个类型只包含自身的定义和注释
+	id, pair := byName["example.com/demo/demo.ID"], byName["example.com/demo/demo.Pair"]
+	if len(id) != 1 || id[0].Content != "// ID 标识\n\tID int" || id[0].StartLine != 52 || id[0].EndLine != 53 || id[0].Signature != "type ID int" {
+		t.Errorf("unexpected grouped type chunk: %+v", id[0])
+	}
+	if len(pair) != 1 || strings.Contains(pair[0].Content, "ID int") || pair[0].StartLine != 55 || pair[0].EndLine != 58 || pair[0].Signature != "type Pair struct" {
+		t.Errorf("unexpected grouped type chunk: %+v", pair[0])
+	}
+	long := byName["example.com/demo/demo.Long"]
+	if len(long) < 3 {
+		t.Fatalf("expected long func to be split, got %d parts", len(long))
+	}
+	for i, chunk := range long {
+		if chunk.TotalParts != len(long) || chunk.Part != i+1 {
+			t.Errorf("unexpected part numbering: %d/%d", chunk.Part, chunk.TotalParts)
+		}
+		if chunk.EndLine-chunk.StartLine+1 > 10 {
+			t.Errorf("part %d too long: %d-%d", chunk.Part, chunk.StartLine, chunk.EndLine)
+		}
+		if i > 0 && chunk.StartLine != long[i-1].EndLine+1 {
+			t.Errorf("parts are not contiguous: %d after %d", chunk.StartLine, long[i-1].EndLine)
+		}
+	}
+	again := ChunkModule(modInfo, &ChunkConfig{MaxLines: 10})
+	for i := range chunks {
+		if chunks[i].ID != again[i].ID {
+			t.Fatalf("chunk id is not stable: %s != %s", chunks[i].ID, again[i].ID)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteChunksJSONL(&buf, chunks); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(chunks) {
+		t.Fatalf("expected %d jsonl lines, got %d", len(chunks), len(lines))
+	}
+	var decoded Chunk
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil {
+		t.Fatal(err)
+	}
+}
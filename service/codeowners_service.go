@@ -0,0 +1,163 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// codeOwnersLocations 按GitHub的优先级查找CODEOWNERS文件
+var codeOwnersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwnerRule CODEOWNERS中的一条规则
+type CodeOwnerRule struct {
+	Pattern string
+	Owners  []string // 为空表示该路径没有负责人
+	Line    int
+	re      *regexp.Regexp
+}
+
+// CodeOwners 解析后的CODEOWNERS文件
+type CodeOwners struct {
+	Root  string // 仓库根目录，规则中的路径相对于该目录
+	Path  string // CODEOWNERS文件路径
+	Rules []*CodeOwnerRule
+}
+
+// LoadCodeOwners 从dir开始向上查找CODEOWNERS直到仓库根目录（包含.git的目录），找不到时返回nil
+func LoadCodeOwners(dir string) (*CodeOwners, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		for _, location := range codeOwnersLocations {
+			ownersPath := filepath.Join(dir, filepath.FromSlash(location))
+			content, err := os.ReadFile(ownersPath)
+			if err == nil {
+				codeOwners, err := ParseCodeOwners(dir, content)
+				if err != nil {
+					return nil, fmt.Errorf("解析%s失败: %w", ownersPath, err)
+				}
+				codeOwners.Path = ownersPath
+				return codeOwners, nil
+			}
+			if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("读取%s失败: %w", ownersPath, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return nil, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// ParseCodeOwners 解析CODEOWNERS内容，root为规则路径的根目录
+func ParseCodeOwners(root string, content []byte) (*CodeOwners, error) {
+	codeOwners := &CodeOwners{Root: root}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = line[:index]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		re, err := codeOwnersPatternRegexp(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("第%d行的模式%s非法: %w", lineNo, fields[0], err)
+		}
+		codeOwners.Rules = append(codeOwners.Rules, &CodeOwnerRule{
+			Pattern: fields[0],
+			Owners:  fields[1:],
+			Line:    lineNo,
+			re:      re,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return codeOwners, nil
+}
+
+// codeOwnersPatternRegexp 将gitignore风格的模式转换为正则：以/开头或中间包含/的模式相对根目录，
+// 否则匹配任意层级；匹配到目录时包含其下所有文件，dir/*只匹配目录下的直接文件
+func codeOwnersPatternRegexp(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	var builder strings.Builder
+	builder.WriteString("^")
+	if !anchored {
+		builder.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			builder.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			builder.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			builder.WriteString("[^/]*")
+		case pattern[i] == '?':
+			builder.WriteString("[^/]")
+		default:
+			builder.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	switch {
+	case dirOnly:
+		builder.WriteString("/.*$")
+	case strings.HasSuffix(pattern, "/*"):
+		builder.WriteString("$")
+	default:
+		builder.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(builder.String())
+}
+
+// Owners 返回相对仓库根目录的文件路径的负责人，后出现的规则优先
+func (c *CodeOwners) Owners(relPath string) []string {
+	if c == nil {
+		return nil
+	}
+	relPath = strings.TrimPrefix(filepath.ToSlash(relPath), "/")
+	for i := len(c.Rules) - 1; i >= 0; i-- {
+		if c.Rules[i].re.MatchString(relPath) {
+			return c.Rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// OwnersForFile 返回文件的负责人，文件不在仓库根目录下时返回nil
+func (c *CodeOwners) OwnersForFile(filePath string) []string {
+	if c == nil {
+		return nil
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil
+	}
+	relPath, err := filepath.Rel(c.Root, absPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return nil
+	}
+	return c.Owners(relPath)
+}
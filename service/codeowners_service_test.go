@@ -0,0 +1,59 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodeOwners(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		".github/CODEOWNERS": `# default owners
+*       @org/all
+*.md    @org/docs
+/svc/   @org/svc # service code
+docs/*  @org/docs-top
+**/internal/** @org/internal
+/svc/gen/
+`,
+		"svc/go.mod": "module example.com/svc\n",
+	})
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	codeOwners, err := LoadCodeOwners(filepath.Join(root, "svc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codeOwners == nil || codeOwners.Root != root || len(codeOwners.Rules) != 6 {
+		t.Fatalf("unexpected code owners: %+v", codeOwners)
+	}
+	cases := map[string]string{
+		"main.go":                  "@org/all",
+		"README.md":                "@org/docs",
+		"svc/handler/handler.go":   "@org/svc",
+		"other/svc/handler.go":     "@org/all",
+		"docs/index.html":          "@org/docs-top",
+		"docs/api/index.html":      "@org/all",
+		"svc/pkg/internal/util.go": "@org/internal",
+		"svc/gen/model.go":         "",
+	}
+	for relPath, expected := range cases {
+		if got := strings.Join(codeOwners.Owners(relPath), ","); got != expected {
+			t.Errorf("%s: expected %q, got %q", relPath, expected, got)
+		}
+	}
+	if got := codeOwners.OwnersForFile(filepath.Join(root, "svc", "a.go")); len(got) != 1 || got[0] != "@org/svc" {
+		t.Errorf("unexpected owners for file: %v", got)
+	}
+	// 到达仓库根目录后不再继续向上查找
+	other := writeTestModule(t, map[string]string{"pkg/a.go": "package pkg\n"})
+	if err := os.Mkdir(filepath.Join(other, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	empty, err := LoadCodeOwners(filepath.Join(other, "pkg"))
+	if err != nil || empty != nil || empty.Owners("main.go") != nil {
+		t.Errorf("expected no code owners, got %+v, %v", empty, err)
+	}
+}
@@ -0,0 +1,492 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+)
+
+const (
+	ConcurrencyGo        = "go"        // go语句
+	ConcurrencyMakeChan  = "make_chan" // 创建channel
+	ConcurrencySend      = "send"      // channel发送
+	ConcurrencyReceive   = "receive"   // channel接收，包括range
+	ConcurrencyClose     = "close"     // 关闭channel
+	ConcurrencySelect    = "select"    // select语句
+	ConcurrencyLock      = "lock"      // sync.Mutex/RWMutex加锁
+	ConcurrencyUnlock    = "unlock"    // sync.Mutex/RWMutex解锁
+	ConcurrencyWaitGroup = "waitgroup" // sync.WaitGroup调用
+	ConcurrencyDefer     = "defer"     // defer语句
+	ConcurrencyContext   = "context"   // context的接收、派生、传递和监听
+
+	ConcurrencyWarnGoroutineNoCancel = "goroutine_no_cancel" // goroutine中有无条件循环但没有退出途径
+	ConcurrencyWarnLockNotReleased   = "lock_not_released"   // 锁没有在所有路径上释放
+)
+
+// concurrencyLockOps sync锁方法对应的事件类型
+var concurrencyLockOps = map[string]string{
+	"Lock":    ConcurrencyLock,
+	"RLock":   ConcurrencyLock,
+	"TryLock": ConcurrencyLock,
+	"Unlock":  ConcurrencyUnlock,
+	"RUnlock": ConcurrencyUnlock,
+}
+
+// concurrencyUnlockOf 加锁方法对应的解锁方法，TryLock的结果需要判断因此不检查
+var concurrencyUnlockOf = map[string]string{"Lock": "Unlock", "RLock": "RUnlock"}
+
+// ConcurrencyEvent 函数中的一处并发相关操作
+type ConcurrencyEvent struct {
+	Kind      string `json:"kind"`               // 操作类型
+	Op        string `json:"op,omitempty"`       // 锁和WaitGroup为方法名，context为param、pass或context包函数、Context方法名，make_chan为buffered，select为default，range接收为range
+	Target    string `json:"target"`             // go和defer为执行的函数，channel操作为channel表达式，锁和WaitGroup为接收者表达式，context为变量或被调用函数
+	Deferred  bool   `json:"deferred,omitempty"` // 是否在defer中执行
+	RFilePath string `json:"file"`               // 所在文件
+	Line      int    `json:"line"`               // 行号
+	Column    int    `json:"column"`             // 列号
+
+	pos   token.Pos
+	block ast.Node // 所在的最内层语句块
+}
+
+// ConcurrencyWarning 启发式检查发现的并发问题
+type ConcurrencyWarning struct {
+	Kind      string `json:"kind"`    // 问题类型
+	Target    string `json:"target"`  // goroutine执行的函数或锁的接收者表达式
+	Message   string `json:"message"` // 问题说明
+	RFilePath string `json:"file"`    // 所在文件
+	Line      int    `json:"line"`    // 行号
+	Column    int    `json:"column"`  // 列号
+}
+
+// FuncConcurrency 函数中的并发操作和检查出的问题
+type FuncConcurrency struct {
+	Pkg       string                `json:"pkg"`      // 所在包
+	Func      string                `json:"func"`     // 函数名，方法为Type.Method，匿名函数为Parent$N
+	RFilePath string                `json:"file"`     // 所在文件
+	Line      int                   `json:"line"`     // 函数起始行
+	Events    []*ConcurrencyEvent   `json:"events"`   // 按位置排序的并发操作
+	Warnings  []*ConcurrencyWarning `json:"warnings"` // 检查出的问题
+	FuncInfo  *vs.FuncInfo          `json:"-"`
+
+	loops           bool                 // 是否有无条件的for循环
+	cancellable     bool                 // 是否使用了context、select或channel接收
+	returns         []*ast.ReturnStmt    // 不含匿名函数中的return
+	deferredUnlocks map[string]bool      // defer的匿名函数中释放的锁，键为方法名:接收者
+	launches        []*concurrencyLaunch // go语句启动的goroutine
+}
+
+// ConcurrencyInventory 模块的并发操作清单
+type ConcurrencyInventory struct {
+	Module   string             `json:"module"`
+	Counts   map[string]int     `json:"counts"`   // 各类操作的数量
+	Warnings map[string]int     `json:"warnings"` // 各类问题的数量
+	Funcs    []*FuncConcurrency `json:"funcs"`    // 有并发操作的函数
+}
+
+// concurrencyLaunch go语句及其启动的模块内函数
+type concurrencyLaunch struct {
+	event     *ConcurrencyEvent
+	fn        *moduleFunc // 启动的函数，无法静态确定或不在模块内时为nil
+	passesCtx bool        // 调用参数中是否有context.Context
+}
+
+// BuildConcurrencyInventory 记录模块内每个函数的go语句、channel操作、select、锁、WaitGroup、defer和context传递，
+// 并启发式检查没有退出途径的goroutine和没有在所有路径上释放的锁
+func BuildConcurrencyInventory(ctx context.Context, modInfo *ModuleInfo) (*ConcurrencyInventory, error) {
+	funcs, err := loadModuleFuncs(ctx, modInfo)
+	if err != nil {
+		return nil, err
+	}
+	byNode := make(map[ast.Node]*moduleFunc, len(funcs))
+	byObj := make(map[*types.Func]*moduleFunc, len(funcs))
+	for _, fn := range funcs {
+		byNode[fn.node] = fn
+		if fn.obj != nil {
+			byObj[fn.obj] = fn
+		}
+	}
+	flows := make(map[*moduleFunc]*FuncConcurrency, len(funcs))
+	for _, fn := range funcs {
+		w := &concurrencyWalker{fn: fn, byNode: byNode, byObj: byObj, flow: &FuncConcurrency{
+			Pkg:             fn.pkg.PkgPath,
+			Func:            fn.name,
+			RFilePath:       fn.rFilePath,
+			Line:            fn.line,
+			Events:          make([]*ConcurrencyEvent, 0),
+			Warnings:        make([]*ConcurrencyWarning, 0),
+			FuncInfo:        fn.funcInfo,
+			deferredUnlocks: make(map[string]bool),
+		}}
+		w.walk()
+		w.checkLocks()
+		flows[fn] = w.flow
+	}
+	inventory := &ConcurrencyInventory{
+		Module:   modInfo.Path,
+		Counts:   make(map[string]int),
+		Warnings: make(map[string]int),
+		Funcs:    make([]*FuncConcurrency, 0),
+	}
+	for _, fn := range funcs {
+		flow := flows[fn]
+		checkLaunches(flow, flows)
+		if len(flow.Events) == 0 {
+			continue
+		}
+		for _, event := range flow.Events {
+			inventory.Counts[event.Kind]++
+		}
+		for _, warning := range flow.Warnings {
+			inventory.Warnings[warning.Kind]++
+		}
+		inventory.Funcs = append(inventory.Funcs, flow)
+	}
+	return inventory, nil
+}
+
+// checkLaunches 启动的函数中有无条件循环，却既不使用context、select、channel接收，也没有被传入context时，认为goroutine无法退出
+func checkLaunches(flow *FuncConcurrency, flows map[*moduleFunc]*FuncConcurrency) {
+	for _, launch := range flow.launches {
+		if launch.fn == nil || launch.passesCtx {
+			continue
+		}
+		target := flows[launch.fn]
+		if !target.loops || target.cancellable {
+			continue
+		}
+		flow.Warnings = append(flow.Warnings, &ConcurrencyWarning{
+			Kind:      ConcurrencyWarnGoroutineNoCancel,
+			Target:    launch.event.Target,
+			Message:   fmt.Sprintf("goroutine %s 中存在无条件for循环，但没有context、select或channel接收可以让它退出", launch.event.Target),
+			RFilePath: launch.event.RFilePath,
+			Line:      launch.event.Line,
+			Column:    launch.event.Column,
+		})
+	}
+}
+
+type concurrencyWalker struct {
+	fn     *moduleFunc
+	byNode map[ast.Node]*moduleFunc
+	byObj  map[*types.Func]*moduleFunc
+	flow   *FuncConcurrency
+	stack  []ast.Node
+}
+
+// walk 按源码顺序遍历函数体，不进入嵌套的匿名函数
+func (w *concurrencyWalker) walk() {
+	w.params()
+	ast.Inspect(w.fn.body, func(node ast.Node) bool {
+		if node == nil {
+			w.stack = w.stack[:len(w.stack)-1]
+			return true
+		}
+		if _, ok := node.(*ast.FuncLit); ok {
+			return false
+		}
+		w.visit(node)
+		w.stack = append(w.stack, node)
+		return true
+	})
+}
+
+// params 记录context.Context类型的参数
+func (w *concurrencyWalker) params() {
+	var signature *types.Signature
+	if w.fn.obj != nil {
+		signature, _ = w.fn.obj.Type().(*types.Signature)
+	} else {
+		signature, _ = w.fn.pkg.TypesInfo.TypeOf(w.fn.node.(*ast.FuncLit)).(*types.Signature)
+	}
+	if signature == nil {
+		return
+	}
+	for i := 0; i < signature.Params().Len(); i++ {
+		param := signature.Params().At(i)
+		if isContextType(param.Type()) {
+			w.flow.cancellable = true
+			w.addEvent(ConcurrencyContext, "param", param.Name(), param.Pos())
+		}
+	}
+}
+
+// visit 记录单个节点上的并发操作
+func (w *concurrencyWalker) visit(node ast.Node) {
+	info := w.fn.pkg.TypesInfo
+	switch n := node.(type) {
+	case *ast.GoStmt:
+		event := w.addEvent(ConcurrencyGo, "", w.launchTarget(n.Call), n.Pos())
+		launch := &concurrencyLaunch{event: event}
+		if lit, ok := ast.Unparen(n.Call.Fun).(*ast.FuncLit); ok {
+			launch.fn = w.byNode[lit]
+		} else if callee := staticCalleeOf(info, n.Call); callee != nil {
+			launch.fn = w.byObj[callee]
+		}
+		for _, arg := range n.Call.Args {
+			if isContextType(info.TypeOf(arg)) {
+				launch.passesCtx = true
+			}
+		}
+		w.flow.launches = append(w.flow.launches, launch)
+	case *ast.DeferStmt:
+		w.addEvent(ConcurrencyDefer, "", w.launchTarget(n.Call), n.Pos())
+		if lit, ok := ast.Unparen(n.Call.Fun).(*ast.FuncLit); ok {
+			w.deferredLitUnlocks(lit)
+		}
+	case *ast.SendStmt:
+		w.addEvent(ConcurrencySend, "", types.ExprString(n.Chan), n.Arrow)
+	case *ast.UnaryExpr:
+		if n.Op == token.ARROW {
+			w.flow.cancellable = true
+			w.addEvent(ConcurrencyReceive, "", types.ExprString(n.X), n.OpPos)
+		}
+	case *ast.RangeStmt:
+		if _, ok := typeUnderlying(info.TypeOf(n.X)).(*types.Chan); ok {
+			w.flow.cancellable = true
+			w.addEvent(ConcurrencyReceive, "range", types.ExprString(n.X), n.For)
+		}
+	case *ast.ReturnStmt:
+		w.flow.returns = append(w.flow.returns, n)
+	case *ast.ForStmt:
+		if n.Cond == nil {
+			w.flow.loops = true
+		}
+	case *ast.SelectStmt:
+		w.flow.cancellable = true
+		op := ""
+		for _, clause := range n.Body.List {
+			if clause.(*ast.CommClause).Comm == nil {
+				op = "default"
+			}
+		}
+		w.addEvent(ConcurrencySelect, op, "", n.Select)
+	case *ast.CallExpr:
+		w.visitCall(n)
+	}
+}
+
+// visitCall 记录channel的创建和关闭、锁、WaitGroup以及context相关的调用
+func (w *concurrencyWalker) visitCall(call *ast.CallExpr) {
+	info := w.fn.pkg.TypesInfo
+	if ident, ok := ast.Unparen(call.Fun).(*ast.Ident); ok {
+		if builtin, ok := info.Uses[ident].(*types.Builtin); ok {
+			switch {
+			case builtin.Name() == "make" && len(call.Args) > 0:
+				if _, ok := typeUnderlying(info.TypeOf(call.Args[0])).(*types.Chan); ok {
+					op := ""
+					if len(call.Args) > 1 {
+						op = "buffered"
+					}
+					w.addEvent(ConcurrencyMakeChan, op, types.TypeString(info.TypeOf(call.Args[0]), types.RelativeTo(w.fn.pkg.Types)), call.Pos())
+				}
+			case builtin.Name() == "close" && len(call.Args) == 1:
+				w.addEvent(ConcurrencyClose, "", types.ExprString(call.Args[0]), call.Pos())
+			}
+			return
+		}
+	}
+	name := calleeFullName(info, call)
+	if kind, op, ok := syncMethod(name); ok {
+		target := ""
+		if selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+			target = types.ExprString(selector.X)
+		}
+		w.addEvent(kind, op, target, call.Pos())
+		return
+	}
+	if strings.HasPrefix(name, "context.") {
+		w.flow.cancellable = true
+		w.addEvent(ConcurrencyContext, strings.TrimPrefix(name, "context."), name, call.Pos())
+		return
+	}
+	if strings.HasPrefix(name, "(context.Context).") {
+		w.flow.cancellable = true
+		selector, _ := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+		w.addEvent(ConcurrencyContext, strings.TrimPrefix(name, "(context.Context)."), types.ExprString(selector.X), call.Pos())
+		return
+	}
+	for _, arg := range call.Args {
+		if isContextType(info.TypeOf(arg)) {
+			w.flow.cancellable = true
+			w.addEvent(ConcurrencyContext, "pass", types.ExprString(call.Fun), call.Pos())
+			return
+		}
+	}
+}
+
+// deferredLitUnlocks 记录defer的匿名函数中直接释放的锁
+func (w *concurrencyWalker) deferredLitUnlocks(lit *ast.FuncLit) {
+	ast.Inspect(lit.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if kind, op, ok := syncMethod(calleeFullName(w.fn.pkg.TypesInfo, call)); ok && kind == ConcurrencyUnlock {
+			if selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+				w.flow.deferredUnlocks[op+":"+types.ExprString(selector.X)] = true
+			}
+		}
+		return true
+	})
+}
+
+// syncMethod 解析sync.Mutex、sync.RWMutex、sync.Locker和sync.WaitGroup的方法调用
+func syncMethod(name string) (string, string, bool) {
+	for _, prefix := range []string{"(*sync.Mutex).", "(*sync.RWMutex).", "(sync.Locker)."} {
+		if op, ok := strings.CutPrefix(name, prefix); ok {
+			kind, ok := concurrencyLockOps[op]
+			return kind, op, ok
+		}
+	}
+	if op, ok := strings.CutPrefix(name, "(*sync.WaitGroup)."); ok {
+		return ConcurrencyWaitGroup, op, true
+	}
+	return "", "", false
+}
+
+// launchTarget 返回go或defer执行的函数，匿名函数为其展示名
+func (w *concurrencyWalker) launchTarget(call *ast.CallExpr) string {
+	if lit, ok := ast.Unparen(call.Fun).(*ast.FuncLit); ok {
+		if fn := w.byNode[lit]; fn != nil && fn.name != "" {
+			return fn.name
+		}
+		return "func literal"
+	}
+	if callee := staticCalleeOf(w.fn.pkg.TypesInfo, call); callee != nil {
+		return callee.FullName()
+	}
+	return types.ExprString(call.Fun)
+}
+
+// checkLocks 加锁后没有defer释放时，要求之后的每个return和加锁所在语句块的结尾之前都有同一语句块或外层语句块中的释放
+func (w *concurrencyWalker) checkLocks() {
+	for _, lock := range w.flow.Events {
+		unlockOp, ok := concurrencyUnlockOf[lock.Op]
+		if lock.Kind != ConcurrencyLock || !ok || lock.Deferred || w.flow.deferredUnlocks[unlockOp+":"+lock.Target] {
+			continue
+		}
+		unlocks := make([]*ConcurrencyEvent, 0)
+		deferred := false
+		for _, event := range w.flow.Events {
+			if event.Kind != ConcurrencyUnlock || event.Op != unlockOp || event.Target != lock.Target {
+				continue
+			}
+			if event.Deferred {
+				deferred = true
+			} else if event.pos > lock.pos {
+				unlocks = append(unlocks, event)
+			}
+		}
+		if deferred {
+			continue
+		}
+		if len(unlocks) == 0 {
+			w.addWarning(lock, lock.pos, fmt.Sprintf("%s.%s 之后没有对应的 %s", lock.Target, lock.Op, unlockOp))
+			continue
+		}
+		last := unlocks[len(unlocks)-1].pos
+		for _, ret := range w.flow.returns {
+			if ret.Pos() > lock.pos && ret.Pos() < last && !releasedBefore(unlocks, lock.pos, ret.Pos()) {
+				w.addWarning(lock, ret.Pos(), fmt.Sprintf("return 前没有释放 %s.%s 加的锁", lock.Target, lock.Op))
+			}
+		}
+		if end, ok := blockFallthrough(lock.block); ok && !releasedBefore(unlocks, lock.pos, end) {
+			w.addWarning(lock, lock.pos, fmt.Sprintf("%s.%s 只在部分分支中释放", lock.Target, lock.Op))
+		}
+	}
+}
+
+// releasedBefore 判断加锁之后、pos之前是否有所在语句块包含pos的释放
+func releasedBefore(unlocks []*ConcurrencyEvent, lockPos, pos token.Pos) bool {
+	for _, unlock := range unlocks {
+		if unlock.pos > lockPos && unlock.pos < pos && unlock.block.Pos() <= pos && pos < unlock.block.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// blockFallthrough 语句块的最后一条语句不是return或panic时，返回语句块结尾的位置
+func blockFallthrough(block ast.Node) (token.Pos, bool) {
+	var list []ast.Stmt
+	switch b := block.(type) {
+	case *ast.BlockStmt:
+		list = b.List
+	case *ast.CaseClause:
+		list = b.Body
+	case *ast.CommClause:
+		list = b.Body
+	default:
+		return token.NoPos, false
+	}
+	if len(list) > 0 {
+		switch last := list[len(list)-1].(type) {
+		case *ast.ReturnStmt:
+			return token.NoPos, false
+		case *ast.ExprStmt:
+			if call, ok := last.X.(*ast.CallExpr); ok && types.ExprString(call.Fun) == "panic" {
+				return token.NoPos, false
+			}
+		}
+	}
+	return block.End() - 1, true
+}
+
+// addEvent 记录并发操作，defer语句中的操作标记为Deferred
+func (w *concurrencyWalker) addEvent(kind, op, target string, pos token.Pos) *ConcurrencyEvent {
+	position := w.fn.pkg.Fset.Position(pos)
+	event := &ConcurrencyEvent{
+		Kind:      kind,
+		Op:        op,
+		Target:    target,
+		RFilePath: w.fn.rFilePath,
+		Line:      position.Line,
+		Column:    position.Column,
+		pos:       pos,
+		block:     w.fn.body,
+	}
+	for i := len(w.stack) - 1; i >= 0; i-- {
+		switch w.stack[i].(type) {
+		case *ast.DeferStmt:
+			event.Deferred = kind != ConcurrencyDefer
+		case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+			if event.block == w.fn.body {
+				event.block = w.stack[i]
+			}
+		}
+	}
+	w.flow.Events = append(w.flow.Events, event)
+	return event
+}
+
+// addWarning 记录锁相关的问题
+func (w *concurrencyWalker) addWarning(lock *ConcurrencyEvent, pos token.Pos, message string) {
+	position := w.fn.pkg.Fset.Position(pos)
+	w.flow.Warnings = append(w.flow.Warnings, &ConcurrencyWarning{
+		Kind:      ConcurrencyWarnLockNotReleased,
+		Target:    lock.Target,
+		Message:   message,
+		RFilePath: w.fn.rFilePath,
+		Line:      position.Line,
+		Column:    position.Column,
+	})
+}
+
+// isContextType 判断类型是否为context.Context
+func isContextType(t types.Type) bool {
+	return t != nil && types.TypeString(t, nil) == "context.Context"
+}
+
+// typeUnderlying 返回类型的底层类型，类型未知时返回nil
+func typeUnderlying(t types.Type) types.Type {
+	if t == nil {
+		return nil
+	}
+	return t.Underlying()
+}
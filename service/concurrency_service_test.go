@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBuildConcurrencyInventory(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"worker/worker.go": `package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Cache struct {
+	mu    sync.RWMutex
+	items map[string]int
+}
+
+func (c *Cache) Get(key string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.items[key]
+}
+
+func (c *Cache) Set(key string, value int) bool {
+	c.mu.Lock()
+	if value < 0 {
+		return false
+	}
+	c.items[key] = value
+	c.mu.Unlock()
+	return true
+}
+
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	if _, ok := c.items[key]; !ok {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.items, key)
+	c.mu.Unlock()
+}
+
+func poll() {
+	for {
+		time.Sleep(time.Second)
+	}
+}
+
+func Start(ctx context.Context, jobs []int) {
+	results := make(chan int, len(jobs))
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job int) {
+			defer wg.Done()
+			results <- job
+		}(job)
+	}
+	wg.Wait()
+	close(results)
+	go poll()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+	for range results {
+	}
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	run(child)
+}
+
+func run(ctx context.Context) {}
+`,
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inventory, err := BuildConcurrencyInventory(context.Background(), modInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flows := make(map[string]*FuncConcurrency)
+	for _, flow := range inventory.Funcs {
+		flows[flow.Func] = flow
+	}
+	describe := func(flow *FuncConcurrency) string {
+		events := make([]string, 0, len(flow.Events))
+		for _, event := range flow.Events {
+			item := event.Kind + ":" + event.Op + ":" + event.Target
+			if event.Deferred {
+				item += ":deferred"
+			}
+			events = append(events, item)
+		}
+		return strings.Join(events, ",")
+	}
+	if got := describe(flows["Cache.Get"]); got != "lock:RLock:c.mu,defer::(*sync.RWMutex).RUnlock,unlock:RUnlock:c.mu:deferred" || len(flows["Cache.Get"].Warnings) != 0 {
+		t.Errorf("Cache.Get事件错误: %s %+v", got, flows["Cache.Get"].Warnings)
+	}
+	set := flows["Cache.Set"]
+	if len(set.Warnings) != 1 || set.Warnings[0].Kind != ConcurrencyWarnLockNotReleased || set.Warnings[0].Line != 23 {
+		t.Errorf("Cache.Set应在return处报告未释放锁: %+v", set.Warnings)
+	}
+	if len(flows["Cache.Delete"].Warnings) != 0 {
+		t.Errorf("Cache.Delete不应报告问题: %+v", flows["Cache.Delete"].Warnings)
+	}
+	start := flows["Start"]
+	want := "context:param:ctx,make_chan:buffered:chan int,waitgroup:Add:wg,go::Start$1,waitgroup:Wait:wg,close::results," +
+		"go::example.com/app/worker.poll,go::Start$2,receive:range:results,context:WithCancel:context.WithCancel,defer::cancel,context:pass:run"
+	if got := describe(start); got != want {
+		t.Errorf("Start事件错误:\n%s\n%s", got, want)
+	}
+	if len(start.Warnings) != 1 || start.Warnings[0].Kind != ConcurrencyWarnGoroutineNoCancel || start.Warnings[0].Target != "example.com/app/worker.poll" {
+		t.Errorf("Start应只报告poll无法退出: %+v", start.Warnings)
+	}
+	if got := describe(flows["Start$1"]); got != "defer::(*sync.WaitGroup).Done,waitgroup:Done:wg:deferred,send::results" {
+		t.Errorf("Start$1事件错误: %s", got)
+	}
+	if got := describe(flows["Start$2"]); got != "select::,receive::ctx.Done(),context:Done:ctx,receive::time.After(time.Second)" {
+		t.Errorf("Start$2事件错误: %s", got)
+	}
+	if inventory.Warnings[ConcurrencyWarnLockNotReleased] != 1 || inventory.Counts[ConcurrencyGo] != 3 {
+		t.Errorf("统计错误: %v %v", inventory.Counts, inventory.Warnings)
+	}
+}
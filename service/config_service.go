@@ -0,0 +1,499 @@
+package service
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+)
+
+const (
+	ConfigSourceEnv   = "env"
+	ConfigSourceFlag  = "flag"
+	ConfigSourceViper = "viper"
+)
+
+const (
+	configImportOs    = "os"
+	configImportFlag  = "flag"
+	configImportPflag = "github.com/spf13/pflag"
+	configImportViper = "github.com/spf13/viper"
+	configImportCobra = "github.com/spf13/cobra"
+)
+
+// configFlagTypes flag和pflag中定义flag的函数名去掉Var、P等后缀后的类型
+var configFlagTypes = map[string]bool{
+	"String": true, "Bool": true, "Int": true, "Int8": true, "Int16": true, "Int32": true, "Int64": true,
+	"Uint": true, "Uint8": true, "Uint16": true, "Uint32": true, "Uint64": true, "Float32": true, "Float64": true,
+	"Duration": true, "Func": true, "BoolFunc": true, "Count": true, "IP": true, "IPMask": true,
+	"IPNet": true, "BytesHex": true, "BytesBase64": true,
+	"StringSlice": true, "StringArray": true, "StringToString": true, "StringToInt": true, "StringToInt64": true,
+	"IntSlice": true, "Int32Slice": true, "Int64Slice": true, "UintSlice": true, "BoolSlice": true,
+	"Float32Slice": true, "Float64Slice": true, "DurationSlice": true, "IPSlice": true,
+}
+
+// ConfigInput 一次读取环境变量、命令行flag或viper配置的调用
+type ConfigInput struct {
+	Source      string       `json:"source"`            // env、flag或viper
+	Call        string       `json:"call"`              // 读取配置的调用，如os.Getenv、flag.String或模块内的封装函数
+	Key         string       `json:"key"`               // 环境变量名、flag名或配置键，无法解析的部分为<表达式>
+	Resolved    bool         `json:"resolved"`          // 键是否为字面量或可解析的常量
+	Type        string       `json:"type,omitempty"`    // 配置值的类型
+	Default     string       `json:"default,omitempty"` // 默认值，字符串字面量会去掉引号，其他为表达式源码
+	Usage       string       `json:"usage,omitempty"`   // flag的用法说明
+	Pkg         string       `json:"pkg"`               // 所在包
+	Func        string       `json:"func,omitempty"`    // 所在函数，包级变量初始化时为空
+	RFilePath   string       `json:"file"`              // 所在文件
+	Line        int          `json:"line"`              // 行号
+	Column      int          `json:"column"`            // 列号
+	FuncInfo    *vs.FuncInfo `json:"-"`
+	defaultExpr ast.Expr
+}
+
+// BinaryConfig 一个可执行程序（main包）通过导入关系可能读取的配置
+type BinaryConfig struct {
+	Pkg    string         `json:"pkg"`    // main包路径
+	Env    []string       `json:"env"`    // 去重排序后的环境变量名
+	Flags  []string       `json:"flags"`  // 去重排序后的flag名
+	Viper  []string       `json:"viper"`  // 去重排序后的viper配置键
+	Inputs []*ConfigInput `json:"inputs"` // 全部配置读取
+}
+
+// ConfigInventory 模块的配置读取清单
+type ConfigInventory struct {
+	Module   string          `json:"module"`
+	Inputs   []*ConfigInput  `json:"inputs"`   // 按文件和位置排序的配置读取
+	Binaries []*BinaryConfig `json:"binaries"` // 按main包路径排序
+}
+
+// configWrapper 模块内对os.Getenv/os.LookupEnv的封装函数，如getEnv(key, def string)
+type configWrapper struct {
+	keyArg     int
+	defaultArg int // 没有默认值参数时为-1
+}
+
+type configExtractor struct {
+	modInfo   *ModuleInfo
+	fileSet   *token.FileSet
+	files     []*moduleGoFile
+	consts    stringConsts
+	fileFuncs map[string][]*vs.FuncInfo
+	wrappers  map[string]*configWrapper // 包路径.函数名 -> 封装函数
+	mainPkgs  map[string]bool
+	inputs    []*ConfigInput
+	defaults  map[string]string // 小写的viper键 -> SetDefault设置的默认值
+}
+
+// BuildConfigInventory 提取模块内所有环境变量、flag/pflag以及viper配置的读取，包括模块内对os.Getenv的简单封装，
+// 并通过导入图计算每个main包可能读取的配置；graph为nil时只统计main包自身目录下的读取
+func BuildConfigInventory(modInfo *ModuleInfo, graph *ImportGraph) (*ConfigInventory, error) {
+	e := &configExtractor{
+		modInfo:   modInfo,
+		fileSet:   token.NewFileSet(),
+		consts:    make(stringConsts),
+		fileFuncs: moduleFileFuncs(modInfo),
+		wrappers:  make(map[string]*configWrapper),
+		mainPkgs:  make(map[string]bool),
+		defaults:  make(map[string]string),
+	}
+	files, err := parseModuleGoFiles(modInfo, e.fileSet)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		for _, decl := range file.file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				e.consts.collect(file.pkg, decl)
+			case *ast.FuncDecl:
+				if file.file.Name.Name == "main" && decl.Name.Name == "main" && decl.Recv == nil {
+					e.mainPkgs[file.pkg] = true
+				}
+				e.collectWrapper(file, decl)
+			}
+		}
+	}
+	e.files = files
+	for _, file := range e.files {
+		e.extract(file)
+	}
+	e.applyViperDefaults()
+	sort.SliceStable(e.inputs, func(i, j int) bool {
+		if e.inputs[i].RFilePath != e.inputs[j].RFilePath {
+			return e.inputs[i].RFilePath < e.inputs[j].RFilePath
+		}
+		if e.inputs[i].Line != e.inputs[j].Line {
+			return e.inputs[i].Line < e.inputs[j].Line
+		}
+		return e.inputs[i].Column < e.inputs[j].Column
+	})
+	inventory := &ConfigInventory{Module: modInfo.Path, Inputs: e.inputs, Binaries: make([]*BinaryConfig, 0)}
+	for _, mainPkg := range sortedMapKeys(e.mainPkgs) {
+		reachable := reachablePkgs(graph, mainPkg)
+		binary := &BinaryConfig{Pkg: mainPkg, Inputs: make([]*ConfigInput, 0)}
+		keys := map[string]map[string]bool{ConfigSourceEnv: {}, ConfigSourceFlag: {}, ConfigSourceViper: {}}
+		for _, input := range e.inputs {
+			if reachable[input.Pkg] {
+				binary.Inputs = append(binary.Inputs, input)
+				keys[input.Source][input.Key] = true
+			}
+		}
+		binary.Env = sortedMapKeys(keys[ConfigSourceEnv])
+		binary.Flags = sortedMapKeys(keys[ConfigSourceFlag])
+		binary.Viper = sortedMapKeys(keys[ConfigSourceViper])
+		inventory.Binaries = append(inventory.Binaries, binary)
+	}
+	return inventory, nil
+}
+
+// reachablePkgs 返回从pkg出发通过导入图可达的仓库内包，包含pkg自身
+func reachablePkgs(graph *ImportGraph, pkg string) map[string]bool {
+	reachable := map[string]bool{pkg: true}
+	if graph == nil {
+		return reachable
+	}
+	queue := []string{pkg}
+	for len(queue) > 0 {
+		node, ok := graph.Nodes[queue[0]]
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+		for _, edge := range node.Imports {
+			if edge.Kind != ImportKindIntraModule || reachable[edge.To] {
+				continue
+			}
+			reachable[edge.To] = true
+			queue = append(queue, edge.To)
+		}
+	}
+	return reachable
+}
+
+// collectWrapper 识别以参数作为环境变量名调用os.Getenv或os.LookupEnv的函数，另一个string参数视为默认值
+func (e *configExtractor) collectWrapper(file *moduleGoFile, decl *ast.FuncDecl) {
+	if decl.Body == nil || decl.Recv != nil {
+		return
+	}
+	params := make([]string, 0)
+	stringParams := make([]bool, 0)
+	for _, field := range decl.Type.Params.List {
+		ident, isString := field.Type.(*ast.Ident)
+		for _, name := range field.Names {
+			params = append(params, name.Name)
+			stringParams = append(stringParams, isString && ident.Name == "string")
+		}
+	}
+	keyArg := -1
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || keyArg >= 0 || len(call.Args) != 1 {
+			return keyArg < 0
+		}
+		if name := file.importedCall(call.Fun, configImportOs); name != "Getenv" && name != "LookupEnv" {
+			return true
+		}
+		if ident, ok := call.Args[0].(*ast.Ident); ok {
+			for i, param := range params {
+				if param == ident.Name {
+					keyArg = i
+				}
+			}
+		}
+		return true
+	})
+	if keyArg < 0 {
+		return
+	}
+	wrapper := &configWrapper{keyArg: keyArg, defaultArg: -1}
+	if len(params) == 2 && stringParams[1-keyArg] {
+		wrapper.defaultArg = 1 - keyArg
+	}
+	e.wrappers[file.pkg+"."+decl.Name.Name] = wrapper
+}
+
+// importedCall 调用的函数为指定包的导出函数时返回函数名
+func (f *moduleGoFile) importedCall(fun ast.Expr, importPath string) string {
+	selector, ok := fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	if ident, ok := selector.X.(*ast.Ident); ok && f.imports[ident.Name] == importPath {
+		return selector.Sel.Name
+	}
+	return ""
+}
+
+// extract 提取文件中的配置读取，FlagSet和viper实例按赋值来源或声明类型识别
+func (e *configExtractor) extract(file *moduleGoFile) {
+	flagSets := make(map[string]string) // 变量表达式 -> flag或pflag导入路径
+	vipers := make(map[string]bool)
+	bind := func(lhs ast.Expr, value ast.Expr) {
+		key := types.ExprString(lhs)
+		if call, ok := value.(*ast.CallExpr); ok {
+			value = call.Fun
+		}
+		if importPath := file.typeImport(value, "NewFlagSet", "FlagSet"); importPath == configImportFlag || importPath == configImportPflag {
+			flagSets[key] = importPath
+		}
+		if file.typeImport(value, "New", "Viper") == configImportViper || file.typeImport(value, "GetViper", "Viper") == configImportViper {
+			vipers[key] = true
+		}
+	}
+	ast.Inspect(file.file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) == len(n.Rhs) {
+				for i := range n.Lhs {
+					bind(n.Lhs[i], n.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if len(n.Values) == len(n.Names) {
+					bind(name, n.Values[i])
+				} else if n.Type != nil {
+					bind(name, n.Type)
+				}
+			}
+		case *ast.Field:
+			for _, name := range n.Names {
+				bind(name, n.Type)
+			}
+		case *ast.CallExpr:
+			e.extractCall(file, n, flagSets, vipers)
+		}
+		return true
+	})
+}
+
+// typeImport 表达式为pkg.ctor或pkg.typeName（可带指针）时返回pkg的导入路径
+func (f *moduleGoFile) typeImport(expr ast.Expr, ctor, typeName string) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok || selector.Sel.Name != ctor && selector.Sel.Name != typeName {
+		return ""
+	}
+	if ident, ok := selector.X.(*ast.Ident); ok {
+		return f.imports[ident.Name]
+	}
+	return ""
+}
+
+// extractCall 识别单个调用是否为配置读取
+func (e *configExtractor) extractCall(file *moduleGoFile, call *ast.CallExpr, flagSets map[string]string, vipers map[string]bool) {
+	var input *ConfigInput
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		input = e.wrapperInput(file, file.pkg+"."+fun.Name, fun.Name, call)
+	case *ast.SelectorExpr:
+		recv := types.ExprString(fun.X)
+		name := fun.Sel.Name
+		importPath := ""
+		if ident, ok := fun.X.(*ast.Ident); ok {
+			importPath = file.imports[ident.Name]
+		}
+		switch {
+		case importPath == configImportOs || importPath == "syscall":
+			if (name == "Getenv" || name == "LookupEnv") && len(call.Args) == 1 {
+				input = &ConfigInput{Source: ConfigSourceEnv, Type: "string"}
+				input.Key, input.Resolved = e.consts.eval(call.Args[0], file.pkg, file.imports)
+			}
+		case importPath == configImportFlag || importPath == configImportPflag:
+			input = e.flagInput(file, name, call, importPath == configImportPflag)
+		case flagSets[recv] != "":
+			input = e.flagInput(file, name, call, flagSets[recv] == configImportPflag)
+		case importPath == configImportViper || vipers[recv]:
+			input = e.viperInput(file, name, call)
+		case e.isCobraFlags(file, fun.X):
+			input = e.flagInput(file, name, call, true)
+		case importPath != "":
+			input = e.wrapperInput(file, importPath+"."+name, types.ExprString(fun), call)
+		}
+	}
+	if input == nil {
+		return
+	}
+	if input.Call == "" {
+		input.Call = types.ExprString(call.Fun)
+	}
+	if input.defaultExpr != nil {
+		input.Default = e.defaultValue(file, input.defaultExpr)
+	}
+	position := e.fileSet.Position(call.Pos())
+	input.Pkg, input.RFilePath, input.Line, input.Column = file.pkg, file.rFilePath, position.Line, position.Column
+	input.FuncInfo = LocateFuncInfo(e.fileFuncs[file.rFilePath], position.Line, position.Column)
+	if input.FuncInfo != nil {
+		// 封装函数内部以参数为键的读取由调用方记录
+		if e.wrappers[input.FuncInfo.Pkg+"."+input.FuncInfo.Name] != nil && !input.Resolved && input.Source == ConfigSourceEnv {
+			return
+		}
+		input.Func = skeletonDisplayName(input.FuncInfo)
+	}
+	e.inputs = append(e.inputs, input)
+}
+
+// isCobraFlags 识别cobra命令的cmd.Flags()和cmd.PersistentFlags()
+func (e *configExtractor) isCobraFlags(file *moduleGoFile, expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || selector.Sel.Name != "Flags" && selector.Sel.Name != "PersistentFlags" && selector.Sel.Name != "LocalFlags" {
+		return false
+	}
+	for _, importPath := range file.imports {
+		if importPath == configImportCobra || importPath == configImportPflag {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapperInput 调用模块内的环境变量封装函数
+func (e *configExtractor) wrapperInput(file *moduleGoFile, key, callName string, call *ast.CallExpr) *ConfigInput {
+	wrapper, ok := e.wrappers[key]
+	if !ok || len(call.Args) <= wrapper.keyArg {
+		return nil
+	}
+	input := &ConfigInput{Source: ConfigSourceEnv, Call: callName, Type: "string"}
+	input.Key, input.Resolved = e.consts.eval(call.Args[wrapper.keyArg], file.pkg, file.imports)
+	if wrapper.defaultArg >= 0 && wrapper.defaultArg < len(call.Args) {
+		input.defaultExpr = call.Args[wrapper.defaultArg]
+	}
+	return input
+}
+
+// flagInput 解析flag定义：Xxx(name, value, usage)、XxxVar(p, name, value, usage)、Var(value, name, usage)，
+// pflag还有在name后带shorthand参数的XxxP、XxxVarP和VarP
+func (e *configExtractor) flagInput(file *moduleGoFile, name string, call *ast.CallExpr, pflag bool) *ConfigInput {
+	typeName := name
+	shorthand := false
+	if trimmed := strings.TrimSuffix(typeName, "P"); pflag && trimmed != typeName &&
+		(configFlagTypes[trimmed] || strings.HasSuffix(trimmed, "Var")) {
+		typeName, shorthand = trimmed, true
+	}
+	isVar := typeName == "Var" || typeName == "TextVar" ||
+		strings.HasSuffix(typeName, "Var") && configFlagTypes[strings.TrimSuffix(typeName, "Var")]
+	if !isVar && !configFlagTypes[typeName] {
+		return nil
+	}
+	args := append([]ast.Expr{}, call.Args...)
+	if shorthand {
+		index := 1
+		if isVar {
+			index = 2
+		}
+		if len(args) <= index {
+			return nil
+		}
+		args = append(args[:index], args[index+1:]...)
+	}
+	var key, value, usage ast.Expr
+	switch {
+	case typeName == "Var" && len(args) == 3:
+		key, usage = args[1], args[2]
+		typeName = "Value"
+	case typeName == "TextVar" && len(args) == 4:
+		key, value, usage = args[1], args[2], args[3]
+		typeName = "Text"
+	case isVar && len(args) == 4:
+		key, value, usage = args[1], args[2], args[3]
+		typeName = strings.TrimSuffix(typeName, "Var")
+	case (typeName == "Func" || typeName == "BoolFunc") && len(args) == 3:
+		key, usage = args[0], args[1]
+	case !isVar && len(args) == 3:
+		key, value, usage = args[0], args[1], args[2]
+	default:
+		return nil
+	}
+	input := &ConfigInput{Source: ConfigSourceFlag, Type: lowerFirst(typeName), defaultExpr: value}
+	input.Key, input.Resolved = e.consts.eval(key, file.pkg, file.imports)
+	if text, ok := e.consts.eval(usage, file.pkg, file.imports); ok {
+		input.Usage = text
+	}
+	return input
+}
+
+// viperInput 解析viper.Get*(key)、viper.IsSet(key)以及viper.BindEnv(key, env...)和viper.SetDefault(key, value)
+func (e *configExtractor) viperInput(file *moduleGoFile, name string, call *ast.CallExpr) *ConfigInput {
+	if len(call.Args) == 0 {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(name, "Get") && len(call.Args) == 1 && name != "GetViper":
+		input := &ConfigInput{Source: ConfigSourceViper, Type: lowerFirst(strings.TrimPrefix(name, "Get"))}
+		if input.Type == "" {
+			input.Type = "any"
+		}
+		input.Key, input.Resolved = e.consts.eval(call.Args[0], file.pkg, file.imports)
+		return input
+	case name == "IsSet" && len(call.Args) == 1:
+		input := &ConfigInput{Source: ConfigSourceViper}
+		input.Key, input.Resolved = e.consts.eval(call.Args[0], file.pkg, file.imports)
+		return input
+	case name == "BindEnv" && len(call.Args) >= 2:
+		// 绑定多个环境变量时只记录第一个
+		input := &ConfigInput{Source: ConfigSourceEnv, Type: "string"}
+		input.Key, input.Resolved = e.consts.eval(call.Args[1], file.pkg, file.imports)
+		return input
+	case name == "SetDefault" && len(call.Args) == 2:
+		key, ok := e.consts.eval(call.Args[0], file.pkg, file.imports)
+		if ok {
+			e.defaults[strings.ToLower(key)] = e.defaultValue(file, call.Args[1])
+		}
+	}
+	return nil
+}
+
+// defaultValue 字符串默认值去掉引号，常量替换为其值，其他表达式保留源码
+func (e *configExtractor) defaultValue(file *moduleGoFile, expr ast.Expr) string {
+	if value, ok := e.consts.eval(expr, file.pkg, file.imports); ok {
+		return value
+	}
+	return types.ExprString(expr)
+}
+
+// applyViperDefaults 将SetDefault的默认值填入读取同一键的viper配置，viper的键不区分大小写
+func (e *configExtractor) applyViperDefaults() {
+	for _, input := range e.inputs {
+		if value, ok := e.defaults[strings.ToLower(input.Key)]; ok && input.Source == ConfigSourceViper && input.Default == "" {
+			input.Default = value
+		}
+	}
+}
+
+// lowerFirst 将首字母转为小写，如StringSlice -> stringSlice
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// ConfigKeyLines 将清单中的配置按来源格式化为便于运维查看的文本行
+func ConfigKeyLines(inputs []*ConfigInput) []string {
+	lines := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		line := fmt.Sprintf("%-5s %s", input.Source, input.Key)
+		if input.Type != "" {
+			line += " " + input.Type
+		}
+		if input.Default != "" {
+			line += " (默认: " + input.Default + ")"
+		}
+		line += fmt.Sprintf(" %s:%d", input.RFilePath, input.Line)
+		if input.Usage != "" {
+			line += " " + input.Usage
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
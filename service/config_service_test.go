@@ -0,0 +1,131 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildConfigInventory(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"config/env.go": `package config
+
+import (
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+const EnvDatabaseURL = "DATABASE_URL"
+
+func getEnv(key, def string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return def
+}
+
+func Load() {
+	_ = os.Getenv(EnvDatabaseURL)
+	_ = getEnv("LOG_LEVEL", "info")
+	v := viper.New()
+	v.SetDefault("server.port", 8080)
+	_ = v.GetInt("Server.Port")
+	_ = viper.BindEnv("token", "APP_TOKEN")
+}
+`,
+		"cmd/server/main.go": `package main
+
+import (
+	"flag"
+	"time"
+
+	"example.com/app/config"
+)
+
+var addr = flag.String("addr", ":8080", "监听地址")
+
+func main() {
+	var timeout time.Duration
+	flag.DurationVar(&timeout, "timeout", 3*time.Second, "超时时间")
+	flagSet := flag.NewFlagSet("sub", flag.ExitOnError)
+	flagSet.Bool("verbose", false, "详细输出")
+	config.Load()
+}
+`,
+		"cmd/cli/main.go": `package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	cmd := &cobra.Command{}
+	cmd.Flags().StringP("output", "o", "text", "输出格式")
+	cmd.PersistentFlags().IntVarP(new(int), "count", "c", 1, "次数")
+	_ = os.Getenv("HOME" + os.Args[0])
+}
+`,
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := BuildImportGraph([]*ModuleInfo{modInfo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inventory, err := BuildConfigInventory(modInfo, graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]*ConfigInput)
+	for _, input := range inventory.Inputs {
+		got[input.Source+":"+input.Key] = input
+	}
+	if len(inventory.Inputs) != 10 {
+		t.Fatalf("期望10个配置读取，实际%d个: %v", len(inventory.Inputs), strings.Join(ConfigKeyLines(inventory.Inputs), "\n"))
+	}
+	cases := []struct {
+		key, call, typ, def, funcName string
+	}{
+		{"env:DATABASE_URL", "os.Getenv", "string", "", "Load"},
+		{"env:LOG_LEVEL", "getEnv", "string", "info", "Load"},
+		{"env:APP_TOKEN", "viper.BindEnv", "string", "", "Load"},
+		{"viper:Server.Port", "v.GetInt", "int", "8080", "Load"},
+		{"flag:addr", "flag.String", "string", ":8080", ""},
+		{"flag:timeout", "flag.DurationVar", "duration", "3 * time.Second", "main"},
+		{"flag:verbose", "flagSet.Bool", "bool", "false", "main"},
+		{"flag:output", "cmd.Flags().StringP", "string", "text", "main"},
+		{"flag:count", "cmd.PersistentFlags().IntVarP", "int", "1", "main"},
+		{"env:HOME<os.Args[0]>", "os.Getenv", "string", "", "main"},
+	}
+	for _, c := range cases {
+		input, ok := got[c.key]
+		if !ok {
+			t.Errorf("缺少配置%s", c.key)
+			continue
+		}
+		if input.Call != c.call || input.Type != c.typ || input.Default != c.def || input.Func != c.funcName {
+			t.Errorf("%s解析错误: %+v", c.key, input)
+		}
+	}
+	if input := got["flag:addr"]; input.Usage != "监听地址" || !input.Resolved || input.RFilePath != "cmd/server/main.go" || input.Line != 10 {
+		t.Errorf("flag位置或用法错误: %+v", input)
+	}
+	if got["env:HOME<os.Args[0]>"].Resolved {
+		t.Errorf("拼接了非常量的键不应视为已解析")
+	}
+	if len(inventory.Binaries) != 2 {
+		t.Fatalf("期望2个可执行程序，实际%d个", len(inventory.Binaries))
+	}
+	cli, server := inventory.Binaries[0], inventory.Binaries[1]
+	if cli.Pkg != "example.com/app/cmd/cli" || strings.Join(cli.Flags, ",") != "count,output" || len(cli.Env) != 1 {
+		t.Errorf("cli配置错误: %+v", cli)
+	}
+	if strings.Join(server.Env, ",") != "APP_TOKEN,DATABASE_URL,LOG_LEVEL" || strings.Join(server.Flags, ",") != "addr,timeout,verbose" || strings.Join(server.Viper, ",") != "Server.Port" {
+		t.Errorf("server配置错误: env=%v flags=%v viper=%v", server.Env, server.Flags, server.Viper)
+	}
+}
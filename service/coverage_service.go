@@ -0,0 +1,313 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+	"golang.org/x/tools/cover"
+)
+
+// FuncCoverage 单个函数的覆盖率，匿名函数的语句只计入其自身而不计入外层函数
+type FuncCoverage struct {
+	Pkg        string
+	Name       string
+	RFilePath  string
+	StartLine  int
+	EndLine    int
+	Statements int
+	Covered    int
+	Percent    float64
+	Blocks     []cover.ProfileBlock // 归属于该函数的覆盖块
+	FuncInfo   *vs.FuncInfo
+}
+
+// PkgCoverage 包的覆盖率
+type PkgCoverage struct {
+	Pkg        string
+	Statements int
+	Covered    int
+	Percent    float64
+}
+
+// CoverageReport 模块的覆盖率报告
+type CoverageReport struct {
+	Module     string
+	Mode       string
+	Statements int
+	Covered    int
+	Percent    float64
+	Pkgs       []*PkgCoverage
+	Funcs      []*FuncCoverage
+}
+
+// ChangedFuncCoverage 有改动但改动行未被覆盖的函数
+type ChangedFuncCoverage struct {
+	*FuncCoverage
+	ChangedLines   []int // 函数内的改动行
+	UncoveredLines []int // 落在未覆盖语句块中的改动行
+}
+
+// ParseCoverProfile 解析go test -coverprofile生成的文件
+func ParseCoverProfile(profilePath string) ([]*cover.Profile, error) {
+	profiles, err := cover.ParseProfiles(profilePath)
+	if err != nil {
+		return nil, fmt.Errorf("解析覆盖率文件失败: %w", err)
+	}
+	return profiles, nil
+}
+
+// BuildCoverageReport 将覆盖块归属到模块中最内层的函数，并汇总函数、包和模块的覆盖率，不属于该模块的文件会被忽略
+func BuildCoverageReport(modInfo *ModuleInfo, profiles []*cover.Profile) *CoverageReport {
+	report := &CoverageReport{Module: modInfo.Path}
+	fileFuncs := moduleFileFuncs(modInfo)
+	pkgCoverages := make(map[string]*PkgCoverage)
+	for _, profile := range profiles {
+		rFilePath, ok := strings.CutPrefix(profile.FileName, modInfo.Path+"/")
+		if !ok {
+			continue
+		}
+		funcs, ok := fileFuncs[rFilePath]
+		if !ok {
+			continue
+		}
+		report.Mode = profile.Mode
+		pkg := path.Dir(profile.FileName)
+		pkgCoverage, ok := pkgCoverages[pkg]
+		if !ok {
+			pkgCoverage = &PkgCoverage{Pkg: pkg}
+			pkgCoverages[pkg] = pkgCoverage
+		}
+		funcCoverages := make(map[*vs.FuncInfo]*FuncCoverage, len(funcs))
+		for _, funcInfo := range funcs {
+			funcCoverage := &FuncCoverage{
+				Pkg:       funcInfo.Pkg,
+				Name:      skeletonDisplayName(funcInfo),
+				RFilePath: rFilePath,
+				StartLine: funcInfo.StartPosition.Line,
+				EndLine:   funcInfo.EndPosition.Line,
+				FuncInfo:  funcInfo,
+			}
+			funcCoverages[funcInfo] = funcCoverage
+			report.Funcs = append(report.Funcs, funcCoverage)
+		}
+		for _, block := range profile.Blocks {
+			pkgCoverage.Statements += block.NumStmt
+			if block.Count > 0 {
+				pkgCoverage.Covered += block.NumStmt
+			}
+			funcInfo := LocateFuncInfo(funcs, block.StartLine, block.StartCol)
+			if funcInfo == nil {
+				continue
+			}
+			funcCoverage := funcCoverages[funcInfo]
+			funcCoverage.Blocks = append(funcCoverage.Blocks, block)
+			funcCoverage.Statements += block.NumStmt
+			if block.Count > 0 {
+				funcCoverage.Covered += block.NumStmt
+			}
+		}
+	}
+	for _, funcCoverage := range report.Funcs {
+		funcCoverage.Percent = coveragePercent(funcCoverage.Covered, funcCoverage.Statements)
+	}
+	sort.SliceStable(report.Funcs, func(i, j int) bool {
+		if report.Funcs[i].RFilePath != report.Funcs[j].RFilePath {
+			return report.Funcs[i].RFilePath < report.Funcs[j].RFilePath
+		}
+		return report.Funcs[i].StartLine < report.Funcs[j].StartLine
+	})
+	for _, pkg := range sortedMapKeys(pkgCoverages) {
+		pkgCoverage := pkgCoverages[pkg]
+		pkgCoverage.Percent = coveragePercent(pkgCoverage.Covered, pkgCoverage.Statements)
+		report.Statements += pkgCoverage.Statements
+		report.Covered += pkgCoverage.Covered
+		report.Pkgs = append(report.Pkgs, pkgCoverage)
+	}
+	report.Percent = coveragePercent(report.Covered, report.Statements)
+	return report
+}
+
+// moduleFileFuncs 按相对模块根目录的文件路径聚合非测试文件中的函数
+func moduleFileFuncs(modInfo *ModuleInfo) map[string][]*vs.FuncInfo {
+	fileFuncs := make(map[string][]*vs.FuncInfo)
+	for _, funcs := range modInfo.PkgFuncMap {
+		for _, funcInfo := range funcs {
+			rFilePath := filepath.ToSlash(funcInfo.RFilePath)
+			fileFuncs[rFilePath] = append(fileFuncs[rFilePath], funcInfo)
+		}
+	}
+	return fileFuncs
+}
+
+// LocateFuncInfo 查找包含指定位置的最内层函数（包括匿名函数），column为0时只按行匹配
+func LocateFuncInfo(funcs []*vs.FuncInfo, line, column int) *vs.FuncInfo {
+	var innermost *vs.FuncInfo
+	for _, funcInfo := range funcs {
+		start, end := funcInfo.StartPosition, funcInfo.EndPosition
+		if start == nil || end == nil || line < start.Line || line > end.Line {
+			continue
+		}
+		if column > 0 && (line == start.Line && column < start.Column || line == end.Line && column >= end.Column) {
+			continue
+		}
+		// 嵌套的函数起始位置一定更靠后
+		if innermost == nil || start.OffSet > innermost.StartPosition.OffSet {
+			innermost = funcInfo
+		}
+	}
+	return innermost
+}
+
+// coveragePercent 与go tool cover一致，没有语句时覆盖率为0
+func coveragePercent(covered, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(covered) / float64(total)
+}
+
+// ParseUnifiedDiff 解析git diff等统一格式的差异，返回新版本文件路径到新增或修改行号的映射，删除的文件会被忽略
+func ParseUnifiedDiff(reader io.Reader) (map[string][]int, error) {
+	changes := make(map[string][]int)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var curFile string
+	newLine, remaining := 0, 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if remaining > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				if curFile != "" {
+					changes[curFile] = append(changes[curFile], newLine)
+				}
+				newLine++
+				remaining--
+			case strings.HasPrefix(line, " "), line == "":
+				newLine++
+				remaining--
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			curFile = strings.TrimSpace(strings.TrimPrefix(line, "+++ "))
+			if tab := strings.IndexByte(curFile, '\t'); tab >= 0 {
+				curFile = curFile[:tab]
+			}
+			if curFile == "/dev/null" {
+				curFile = ""
+			} else {
+				curFile = strings.TrimPrefix(curFile, "b/")
+			}
+		case strings.HasPrefix(line, "@@ "):
+			start, count, err := parseHunkNewRange(line)
+			if err != nil {
+				return nil, err
+			}
+			newLine, remaining = start, count
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取diff失败: %w", err)
+	}
+	return changes, nil
+}
+
+// parseHunkNewRange 解析hunk头 @@ -a,b +c,d @@ 中新版本的起始行和行数
+func parseHunkNewRange(header string) (int, int, error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, fmt.Errorf("非法的hunk头: %s", header)
+	}
+	startStr, countStr, hasCount := strings.Cut(fields[2][1:], ",")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("非法的hunk头: %s: %w", header, err)
+	}
+	count := 1
+	if hasCount {
+		if count, err = strconv.Atoi(countStr); err != nil {
+			return 0, 0, fmt.Errorf("非法的hunk头: %s: %w", header, err)
+		}
+	}
+	return start, count, nil
+}
+
+// ChangedUncoveredFuncs 结合diff找出改动行落在未覆盖语句块中的函数，diff路径相对仓库根目录，
+// moduleRPath为模块目录相对仓库根目录的路径，模块位于仓库根目录时为"."，为空表示未知，此时按后缀与模块内路径匹配
+func ChangedUncoveredFuncs(report *CoverageReport, changes map[string][]int, moduleRPath string) []*ChangedFuncCoverage {
+	fileFuncs := make(map[string][]*vs.FuncInfo)
+	funcCoverages := make(map[*vs.FuncInfo]*FuncCoverage)
+	for _, funcCoverage := range report.Funcs {
+		fileFuncs[funcCoverage.RFilePath] = append(fileFuncs[funcCoverage.RFilePath], funcCoverage.FuncInfo)
+		funcCoverages[funcCoverage.FuncInfo] = funcCoverage
+	}
+	result := make([]*ChangedFuncCoverage, 0)
+	for _, rFilePath := range sortedMapKeys(fileFuncs) {
+		changedLines := diffLinesForFile(changes, rFilePath, moduleRPath)
+		if len(changedLines) == 0 {
+			continue
+		}
+		changedFuncs := make(map[*vs.FuncInfo]*ChangedFuncCoverage)
+		for _, line := range changedLines {
+			funcInfo := LocateFuncInfo(fileFuncs[rFilePath], line, 0)
+			if funcInfo == nil {
+				continue
+			}
+			changed, ok := changedFuncs[funcInfo]
+			if !ok {
+				changed = &ChangedFuncCoverage{FuncCoverage: funcCoverages[funcInfo]}
+				changedFuncs[funcInfo] = changed
+			}
+			changed.ChangedLines = append(changed.ChangedLines, line)
+			for _, block := range changed.Blocks {
+				if block.Count == 0 && block.NumStmt > 0 && line >= block.StartLine && line <= block.EndLine {
+					changed.UncoveredLines = append(changed.UncoveredLines, line)
+					break
+				}
+			}
+		}
+		for _, funcInfo := range fileFuncs[rFilePath] {
+			if changed, ok := changedFuncs[funcInfo]; ok && len(changed.UncoveredLines) > 0 {
+				result = append(result, changed)
+			}
+		}
+	}
+	return result
+}
+
+// diffLinesForFile 查找模块内文件对应的改动行，并去重排序，已知模块相对仓库的路径时要求完全匹配
+func diffLinesForFile(changes map[string][]int, rFilePath, moduleRPath string) []int {
+	if moduleRPath != "" {
+		return sortedUniqueLines(changes[path.Join(moduleRPath, rFilePath)])
+	}
+	lines := make([]int, 0)
+	for diffPath, fileLines := range changes {
+		if diffPath == rFilePath || strings.HasSuffix(diffPath, "/"+rFilePath) {
+			lines = append(lines, fileLines...)
+		}
+	}
+	return sortedUniqueLines(lines)
+}
+
+// sortedUniqueLines 对行号去重排序
+func sortedUniqueLines(lines []int) []int {
+	lineSet := make(map[int]bool)
+	result := make([]int, 0)
+	for _, line := range lines {
+		if !lineSet[line] {
+			lineSet[line] = true
+			result = append(result, line)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
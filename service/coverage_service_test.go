@@ -0,0 +1,128 @@
+package service
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildCoverageReport(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"calc/calc.go": `package calc
+
+func Abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func Apply(values []int) []int {
+	mapper := func(v int) int {
+		if v > 100 {
+			return 100
+		}
+		return v
+	}
+	result := make([]int, 0, len(values))
+	for _, v := range values {
+		result = append(result, mapper(v))
+	}
+	return result
+}
+
+func Unused() int {
+	return 1
+}
+`,
+		"calc/calc_test.go": `package calc
+
+import "testing"
+
+func TestAbs(t *testing.T) {
+	Abs(-1)
+	Apply([]int{1})
+}
+`,
+	})
+	profilePath := filepath.Join(dir, "cover.out")
+	cmd := exec.Command("go", "test", "-coverprofile="+profilePath, "./...")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test failed: %v\n%s", err, out)
+	}
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := ParseCoverProfile(profilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := BuildCoverageReport(modInfo, profiles)
+	funcs := make(map[string]*FuncCoverage)
+	for _, funcCoverage := range report.Funcs {
+		funcs[funcCoverage.Name] = funcCoverage
+	}
+	if funcs["Abs"].Statements != 3 || funcs["Abs"].Covered != 2 {
+		t.Errorf("Abs: unexpected coverage %+v", funcs["Abs"])
+	}
+	if funcs["Unused"].Covered != 0 || funcs["Unused"].Statements != 1 {
+		t.Errorf("Unused: unexpected coverage %+v", funcs["Unused"])
+	}
+	// 闭包的语句只计入闭包本身
+	if funcs["Apply$1"].Statements != 3 || funcs["Apply$1"].Covered != 2 {
+		t.Errorf("Apply$1: unexpected coverage %+v", funcs["Apply$1"])
+	}
+	if funcs["Apply"].Statements != 5 || funcs["Apply"].Percent != 100 {
+		t.Errorf("Apply: unexpected coverage %+v", funcs["Apply"])
+	}
+	if len(report.Pkgs) != 1 || report.Pkgs[0].Statements != 12 || report.Pkgs[0].Covered != 9 {
+		t.Errorf("unexpected package coverage %+v", report.Pkgs[0])
+	}
+	if report.Statements != 12 || report.Mode != "set" {
+		t.Errorf("unexpected module coverage %+v", report)
+	}
+
+	diff := `diff --git a/svc/calc/calc.go b/svc/calc/calc.go
+--- a/svc/calc/calc.go
++++ b/svc/calc/calc.go
+@@ -3,3 +3,3 @@ package calc
+ func Abs(v int) int {
+-	if v <= 0 {
++	if v < 0 {
+ 		return -v
+@@ -12,2 +12,2 @@ func Apply(values []int) []int {
+ 		if v > 100 {
+-			return 101
++			return 100
+@@ -24,2 +24,3 @@ func Apply(values []int) []int {
+ func Unused() int {
++	// unused
+ 	return 1
+`
+	changes, err := ParseUnifiedDiff(strings.NewReader(diff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changes["svc/calc/calc.go"]; len(got) != 3 || got[0] != 4 || got[1] != 13 || got[2] != 25 {
+		t.Fatalf("unexpected changed lines: %v", changes)
+	}
+	// Abs的改动行已被覆盖
+	for _, moduleRPath := range []string{"", "svc"} {
+		changed := ChangedUncoveredFuncs(report, changes, moduleRPath)
+		if len(changed) != 2 || changed[0].Name != "Apply$1" || changed[0].UncoveredLines[0] != 13 || changed[1].Name != "Unused" {
+			t.Errorf("unexpected changed uncovered funcs for %q: %+v", moduleRPath, changed)
+		}
+	}
+	// 已知模块路径时，其他目录下同后缀的文件不应匹配
+	other := map[string][]int{"tools/calc/calc.go": {13, 25}}
+	if changed := ChangedUncoveredFuncs(report, other, "svc"); len(changed) != 0 {
+		t.Errorf("diff of another directory should not match: %+v", changed)
+	}
+	if changed := ChangedUncoveredFuncs(report, other, ""); len(changed) != 2 {
+		t.Errorf("unknown module path should match by suffix: %+v", changed)
+	}
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+// DepParseConfig 依赖源码解析配置
+type DepParseConfig struct {
+	ModCacheDir string             // 模块缓存目录，默认为GOMODCACHE
+	Proxy       *ModuleProxyClient // 模块缓存中不存在时用于下载源码，为空则不下载
+	ExtractDir  string             // 下载的源码解压目录，默认在系统临时目录下
+}
+
+// DepUsage 表示自有函数对某个依赖模块的引用
+type DepUsage struct {
+	Func    *vs.FuncInfo // 自有函数
+	DepPkgs []string     // 引用到的依赖模块中的包
+}
+
+// DepEmbed 表示自有类型中嵌入的依赖类型
+type DepEmbed struct {
+	Struct  *vs.StructInfo // 自有类型
+	Field   *vs.VarInfo    // 嵌入字段
+	DepType *vs.StructInfo // 依赖模块中的类型定义，依赖未解析时为空
+}
+
+// ResolveModCacheDir 获取模块缓存目录，依次读取GOMODCACHE、go env和GOPATH
+func ResolveModCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	if out, err := exec.Command("go", "env", "GOMODCACHE").Output(); err == nil {
+		if dir := strings.TrimSpace(string(out)); dir != "" {
+			return dir
+		}
+	}
+	goPath := os.Getenv("GOPATH")
+	if goPath == "" {
+		home, _ := os.UserHomeDir()
+		goPath = filepath.Join(home, "go")
+	}
+	return filepath.Join(filepath.SplitList(goPath)[0], "pkg", "mod")
+}
+
+// ParseDependencies 解析模块所有依赖的源码，替换为本地目录的依赖不解析；
+// 单个依赖解析失败时继续解析其余依赖，返回已解析的依赖以及合并后的错误
+func ParseDependencies(ctx context.Context, modInfo *ModuleInfo, cfg *DepParseConfig) ([]*ModuleInfo, error) {
+	deps := make([]*ModuleInfo, 0, len(modInfo.Requires))
+	errs := make([]error, 0)
+	for _, req := range modInfo.Requires {
+		source := module.Version{Path: req.Path, Version: req.Version}
+		if replace := findReplaceRule(modInfo.Replaces, req); replace != nil {
+			if modfile.IsDirectoryPath(replace.NewPath) {
+				continue
+			}
+			source = module.Version{Path: replace.NewPath, Version: replace.NewVersion}
+		}
+		// 以go.mod中require的路径作为模块路径，保证包路径与自有代码中的导入一致
+		depInfo, err := parseDependencySource(ctx, req.Path, source, cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("解析依赖 %s@%s 失败: %w", req.Path, req.Version, err))
+			continue
+		}
+		deps = append(deps, depInfo)
+	}
+	return deps, errors.Join(errs...)
+}
+
+// ParseDependency 从模块缓存或代理下载的源码中解析单个依赖模块
+func ParseDependency(ctx context.Context, dep module.Version, cfg *DepParseConfig) (*ModuleInfo, error) {
+	return parseDependencySource(ctx, dep.Path, dep, cfg)
+}
+
+// parseDependencySource 解析source对应的源码，包路径以modPath为前缀
+func parseDependencySource(ctx context.Context, modPath string, source module.Version, cfg *DepParseConfig) (*ModuleInfo, error) {
+	if cfg == nil {
+		cfg = &DepParseConfig{}
+	}
+	dir, err := resolveDependencyDir(ctx, source, cfg)
+	if err != nil {
+		return nil, err
+	}
+	info := &ModuleInfo{
+		Path:         modPath,
+		Dir:          dir,
+		PkgFuncMap:   make(map[string][]*vs.FuncInfo),
+		PkgVarMap:    make(map[string][]*vs.VarInfo),
+		PkgStructMap: make(map[string][]*vs.StructInfo),
+	}
+	if parsed, err := ParseModule(dir); err == nil {
+		info = parsed
+		if info.Path != modPath {
+			rekeyModulePkgs(info, modPath)
+		}
+	} else {
+		// 没有go.mod的老模块直接解析源码
+		AppendModuleInfo(info)
+	}
+	info.Version = source.Version
+	info.ThirdParty = true
+	return info, nil
+}
+
+// rekeyModulePkgs 将模块中的包路径前缀替换为新的模块路径
+func rekeyModulePkgs(info *ModuleInfo, modPath string) {
+	rekey := func(pkg string) string {
+		return modPath + strings.TrimPrefix(pkg, info.Path)
+	}
+	info.PkgFuncMap = rekeyPkgMap(info.PkgFuncMap, rekey, func(funcInfo *vs.FuncInfo) *vs.BaseAstInfo { return &funcInfo.BaseAstInfo })
+	info.PkgVarMap = rekeyPkgMap(info.PkgVarMap, rekey, func(varInfo *vs.VarInfo) *vs.BaseAstInfo { return &varInfo.BaseAstInfo })
+	info.PkgStructMap = rekeyPkgMap(info.PkgStructMap, rekey, func(structInfo *vs.StructInfo) *vs.BaseAstInfo { return &structInfo.BaseAstInfo })
+	info.PkgTestFuncMap = rekeyPkgMap(info.PkgTestFuncMap, rekey, func(funcInfo *vs.FuncInfo) *vs.BaseAstInfo { return &funcInfo.BaseAstInfo })
+	info.PkgTestVarMap = rekeyPkgMap(info.PkgTestVarMap, rekey, func(varInfo *vs.VarInfo) *vs.BaseAstInfo { return &varInfo.BaseAstInfo })
+	info.PkgTestStructMap = rekeyPkgMap(info.PkgTestStructMap, rekey, func(structInfo *vs.StructInfo) *vs.BaseAstInfo { return &structInfo.BaseAstInfo })
+	info.Path = modPath
+}
+
+// rekeyPkgMap 替换按包路径分组的声明的包路径
+func rekeyPkgMap[T any](pkgMap map[string][]T, rekey func(string) string, base func(T) *vs.BaseAstInfo) map[string][]T {
+	result := make(map[string][]T, len(pkgMap))
+	for pkg, items := range pkgMap {
+		for _, item := range items {
+			base(item).Pkg = rekey(base(item).Pkg)
+		}
+		result[rekey(pkg)] = items
+	}
+	return result
+}
+
+// resolveDependencyDir 返回依赖源码目录，缓存中不存在时通过代理下载并解压
+func resolveDependencyDir(ctx context.Context, dep module.Version, cfg *DepParseConfig) (string, error) {
+	escapedPath, err := module.EscapePath(dep.Path)
+	if err != nil {
+		return "", err
+	}
+	escapedVersion, err := module.EscapeVersion(dep.Version)
+	if err != nil {
+		return "", err
+	}
+	modCacheDir := cfg.ModCacheDir
+	if modCacheDir == "" {
+		modCacheDir = ResolveModCacheDir()
+	}
+	dirName := filepath.FromSlash(escapedPath) + "@" + escapedVersion
+	cacheDir := filepath.Join(modCacheDir, dirName)
+	if stat, err := os.Stat(cacheDir); err == nil && stat.IsDir() {
+		return cacheDir, nil
+	}
+	if cfg.Proxy == nil {
+		return "", fmt.Errorf("依赖 %s@%s 不在模块缓存 %s 中", dep.Path, dep.Version, modCacheDir)
+	}
+	extractDir := cfg.ExtractDir
+	if extractDir == "" {
+		extractDir = filepath.Join(os.TempDir(), "static_parser", "mod")
+	}
+	targetDir := filepath.Join(extractDir, dirName)
+	if stat, err := os.Stat(targetDir); err == nil && stat.IsDir() {
+		return targetDir, nil
+	}
+	zipFile, err := cfg.Proxy.DownloadZip(ctx, dep.Path, dep.Version)
+	if err != nil {
+		return "", fmt.Errorf("下载依赖 %s@%s 失败: %w", dep.Path, dep.Version, err)
+	}
+	if err := modzip.Unzip(targetDir, dep, zipFile); err != nil {
+		os.RemoveAll(targetDir)
+		return "", fmt.Errorf("解压依赖 %s@%s 失败: %w", dep.Path, dep.Version, err)
+	}
+	return targetDir, nil
+}
+
+// FindFuncsUsingModule 查找自有函数中引用了某个依赖模块的函数
+func FindFuncsUsingModule(modules []*ModuleInfo, depModPath string) []*DepUsage {
+	usages := make([]*DepUsage, 0)
+	for _, modInfo := range modules {
+		if modInfo.ThirdParty {
+			continue
+		}
+		for _, pkg := range sortedMapKeys(modInfo.PkgFuncMap) {
+			for _, funcInfo := range modInfo.PkgFuncMap[pkg] {
+				depPkgs := make([]string, 0)
+				for _, importPath := range funcInfo.Imports {
+					if importPath == depModPath || strings.HasPrefix(importPath, depModPath+"/") {
+						depPkgs = append(depPkgs, importPath)
+					}
+				}
+				if len(depPkgs) > 0 {
+					usages = append(usages, &DepUsage{Func: funcInfo, DepPkgs: depPkgs})
+				}
+			}
+		}
+	}
+	return usages
+}
+
+// FindEmbeddedDepTypes 查找自有类型中嵌入的依赖模块类型
+func FindEmbeddedDepTypes(modules []*ModuleInfo, deps []*ModuleInfo) []*DepEmbed {
+	embeds := make([]*DepEmbed, 0)
+	for _, modInfo := range modules {
+		if modInfo.ThirdParty {
+			continue
+		}
+		for _, pkg := range sortedMapKeys(modInfo.PkgStructMap) {
+			for _, structInfo := range modInfo.PkgStructMap[pkg] {
+				for _, field := range structInfo.Fields {
+					// 嵌入字段没有名字，BaseType为 包路径.类型名
+					lastDot := strings.LastIndex(field.BaseType, ".")
+					if field.Name != "_" || lastDot < 0 {
+						continue
+					}
+					depPkg, typeName := field.BaseType[:lastDot], field.BaseType[lastDot+1:]
+					depInfo := findOwnerModule(deps, depPkg)
+					if depInfo == nil {
+						continue
+					}
+					embed := &DepEmbed{Struct: structInfo, Field: field}
+					for _, depStruct := range depInfo.PkgStructMap[depPkg] {
+						if depStruct.Name == typeName {
+							embed.DepType = depStruct
+							break
+						}
+					}
+					embeds = append(embeds, embed)
+				}
+			}
+		}
+	}
+	return embeds
+}
+
+// findOwnerModule 按最长前缀查找包所属的模块
+func findOwnerModule(modules []*ModuleInfo, pkgPath string) *ModuleInfo {
+	var owner *ModuleInfo
+	for _, modInfo := range modules {
+		if pkgPath != modInfo.Path && !strings.HasPrefix(pkgPath, modInfo.Path+"/") {
+			continue
+		}
+		if owner == nil || len(modInfo.Path) > len(owner.Path) {
+			owner = modInfo
+		}
+	}
+	return owner
+}
+
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+func TestParseDependencies(t *testing.T) {
+	ctx := context.Background()
+	modCacheDir := writeTestModule(t, map[string]string{
+		"example.com/base@v1.0.0/go.mod":       "module example.com/base\n\ngo 1.21\n",
+		"example.com/base@v1.0.0/base/base.go": "package base\n\n// Base 基础类型\ntype Base struct {\n\tID int\n}\n",
+	})
+	// 仅代理中存在的依赖
+	utilSrc := writeTestModule(t, map[string]string{
+		"go.mod":  "module example.com/util\n\ngo 1.21\n",
+		"util.go": "package util\n\nfunc Trim(s string) string { return s }\n",
+	})
+	proxyDir := t.TempDir()
+	zipPath := filepath.Join(proxyDir, "example.com", "util", "@v", "v1.1.0.zip")
+	if err := os.MkdirAll(filepath.Dir(zipPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	zipFile, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := modzip.CreateFromDir(zipFile, module.Version{Path: "example.com/util", Version: "v1.1.0"}, utilSrc); err != nil {
+		t.Fatal(err)
+	}
+	zipFile.Close()
+	proxy, err := NewModuleProxyClient(&ProxyConfig{GoProxy: "file://" + filepath.ToSlash(proxyDir), CacheDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appDir := writeTestModule(t, map[string]string{
+		"go.mod": `module example.com/app
+
+go 1.21
+
+require (
+	example.com/missing v1.0.0
+	example.com/base v1.0.0
+	example.com/util v1.1.0
+	example.com/local v0.0.0
+)
+
+replace example.com/local => ../local
+`,
+		"app.go": `package app
+
+import (
+	"example.com/base/base"
+	"example.com/util"
+)
+
+type Model struct {
+	base.Base
+	Name string
+}
+
+func Clean(m *Model) string {
+	return util.Trim(m.Name)
+}
+
+func Plain() {}
+`,
+	})
+	app, err := ParseModule(appDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 缺失的依赖不影响其余依赖的解析
+	deps, err := ParseDependencies(ctx, app, &DepParseConfig{ModCacheDir: modCacheDir, Proxy: proxy, ExtractDir: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "example.com/missing@v1.0.0") {
+		t.Errorf("expected error for missing dep, got %v", err)
+	}
+	if len(deps) != 2 {
+		t.Fatalf("expected two parsed deps, got %d", len(deps))
+	}
+	for _, dep := range deps {
+		if !dep.ThirdParty || dep.Version == "" {
+			t.Errorf("dep should be marked third party with version: %+v", dep)
+		}
+	}
+	if len(deps[1].PkgFuncMap["example.com/util"]) != 1 {
+		t.Errorf("proxy downloaded dep not parsed: %v", deps[1].PkgFuncMap)
+	}
+	usages := FindFuncsUsingModule([]*ModuleInfo{app}, "example.com/util")
+	if len(usages) != 1 || usages[0].Func.Name != "Clean" {
+		t.Fatalf("unexpected usages: %+v", usages)
+	}
+	embeds := FindEmbeddedDepTypes([]*ModuleInfo{app}, deps)
+	if len(embeds) != 1 || embeds[0].Struct.Name != "Model" || embeds[0].DepType == nil || embeds[0].DepType.Name != "Base" {
+		t.Fatalf("unexpected embeds: %+v", embeds)
+	}
+}
@@ -0,0 +1,628 @@
+package service
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+)
+
+const (
+	DocFormatMarkdown = "markdown"
+	DocFormatHTML     = "html"
+)
+
+// DocSiteConfig 文档站点生成配置
+type DocSiteConfig struct {
+	OutDir     string // 输出目录
+	Format     string // 输出格式，markdown或html，默认为markdown
+	SourceURL  string // 源码链接模板，{file}和{line}会被替换；为空时链接到本地源码文件
+	Unexported bool   // 是否包含未导出的标识符
+}
+
+// DocPackage 文档中的一个包
+type DocPackage struct {
+	Pkg        string
+	Name       string // 包名
+	Doc        string // 包注释
+	Page       string // 页面相对输出目录的路径
+	Consts     []*DocValue
+	Vars       []*DocValue
+	Funcs      []*DocFunc
+	Types      []*DocType
+	Imports    []*DocImport // 导入的包
+	ImportedBy []*DocImport // 被哪些包导入
+}
+
+// DocType 文档中的类型
+type DocType struct {
+	Name       string
+	Doc        string
+	Definition string
+	Source     string // 源码链接
+	Refs       []*DocRef
+	Funcs      []*DocFunc // 返回该类型的构造函数
+	Methods    []*DocFunc
+}
+
+// DocFunc 文档中的函数或方法
+type DocFunc struct {
+	Name      string
+	Anchor    string
+	Doc       string
+	Signature string
+	Source    string
+	Refs      []*DocRef
+}
+
+// DocValue 文档中的常量或变量
+type DocValue struct {
+	Name       string
+	Doc        string
+	Definition string
+	Source     string
+}
+
+// DocRef 指向模块内其他类型的链接
+type DocRef struct {
+	Name string
+	Link string
+}
+
+// DocImport 包的导入关系
+type DocImport struct {
+	Pkg  string
+	Kind string
+	Link string // 模块内的包才有链接
+}
+
+// docSite 生成过程中的上下文
+type docSite struct {
+	modInfo *ModuleInfo
+	cfg     *DocSiteConfig
+	ext     string
+	types   map[string]map[string]bool // 包路径 -> 文档中出现的类型名
+}
+
+// BuildDocSite 将模块渲染为静态文档站点，返回写入的文件列表
+func BuildDocSite(modInfo *ModuleInfo, graph *ImportGraph, cfg *DocSiteConfig) ([]string, error) {
+	format := cfg.Format
+	if format == "" {
+		format = DocFormatMarkdown
+	}
+	site := &docSite{modInfo: modInfo, cfg: cfg, types: make(map[string]map[string]bool)}
+	switch format {
+	case DocFormatMarkdown:
+		site.ext = ".md"
+	case DocFormatHTML:
+		site.ext = ".html"
+	default:
+		return nil, fmt.Errorf("不支持的文档格式: %s", format)
+	}
+	pkgs := site.collectPackages()
+	written := make([]string, 0, len(pkgs)+1)
+	for _, docPkg := range pkgs {
+		site.fillImports(docPkg, graph)
+		if err := site.writePage(docPkg.Page, format, "package", docPkg); err != nil {
+			return written, err
+		}
+		written = append(written, docPkg.Page)
+	}
+	index := struct {
+		Module   string
+		Packages []*DocPackage
+	}{Module: modInfo.Path, Packages: pkgs}
+	indexPage := "index" + site.ext
+	if err := site.writePage(indexPage, format, "index", index); err != nil {
+		return written, err
+	}
+	return append(written, indexPage), nil
+}
+
+func (s *docSite) collectPackages() []*DocPackage {
+	pkgSet := make(map[string]bool)
+	for pkg := range s.modInfo.PkgFuncMap {
+		pkgSet[pkg] = true
+	}
+	for pkg := range s.modInfo.PkgStructMap {
+		pkgSet[pkg] = true
+	}
+	for pkg := range s.modInfo.PkgVarMap {
+		pkgSet[pkg] = true
+	}
+	// 先登记所有类型，便于跨包链接
+	for pkg := range pkgSet {
+		s.types[pkg] = make(map[string]bool)
+		for _, structInfo := range s.modInfo.PkgStructMap[pkg] {
+			if s.visible(structInfo.Name, structInfo.RFilePath) {
+				s.types[pkg][structInfo.Name] = true
+			}
+		}
+	}
+	pkgs := make([]*DocPackage, 0, len(pkgSet))
+	for _, pkg := range sortedMapKeys(pkgSet) {
+		if docPkg := s.buildPackage(pkg); docPkg != nil {
+			pkgs = append(pkgs, docPkg)
+		}
+	}
+	return pkgs
+}
+
+func (s *docSite) buildPackage(pkg string) *DocPackage {
+	docPkg := &DocPackage{Pkg: pkg, Page: s.pkgPage(pkg)}
+	files := make(map[string]bool)
+	typeMap := make(map[string]*DocType)
+	for _, structInfo := range s.modInfo.PkgStructMap[pkg] {
+		if !s.types[pkg][structInfo.Name] {
+			continue
+		}
+		files[structInfo.RFilePath] = true
+		// 分组声明 type ( ... ) 中的类型只展示自身的定义
+		_, specStart, _ := typeSpecRange(structInfo)
+		docType := &DocType{
+			Name:       structInfo.Name,
+			Doc:        structInfo.Doc,
+			Definition: typeSpecSource(structInfo),
+			Source:     s.sourceLink(docPkg.Page, structInfo.RFilePath, structInfo.StartPosition.Line+strings.Count(structInfo.Content[:specStart], "\n")),
+		}
+		if structInfo.IsInterface {
+			for _, method := range structInfo.Methods {
+				docType.Refs = s.appendRefs(docType.Refs, docPkg.Page, pkg, method.Params, method.Results)
+			}
+			docType.Refs = s.appendRefs(docType.Refs, docPkg.Page, pkg, structInfo.Embeds)
+		} else {
+			docType.Refs = s.appendRefs(docType.Refs, docPkg.Page, pkg, structInfo.Fields)
+		}
+		typeMap[structInfo.Name] = docType
+		docPkg.Types = append(docPkg.Types, docType)
+	}
+	for _, funcInfo := range s.modInfo.PkgFuncMap[pkg] {
+		if strings.Contains(funcInfo.Name, "$") || !s.visible(funcInfo.Name, funcInfo.RFilePath) {
+			continue
+		}
+		files[funcInfo.RFilePath] = true
+		signature, _ := splitFuncContent(funcInfo.Content, len(funcInfo.Content))
+		docFunc := &DocFunc{
+			Name:      funcInfo.Name,
+			Anchor:    funcInfo.Name,
+			Doc:       funcInfo.Doc,
+			Signature: signature,
+			Source:    s.sourceLink(docPkg.Page, funcInfo.RFilePath, funcInfo.StartPosition.Line),
+		}
+		docFunc.Refs = s.appendRefs(docFunc.Refs, docPkg.Page, pkg, funcInfo.Params, funcInfo.Results)
+		if funcInfo.Receiver != nil {
+			recvType := receiverTypeName(funcInfo.Receiver)
+			docType, ok := typeMap[recvType]
+			if !ok {
+				continue
+			}
+			docFunc.Anchor = recvType + "." + funcInfo.Name
+			docType.Methods = append(docType.Methods, docFunc)
+			continue
+		}
+		// 与go doc一致，返回本包类型的函数归到该类型下
+		if len(funcInfo.Results) > 0 {
+			if docType, ok := typeMap[strings.TrimPrefix(funcInfo.Results[0].BaseType, "*")]; ok {
+				docType.Funcs = append(docType.Funcs, docFunc)
+				continue
+			}
+		}
+		docPkg.Funcs = append(docPkg.Funcs, docFunc)
+	}
+	for _, varInfo := range s.modInfo.PkgVarMap[pkg] {
+		if varInfo.Name == "_" || !s.visible(varInfo.Name, varInfo.RFilePath) {
+			continue
+		}
+		files[varInfo.RFilePath] = true
+		docValue := &DocValue{
+			Name:       varInfo.Name,
+			Doc:        varInfo.Doc,
+			Definition: "var " + varInfo.Content,
+		}
+		if varInfo.StartPosition != nil {
+			docValue.Source = s.sourceLink(docPkg.Page, varInfo.RFilePath, varInfo.StartPosition.Line)
+		}
+		if varInfo.IsConst {
+			docValue.Definition = "const " + varInfo.Content
+			docPkg.Consts = append(docPkg.Consts, docValue)
+		} else {
+			docPkg.Vars = append(docPkg.Vars, docValue)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	docPkg.Name, docPkg.Doc = s.packageDoc(sortedMapKeys(files))
+	sort.Slice(docPkg.Types, func(i, j int) bool { return docPkg.Types[i].Name < docPkg.Types[j].Name })
+	sort.Slice(docPkg.Funcs, func(i, j int) bool { return docPkg.Funcs[i].Name < docPkg.Funcs[j].Name })
+	for _, docType := range docPkg.Types {
+		sort.Slice(docType.Methods, func(i, j int) bool { return docType.Methods[i].Name < docType.Methods[j].Name })
+	}
+	return docPkg
+}
+
+// visible 判断标识符是否出现在文档中，测试文件中的声明不计入
+func (s *docSite) visible(name, rFilePath string) bool {
+	if strings.HasSuffix(rFilePath, "_test.go") {
+		return false
+	}
+	return s.cfg.Unexported || ast.IsExported(name)
+}
+
+// packageDoc 从包所在目录的文件中读取包名和包注释，优先使用doc.go
+func (s *docSite) packageDoc(rFilePaths []string) (string, string) {
+	pkgDir := filepath.Join(s.modInfo.Dir, filepath.Dir(rFilePaths[0]))
+	entries, err := os.ReadDir(pkgDir)
+	if err != nil {
+		return "", ""
+	}
+	fileNames := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".go") && !strings.HasSuffix(entry.Name(), "_test.go") {
+			fileNames = append(fileNames, entry.Name())
+		}
+	}
+	sort.SliceStable(fileNames, func(i, j int) bool {
+		return fileNames[i] == "doc.go" && fileNames[j] != "doc.go"
+	})
+	name := ""
+	for _, fileName := range fileNames {
+		file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(pkgDir, fileName), nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		if name == "" {
+			name = file.Name.Name
+		}
+		if file.Doc != nil {
+			return file.Name.Name, file.Doc.Text()
+		}
+	}
+	return name, ""
+}
+
+// appendRefs 将参数、字段等引用的模块内类型追加为交叉链接
+func (s *docSite) appendRefs(refs []*DocRef, page, pkg string, varLists ...[]*vs.VarInfo) []*DocRef {
+	for _, varInfos := range varLists {
+		for _, varInfo := range varInfos {
+			refPkg, name := pkg, strings.TrimPrefix(varInfo.BaseType, "*")
+			if index := strings.LastIndex(name, "."); index >= 0 {
+				refPkg, name = name[:index], name[index+1:]
+			}
+			if !s.types[refPkg][name] {
+				continue
+			}
+			refName := name
+			if refPkg != pkg {
+				refName = path.Base(refPkg) + "." + name
+			}
+			duplicate := false
+			for _, ref := range refs {
+				duplicate = duplicate || ref.Name == refName
+			}
+			if !duplicate {
+				refs = append(refs, &DocRef{Name: refName, Link: relLink(page, s.pkgPage(refPkg)) + "#" + name})
+			}
+		}
+	}
+	return refs
+}
+
+func (s *docSite) fillImports(docPkg *DocPackage, graph *ImportGraph) {
+	if graph == nil {
+		return
+	}
+	node, ok := graph.Nodes[docPkg.Pkg]
+	if !ok {
+		return
+	}
+	toImport := func(pkg, kind string) *DocImport {
+		docImport := &DocImport{Pkg: pkg, Kind: kind}
+		if _, ok := s.types[pkg]; ok {
+			docImport.Link = relLink(docPkg.Page, s.pkgPage(pkg))
+		}
+		return docImport
+	}
+	for _, edge := range node.Imports {
+		docPkg.Imports = append(docPkg.Imports, toImport(edge.To, edge.Kind))
+	}
+	for _, edge := range node.ImportedBy {
+		docPkg.ImportedBy = append(docPkg.ImportedBy, toImport(edge.From, edge.Kind))
+	}
+	sort.Slice(docPkg.ImportedBy, func(i, j int) bool { return docPkg.ImportedBy[i].Pkg < docPkg.ImportedBy[j].Pkg })
+}
+
+// pkgPage 返回包页面相对输出目录的路径
+func (s *docSite) pkgPage(pkg string) string {
+	return "pkg/" + pkg + s.ext
+}
+
+// sourceLink 生成源码链接，未配置模板时链接到相对于页面的本地文件
+func (s *docSite) sourceLink(page, rFilePath string, line int) string {
+	rFilePath = filepath.ToSlash(rFilePath)
+	if s.cfg.SourceURL != "" {
+		return expandSourceURL(s.cfg.SourceURL, rFilePath, line)
+	}
+	outDir, err := filepath.Abs(s.cfg.OutDir)
+	if err != nil {
+		outDir = s.cfg.OutDir
+	}
+	modDir, err := filepath.Abs(s.modInfo.Dir)
+	if err != nil {
+		modDir = s.modInfo.Dir
+	}
+	pageDir := filepath.Join(outDir, filepath.FromSlash(path.Dir(page)))
+	rel, err := filepath.Rel(pageDir, filepath.Join(modDir, rFilePath))
+	if err != nil {
+		return rFilePath
+	}
+	return fmt.Sprintf("%s#L%d", filepath.ToSlash(rel), line)
+}
+
+// expandSourceURL 替换源码链接模板中的{file}和{line}
+func expandSourceURL(sourceURL, rFilePath string, line int) string {
+	link := strings.ReplaceAll(sourceURL, "{file}", filepath.ToSlash(rFilePath))
+	return strings.ReplaceAll(link, "{line}", strconv.Itoa(line))
+}
+
+// relLink 返回从一个页面到另一个页面的相对链接
+func relLink(fromPage, toPage string) string {
+	fromDir := path.Dir(fromPage)
+	rel, err := filepath.Rel(filepath.FromSlash(fromDir), filepath.FromSlash(toPage))
+	if err != nil {
+		return toPage
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (s *docSite) writePage(page, format, name string, data any) error {
+	filePath := filepath.Join(s.cfg.OutDir, filepath.FromSlash(page))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return fmt.Errorf("创建文档目录失败: %w", err)
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("创建文档页面 %s 失败: %w", page, err)
+	}
+	defer file.Close()
+	var executor interface {
+		ExecuteTemplate(w io.Writer, name string, data any) error
+	}
+	if format == DocFormatHTML {
+		executor = docHTMLTemplates
+	} else {
+		executor = docMarkdownTemplates
+	}
+	if err := executor.ExecuteTemplate(file, name, data); err != nil {
+		return fmt.Errorf("渲染文档页面 %s 失败: %w", page, err)
+	}
+	return nil
+}
+
+var docTemplateFuncs = map[string]any{
+	"trim":     strings.TrimSpace,
+	"synopsis": docSynopsis,
+	"mdCell": func(text string) string {
+		return strings.ReplaceAll(text, "|", `\|`)
+	},
+	"mermaidID": func(i int) string {
+		return fmt.Sprintf("n%d", i)
+	},
+}
+
+// docSynopsis 返回注释第一段的第一句，合并为一行，用于索引页的表格
+func docSynopsis(text string) string {
+	paragraph, _, _ := strings.Cut(strings.TrimSpace(text), "\n\n")
+	paragraph = strings.Join(strings.Fields(paragraph), " ")
+	if index := strings.Index(paragraph, "。"); index >= 0 {
+		return paragraph[:index+len("。")]
+	}
+	if index := strings.Index(paragraph, ". "); index >= 0 {
+		return paragraph[:index+1]
+	}
+	return paragraph
+}
+
+var docMarkdownTemplates = template.Must(template.New("markdown").Funcs(docTemplateFuncs).Parse(`
+{{- define "index" -}}
+# {{.Module}}
+
+| 包 | 说明 |
+| --- | --- |
+{{range .Packages}}| [{{.Pkg}}]({{.Page}}) | {{with .Doc}}{{synopsis . | mdCell}}{{end}} |
+{{end}}
+{{- end}}
+
+{{- define "package" -}}
+# package {{.Name}}
+
+` + "`import \"{{.Pkg}}\"`" + `
+
+{{with .Doc}}{{trim .}}
+
+{{end}}
+{{- with .Consts}}## 常量
+{{range .}}
+{{template "value" .}}
+{{- end}}
+{{end}}
+{{- with .Vars}}## 变量
+{{range .}}
+{{template "value" .}}
+{{- end}}
+{{end}}
+{{- with .Funcs}}## 函数
+{{range .}}
+{{template "func" .}}
+{{- end}}
+{{end}}
+{{- with .Types}}## 类型
+{{range .}}
+### <a id="{{.Name}}"></a>type {{.Name}}
+
+` + "```go\n{{.Definition}}\n```" + `
+
+{{with .Doc}}{{trim .}}
+
+{{end}}[源码]({{.Source}})
+{{template "refs" .Refs}}
+{{- range .Funcs}}
+{{template "func" .}}
+{{- end}}
+{{- range .Methods}}
+{{template "func" .}}
+{{- end}}
+{{end}}
+{{end}}
+{{- if or .Imports .ImportedBy}}## 导入关系
+
+` + "```mermaid" + `
+graph LR
+  self["{{.Pkg}}"]
+{{range $i, $imp := .Imports}}  self --> {{mermaidID $i}}["{{$imp.Pkg}}"]
+{{end}}
+{{- range $i, $imp := .ImportedBy}}  by{{mermaidID $i}}["{{$imp.Pkg}}"] --> self
+{{end -}}
+` + "```" + `
+{{with .Imports}}
+导入的包:
+{{range .}}
+- {{if .Link}}[{{.Pkg}}]({{.Link}}){{else}}{{.Pkg}}{{end}} ({{.Kind}})
+{{- end}}
+{{end}}
+{{- with .ImportedBy}}
+被以下包导入:
+{{range .}}
+- {{if .Link}}[{{.Pkg}}]({{.Link}}){{else}}{{.Pkg}}{{end}}
+{{- end}}
+{{end}}
+{{- end}}
+{{- end}}
+
+{{- define "func" -}}
+#### <a id="{{.Anchor}}"></a>func {{.Anchor}}
+
+` + "```go\n{{.Signature}}\n```" + `
+
+{{with .Doc}}{{trim .}}
+
+{{end}}[源码]({{.Source}})
+{{template "refs" .Refs}}
+{{- end}}
+
+{{- define "value" -}}
+` + "```go\n{{.Definition}}\n```" + `
+
+{{with .Doc}}{{trim .}}
+
+{{end}}{{with .Source}}[源码]({{.}})
+{{end}}
+{{- end}}
+
+{{- define "refs" -}}
+{{with .}}
+相关类型: {{range $i, $ref := .}}{{if $i}}, {{end}}[{{$ref.Name}}]({{$ref.Link}}){{end}}
+{{end}}
+{{- end}}
+`))
+
+var docHTMLTemplates = htmltemplate.Must(htmltemplate.New("html").Funcs(docTemplateFuncs).Parse(`
+{{- define "header" -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 0 auto; padding: 1em; }
+pre { background: #f6f8fa; padding: 0.8em; overflow-x: auto; }
+.doc { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+{{- end}}
+
+{{- define "index" -}}
+{{template "header" .Module}}
+<h1>{{.Module}}</h1>
+<table>
+<tr><th>包</th><th>说明</th></tr>
+{{range .Packages}}<tr><td><a href="{{.Page}}">{{.Pkg}}</a></td><td>{{with .Doc}}{{synopsis .}}{{end}}</td></tr>
+{{end -}}
+</table>
+</body>
+</html>
+{{end}}
+
+{{- define "package" -}}
+{{template "header" .Pkg}}
+<h1>package {{.Name}}</h1>
+<p><code>import "{{.Pkg}}"</code></p>
+{{with .Doc}}<p class="doc">{{trim .}}</p>{{end}}
+{{with .Consts}}<h2>常量</h2>
+{{range .}}{{template "value" .}}{{end}}
+{{end}}
+{{- with .Vars}}<h2>变量</h2>
+{{range .}}{{template "value" .}}{{end}}
+{{end}}
+{{- with .Funcs}}<h2>函数</h2>
+{{range .}}{{template "func" .}}{{end}}
+{{end}}
+{{- with .Types}}<h2>类型</h2>
+{{range .}}<h3 id="{{.Name}}">type {{.Name}}</h3>
+<pre><code>{{.Definition}}</code></pre>
+{{with .Doc}}<p class="doc">{{trim .}}</p>{{end}}
+<p><a href="{{.Source}}">源码</a></p>
+{{template "refs" .Refs}}
+{{range .Funcs}}{{template "func" .}}{{end}}
+{{- range .Methods}}{{template "func" .}}{{end}}
+{{- end}}
+{{end}}
+{{- if or .Imports .ImportedBy}}<h2>导入关系</h2>
+{{with .Imports}}<p>导入的包:</p>
+<ul>
+{{range .}}<li>{{if .Link}}<a href="{{.Link}}">{{.Pkg}}</a>{{else}}{{.Pkg}}{{end}} ({{.Kind}})</li>
+{{end -}}
+</ul>
+{{end}}
+{{- with .ImportedBy}}<p>被以下包导入:</p>
+<ul>
+{{range .}}<li>{{if .Link}}<a href="{{.Link}}">{{.Pkg}}</a>{{else}}{{.Pkg}}{{end}}</li>
+{{end -}}
+</ul>
+{{end}}
+{{- end -}}
+</body>
+</html>
+{{end}}
+
+{{- define "func" -}}
+<h4 id="{{.Anchor}}">func {{.Anchor}}</h4>
+<pre><code>{{.Signature}}</code></pre>
+{{with .Doc}}<p class="doc">{{trim .}}</p>{{end}}
+<p><a href="{{.Source}}">源码</a></p>
+{{template "refs" .Refs}}
+{{end}}
+
+{{- define "value" -}}
+<pre><code>{{.Definition}}</code></pre>
+{{with .Doc}}<p class="doc">{{trim .}}</p>{{end}}
+{{with .Source}}<p><a href="{{.}}">源码</a></p>{{end}}
+{{end}}
+
+{{- define "refs" -}}
+{{with .}}<p>相关类型: {{range $i, $ref := .}}{{if $i}}, {{end}}<a href="{{$ref.Link}}">{{$ref.Name}}</a>{{end}}</p>
+{{end}}
+{{- end}}
+`))
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildDocSite(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod":       "module example.com/app\n\ngo 1.21\n",
+		"model/doc.go": "// Package model 定义数据模型，标签以|分隔。\n// 第二句不出现在索引中。\npackage model\n",
+		"model/types.go": `package model
+
+type (
+	// Role 角色
+	Role string
+
+	// Team 团队
+	Team struct {
+		Name string
+	}
+)
+`,
+		"model/model.go": `package model
+
+// MaxAge 最大年龄
+const MaxAge = 150
+
+// User 用户
+type User struct {
+	Name string
+	Age  int
+}
+
+// NewUser 创建用户
+func NewUser(name string) *User { return &User{Name: name} }
+
+// Greet 打招呼
+func (u *User) Greet() string { return "hi " + u.Name }
+
+func (u *User) secret() {}
+`,
+		"api/api.go": `package api
+
+import "example.com/app/model"
+
+// Handle 处理用户请求
+func Handle(u *model.User) error { return nil }
+`,
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := BuildImportGraph([]*ModuleInfo{modInfo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outDir := t.TempDir()
+	pages, err := BuildDocSite(modInfo, graph, &DocSiteConfig{OutDir: outDir, SourceURL: "https://git.example.com/app/blob/main/{file}#L{line}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(pages, ",") != "pkg/example.com/app/api.md,pkg/example.com/app/model.md,index.md" {
+		t.Fatalf("unexpected pages: %v", pages)
+	}
+	read := func(page string) string {
+		data, err := os.ReadFile(filepath.Join(outDir, page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	index := read("index.md")
+	if !strings.Contains(index, "| [example.com/app/model](pkg/example.com/app/model.md) | Package model 定义数据模型，标签以\\|分隔。 |") {
+		t.Errorf("unexpected index:\n%s", index)
+	}
+	model := read("pkg/example.com/app/model.md")
+	for _, want := range []string{"# package model", "const MaxAge = 150", "### <a id=\"User\"></a>type User",
+		"#### <a id=\"NewUser\"></a>func NewUser", "#### <a id=\"User.Greet\"></a>func User.Greet", "func (u *User) Greet() string",
+		"[源码](https://git.example.com/app/blob/main/model/model.go#L16)", "[example.com/app/api](api.md)"} {
+		if !strings.Contains(model, want) {
+			t.Errorf("model page missing %q:\n%s", want, model)
+		}
+	}
+	if !strings.Contains(model, "```go\ntype Role string\n```") || strings.Contains(model, "type (") ||
+		!strings.Contains(model, "model/types.go#L5") {
+		t.Errorf("grouped types should be rendered one by one:\n%s", model)
+	}
+	if strings.Contains(model, "secret") {
+		t.Error("unexported method should be hidden")
+	}
+	api := read("pkg/example.com/app/api.md")
+	if !strings.Contains(api, "相关类型: [model.User](model.md#User)") || !strings.Contains(api, `self --> n0["example.com/app/model"]`) {
+		t.Errorf("unexpected api page:\n%s", api)
+	}
+	htmlDir := t.TempDir()
+	if _, err := BuildDocSite(modInfo, graph, &DocSiteConfig{OutDir: htmlDir, Format: DocFormatHTML}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(htmlDir, "pkg", "example.com", "app", "api.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `<a href="model.html#User">model.User</a>`) || !strings.Contains(string(data), "api/api.go#L6") {
+		t.Errorf("unexpected html page:\n%s", data)
+	}
+}
@@ -0,0 +1,618 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	ErrorFindingIgnored      = "ignored"        // 调用返回的error被整体丢弃
+	ErrorFindingBlank        = "blank"          // 调用返回的error被赋值给_
+	ErrorFindingUnwrapped    = "unwrapped"      // 调用得到的error未添加上下文直接返回
+	ErrorFindingErrorfNoWrap = "errorf_no_wrap" // fmt.Errorf格式化了error参数但没有使用%w
+)
+
+// errorIgnoredExcludes 不检查返回值的函数，与errcheck的默认排除项一致：打印到标准输出以及写入内存缓冲区不会失败
+var errorIgnoredExcludes = map[string]bool{
+	"fmt.Print":                      true,
+	"fmt.Printf":                     true,
+	"fmt.Println":                    true,
+	"(*bytes.Buffer).Write":          true,
+	"(*bytes.Buffer).WriteByte":      true,
+	"(*bytes.Buffer).WriteRune":      true,
+	"(*bytes.Buffer).WriteString":    true,
+	"(*strings.Builder).Write":       true,
+	"(*strings.Builder).WriteByte":   true,
+	"(*strings.Builder).WriteRune":   true,
+	"(*strings.Builder).WriteString": true,
+}
+
+// errorFprintTargets fmt.Fprint系列写入这些目标时不检查返回值
+var errorFprintTargets = map[string]bool{"os.Stdout": true, "os.Stderr": true}
+
+// ErrorFinding 一处错误处理问题
+type ErrorFinding struct {
+	Kind      string `json:"kind"`              // 问题类型
+	Call      string `json:"call"`              // 相关的调用，unwrapped为产生error的调用
+	Message   string `json:"message,omitempty"` // errorf_no_wrap为格式串
+	RFilePath string `json:"file"`              // 所在文件
+	Line      int    `json:"line"`              // 行号
+	Column    int    `json:"column"`            // 列号
+}
+
+// FuncErrorFlow 函数的错误处理问题以及可能返回的哨兵错误和错误类型
+type FuncErrorFlow struct {
+	Pkg       string          `json:"pkg"`               // 所在包
+	Func      string          `json:"func"`              // 函数名，方法为Type.Method，匿名函数为Parent$N
+	RFilePath string          `json:"file"`              // 所在文件
+	Line      int             `json:"line"`              // 函数起始行
+	Findings  []*ErrorFinding `json:"findings"`          // 按位置排序的问题
+	Returns   []string        `json:"returns,omitempty"` // 可能返回的哨兵错误（包路径.变量名）和错误类型（含*），包括透传自模块内被调用函数的
+	FuncInfo  *vs.FuncInfo    `json:"-"`
+
+	passthrough []*types.Func   // 直接或以%w返回其error的被调用函数
+	returns     map[string]bool // 可能返回的哨兵错误和错误类型
+}
+
+// ErrorFlowReport 模块的错误流分析报告
+type ErrorFlowReport struct {
+	Module string           `json:"module"`
+	Counts map[string]int   `json:"counts"` // 各类问题的数量
+	Funcs  []*FuncErrorFlow `json:"funcs"`  // 有问题或会返回哨兵错误的函数
+}
+
+// errorOrigin error变量最近一次赋值的来源
+type errorOrigin struct {
+	call   string      // 产生error的调用，为空表示在本函数内创建
+	callee *types.Func // 静态可确定的被调用函数
+}
+
+type errorFlowAnalyzer struct {
+	flows  []*FuncErrorFlow
+	byFunc map[*types.Func]*FuncErrorFlow
+}
+
+// AnalyzeErrorFlow 基于类型检查后的包分析模块内每个函数的错误处理：被忽略或赋值给_的error、
+// 未添加上下文直接返回的error、格式化了error却没有使用%w的fmt.Errorf，并计算每个函数可能返回的哨兵错误和错误类型；
+// defer和go语句中的调用不检查
+func AnalyzeErrorFlow(ctx context.Context, modInfo *ModuleInfo) (*ErrorFlowReport, error) {
+	funcs, err := loadModuleFuncs(ctx, modInfo)
+	if err != nil {
+		return nil, err
+	}
+	a := &errorFlowAnalyzer{
+		byFunc: make(map[*types.Func]*FuncErrorFlow),
+	}
+	for _, fn := range funcs {
+		flow := &FuncErrorFlow{
+			Pkg:       fn.pkg.PkgPath,
+			Func:      fn.name,
+			RFilePath: fn.rFilePath,
+			Line:      fn.line,
+			Findings:  make([]*ErrorFinding, 0),
+			FuncInfo:  fn.funcInfo,
+			returns:   make(map[string]bool),
+		}
+		(&errorFuncWalker{a: a, pkg: fn.pkg, flow: flow, rFilePath: fn.rFilePath, origins: make(map[*types.Var]*errorOrigin)}).walk(fn.body)
+		a.flows = append(a.flows, flow)
+		if fn.obj != nil {
+			a.byFunc[fn.obj] = flow
+		}
+	}
+	a.propagateReturns()
+	report := &ErrorFlowReport{Module: modInfo.Path, Counts: make(map[string]int), Funcs: make([]*FuncErrorFlow, 0)}
+	for _, flow := range a.flows {
+		flow.Returns = sortedMapKeys(flow.returns)
+		if len(flow.Findings) == 0 && len(flow.Returns) == 0 {
+			continue
+		}
+		for _, finding := range flow.Findings {
+			report.Counts[finding.Kind]++
+		}
+		report.Funcs = append(report.Funcs, flow)
+	}
+	sort.SliceStable(report.Funcs, func(i, j int) bool {
+		if report.Funcs[i].RFilePath != report.Funcs[j].RFilePath {
+			return report.Funcs[i].RFilePath < report.Funcs[j].RFilePath
+		}
+		return report.Funcs[i].Line < report.Funcs[j].Line
+	})
+	return report, nil
+}
+
+// moduleFunc 类型检查后的模块内有函数体的具名函数或匿名函数
+type moduleFunc struct {
+	pkg       *packages.Package
+	rFilePath string
+	line      int
+	node      ast.Node // *ast.FuncDecl或*ast.FuncLit
+	body      *ast.BlockStmt
+	obj       *types.Func  // 具名函数的类型对象，匿名函数为nil
+	funcInfo  *vs.FuncInfo // 对应的FuncInfo，未找到时为nil
+	name      string       // 展示名，方法为Type.Method，匿名函数为Parent$N
+}
+
+// loadModuleFuncs 加载并类型检查模块内的包，按文件和源码顺序返回所有函数，匿名函数单独作为一项
+func loadModuleFuncs(ctx context.Context, modInfo *ModuleInfo) ([]*moduleFunc, error) {
+	pkgs, err := LoadPackages(ctx, &LoadConfig{RepoPath: modInfo.Dir, LoadEnum: LoadCurrentRepo})
+	if err != nil {
+		return nil, err
+	}
+	absDir, err := filepath.Abs(modInfo.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("获取模块绝对路径失败: %w", err)
+	}
+	fileFuncs := moduleFileFuncs(modInfo)
+	funcs := make([]*moduleFunc, 0)
+	for _, pkg := range pkgs {
+		if !isModulePkg(modInfo.Path, pkg.PkgPath) || pkg.TypesInfo == nil {
+			continue
+		}
+		for _, file := range pkg.Syntax {
+			rFilePath, err := filepath.Rel(absDir, pkg.Fset.Position(file.Pos()).Filename)
+			if err != nil {
+				continue
+			}
+			rFilePath = filepath.ToSlash(rFilePath)
+			ast.Inspect(file, func(node ast.Node) bool {
+				fn := &moduleFunc{pkg: pkg, rFilePath: rFilePath, node: node}
+				switch n := node.(type) {
+				case *ast.FuncDecl:
+					fn.body = n.Body
+					fn.obj, _ = pkg.TypesInfo.Defs[n.Name].(*types.Func)
+				case *ast.FuncLit:
+					fn.body = n.Body
+				default:
+					return true
+				}
+				if fn.body == nil {
+					return false
+				}
+				position := pkg.Fset.Position(node.Pos())
+				fn.line = position.Line
+				fn.funcInfo = LocateFuncInfo(fileFuncs[rFilePath], position.Line, position.Column)
+				if fn.funcInfo != nil {
+					fn.name = skeletonDisplayName(fn.funcInfo)
+				} else if fn.obj != nil {
+					fn.name = fn.obj.Name()
+				}
+				funcs = append(funcs, fn)
+				// 继续遍历，匿名函数作为单独的函数返回
+				return true
+			})
+		}
+	}
+	return funcs, nil
+}
+
+// propagateReturns 将被调用函数可能返回的哨兵错误合并到透传其error的调用方，直到不再变化
+func (a *errorFlowAnalyzer) propagateReturns() {
+	for changed := true; changed; {
+		changed = false
+		for _, flow := range a.flows {
+			for _, callee := range flow.passthrough {
+				calleeFlow, ok := a.byFunc[callee]
+				if !ok || calleeFlow == flow {
+					continue
+				}
+				for name := range calleeFlow.returns {
+					if !flow.returns[name] {
+						flow.returns[name] = true
+						changed = true
+					}
+				}
+			}
+		}
+	}
+}
+
+type errorFuncWalker struct {
+	a         *errorFlowAnalyzer
+	pkg       *packages.Package
+	flow      *FuncErrorFlow
+	rFilePath string
+	origins   map[*types.Var]*errorOrigin
+}
+
+// walk 按源码顺序遍历函数体，不进入嵌套的匿名函数
+func (w *errorFuncWalker) walk(body *ast.BlockStmt) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt, *ast.GoStmt:
+			return false
+		case *ast.ExprStmt:
+			if call, ok := ast.Unparen(n.X).(*ast.CallExpr); ok && w.returnsError(call) && !w.excluded(call) {
+				w.addFinding(ErrorFindingIgnored, call, w.callName(call), "")
+			}
+		case *ast.AssignStmt:
+			w.assign(n.Lhs, n.Rhs)
+		case *ast.ValueSpec:
+			lhs := make([]ast.Expr, 0, len(n.Names))
+			for _, name := range n.Names {
+				lhs = append(lhs, name)
+			}
+			w.assign(lhs, n.Values)
+		case *ast.ReturnStmt:
+			for _, result := range n.Results {
+				w.returned(result, false)
+			}
+		case *ast.CallExpr:
+			w.checkErrorf(n)
+		}
+		return true
+	})
+}
+
+// assign 记录error变量的来源，并检查赋值给_的error
+func (w *errorFuncWalker) assign(lhs, rhs []ast.Expr) {
+	info := w.pkg.TypesInfo
+	if len(rhs) == 1 && len(lhs) > 1 {
+		call, ok := ast.Unparen(rhs[0]).(*ast.CallExpr)
+		if !ok {
+			return
+		}
+		tuple, ok := info.TypeOf(call).(*types.Tuple)
+		if !ok || tuple.Len() != len(lhs) {
+			return
+		}
+		for i := range lhs {
+			if w.isError(tuple.At(i).Type()) {
+				w.bind(lhs[i], call)
+			}
+		}
+		return
+	}
+	if len(lhs) != len(rhs) {
+		return
+	}
+	for i := range lhs {
+		if !w.isError(info.TypeOf(rhs[i])) {
+			continue
+		}
+		call, _ := ast.Unparen(rhs[i]).(*ast.CallExpr)
+		w.bind(lhs[i], call)
+	}
+}
+
+// bind 将error赋值给变量或_，call为nil表示来源不是调用
+func (w *errorFuncWalker) bind(lhs ast.Expr, call *ast.CallExpr) {
+	ident, ok := lhs.(*ast.Ident)
+	if !ok {
+		return
+	}
+	if ident.Name == "_" {
+		if call != nil && !w.excluded(call) {
+			w.addFinding(ErrorFindingBlank, call, w.callName(call), "")
+		}
+		return
+	}
+	v, ok := w.pkg.TypesInfo.ObjectOf(ident).(*types.Var)
+	if !ok {
+		return
+	}
+	origin := &errorOrigin{}
+	if call != nil && !w.createsError(call) {
+		origin.call = w.callName(call)
+		origin.callee = w.staticCallee(call)
+	} else if call != nil {
+		// 以%w包装的error仍然可能是被包装的哨兵错误
+		w.wrappedReturns(call)
+	}
+	w.origins[v] = origin
+}
+
+// returned 处理返回的表达式，wrapped表示表达式通过%w被包装后返回
+func (w *errorFuncWalker) returned(expr ast.Expr, wrapped bool) {
+	info := w.pkg.TypesInfo
+	expr = ast.Unparen(expr)
+	if !w.isError(info.TypeOf(expr)) {
+		return
+	}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		v, ok := info.ObjectOf(e).(*types.Var)
+		if !ok {
+			return
+		}
+		if sentinel := w.sentinelName(v); sentinel != "" {
+			w.flow.returns[sentinel] = true
+			return
+		}
+		origin, ok := w.origins[v]
+		if !ok || origin.call == "" {
+			return
+		}
+		if origin.callee != nil {
+			w.flow.passthrough = append(w.flow.passthrough, origin.callee)
+		}
+		if !wrapped {
+			w.addFinding(ErrorFindingUnwrapped, e, origin.call, "")
+		}
+	case *ast.SelectorExpr:
+		if v, ok := info.ObjectOf(e.Sel).(*types.Var); ok {
+			if sentinel := w.sentinelName(v); sentinel != "" {
+				w.flow.returns[sentinel] = true
+			}
+		}
+	case *ast.CallExpr:
+		if w.createsError(e) {
+			w.wrappedReturns(e)
+			return
+		}
+		if callee := w.staticCallee(e); callee != nil {
+			w.flow.passthrough = append(w.flow.passthrough, callee)
+		}
+		if !wrapped {
+			w.addFinding(ErrorFindingUnwrapped, e, w.callName(e), "")
+		}
+	case *ast.CompositeLit, *ast.UnaryExpr:
+		if t := info.TypeOf(e); t != nil && !types.IsInterface(t) {
+			w.flow.returns[types.TypeString(t, nil)] = true
+		}
+	}
+}
+
+// wrappedReturns 记录fmt.Errorf中以%w包装的error
+func (w *errorFuncWalker) wrappedReturns(call *ast.CallExpr) {
+	if w.calleeName(call) != "fmt.Errorf" || len(call.Args) < 2 {
+		return
+	}
+	format, ok := w.constString(call.Args[0])
+	if !ok {
+		return
+	}
+	for _, verb := range parseFormatVerbs(format) {
+		if verb.verb == 'w' && verb.arg+1 < len(call.Args) {
+			w.returned(call.Args[verb.arg+1], true)
+		}
+	}
+}
+
+// checkErrorf 检查格式化了error参数却没有使用%w的fmt.Errorf
+func (w *errorFuncWalker) checkErrorf(call *ast.CallExpr) {
+	if len(errorfUnwrappedVerbs(w.pkg.TypesInfo, call)) == 0 {
+		return
+	}
+	format, _ := w.constString(call.Args[0])
+	w.addFinding(ErrorFindingErrorfNoWrap, call, "fmt.Errorf", format)
+}
+
+// errorfUnwrappedVerbs 返回格式串为常量且没有使用%w的fmt.Errorf中，以%v或%s格式化error参数的动词，
+// 错误流分析和errorfwrap检查共用这一规则
+func errorfUnwrappedVerbs(info *types.Info, call *ast.CallExpr) []formatVerb {
+	if len(call.Args) < 2 || calleeFullName(info, call) != "fmt.Errorf" {
+		return nil
+	}
+	tv, ok := info.Types[call.Args[0]]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return nil
+	}
+	verbs := parseFormatVerbs(constant.StringVal(tv.Value))
+	result := make([]formatVerb, 0)
+	for _, verb := range verbs {
+		if verb.verb == 'w' {
+			return nil
+		}
+		if verb.arg+1 < len(call.Args) && (verb.verb == 'v' || verb.verb == 's') && isErrorType(info.TypeOf(call.Args[verb.arg+1])) {
+			result = append(result, verb)
+		}
+	}
+	return result
+}
+
+// formatVerb 格式串中的一个动词
+type formatVerb struct {
+	start   int  // 动词在格式串中的起始字节偏移，指向%
+	end     int  // 动词在格式串中的结束字节偏移
+	verb    rune // 动词字符
+	arg     int  // 对应的参数序号，从0开始，不含格式串本身
+	indexed bool // 是否使用%[n]显式指定了参数
+}
+
+// parseFormatVerbs 按fmt的规则解析格式串中的动词及其对应的参数：%%不占用参数，宽度和精度中的*占用一个参数，
+// %[n]将后续参数序号重置为n
+func parseFormatVerbs(format string) []formatVerb {
+	verbs := make([]formatVerb, 0)
+	arg := 0
+	for i := 0; i < len(format); {
+		if format[i] != '%' {
+			i++
+			continue
+		}
+		verb := formatVerb{start: i}
+		i++
+	flags:
+		for i < len(format) {
+			switch c := format[i]; {
+			case c == '*':
+				arg++
+				i++
+			case c == '[':
+				end := strings.IndexByte(format[i:], ']')
+				if end < 0 {
+					i = len(format)
+					break flags
+				}
+				if n, err := strconv.Atoi(format[i+1 : i+end]); err == nil && n > 0 {
+					arg = n - 1
+				}
+				verb.indexed = true
+				i += end + 1
+			case strings.IndexByte("+-# .0123456789", c) >= 0:
+				i++
+			default:
+				break flags
+			}
+		}
+		if i >= len(format) {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(format[i:])
+		i += size
+		if r == '%' {
+			continue
+		}
+		verb.end, verb.verb, verb.arg = i, r, arg
+		verbs = append(verbs, verb)
+		arg++
+	}
+	return verbs
+}
+
+// createsError 判断调用是否在本地创建error，创建时已带有上下文
+func (w *errorFuncWalker) createsError(call *ast.CallExpr) bool {
+	switch w.calleeName(call) {
+	case "errors.New", "fmt.Errorf", "errors.Join":
+		return true
+	}
+	return false
+}
+
+// sentinelName 包级error变量视为哨兵错误
+func (w *errorFuncWalker) sentinelName(v *types.Var) string {
+	if v.Pkg() == nil || v.Parent() != v.Pkg().Scope() {
+		return ""
+	}
+	return v.Pkg().Path() + "." + v.Name()
+}
+
+// returnsError 判断调用的结果中是否包含error
+func (w *errorFuncWalker) returnsError(call *ast.CallExpr) bool {
+	tv, ok := w.pkg.TypesInfo.Types[call]
+	if !ok || tv.IsType() || tv.IsBuiltin() {
+		return false
+	}
+	if tuple, ok := tv.Type.(*types.Tuple); ok {
+		for i := 0; i < tuple.Len(); i++ {
+			if w.isError(tuple.At(i).Type()) {
+				return true
+			}
+		}
+		return false
+	}
+	return w.isError(tv.Type)
+}
+
+// isError 判断类型是否为error接口或实现了error的具名类型
+func (w *errorFuncWalker) isError(t types.Type) bool {
+	return isErrorType(t)
+}
+
+// isErrorType 判断类型是否为error接口或实现了error的具名类型，其他接口和未命名类型不算
+func isErrorType(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	errorType := types.Universe.Lookup("error").Type()
+	if types.Identical(t, errorType) {
+		return true
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		return false
+	}
+	if _, ok := types.Unalias(t).(*types.Named); !ok {
+		if pointer, ok := t.(*types.Pointer); !ok || !isNamedType(pointer.Elem()) {
+			return false
+		}
+	}
+	return types.Implements(t, errorType.Underlying().(*types.Interface))
+}
+
+// isNamedType 判断是否为具名类型
+func isNamedType(t types.Type) bool {
+	_, ok := types.Unalias(t).(*types.Named)
+	return ok
+}
+
+// excluded 判断调用是否属于不需要检查返回值的函数
+func (w *errorFuncWalker) excluded(call *ast.CallExpr) bool {
+	name := w.calleeName(call)
+	if errorIgnoredExcludes[name] {
+		return true
+	}
+	if strings.HasPrefix(name, "fmt.Fprint") && len(call.Args) > 0 {
+		if errorFprintTargets[types.ExprString(call.Args[0])] {
+			return true
+		}
+		switch types.TypeString(w.pkg.TypesInfo.TypeOf(call.Args[0]), nil) {
+		case "*bytes.Buffer", "*strings.Builder":
+			return true
+		}
+	}
+	return false
+}
+
+// staticCallee 返回静态可确定的被调用函数，泛型函数返回其原始声明
+func (w *errorFuncWalker) staticCallee(call *ast.CallExpr) *types.Func {
+	return staticCalleeOf(w.pkg.TypesInfo, call)
+}
+
+// calleeName 返回被调用函数的全名
+func (w *errorFuncWalker) calleeName(call *ast.CallExpr) string {
+	return calleeFullName(w.pkg.TypesInfo, call)
+}
+
+// staticCalleeOf 返回静态可确定的被调用函数，泛型函数返回其原始声明
+func staticCalleeOf(info *types.Info, call *ast.CallExpr) *types.Func {
+	callee := typeutil.StaticCallee(info, call)
+	if callee == nil {
+		return nil
+	}
+	return callee.Origin()
+}
+
+// calleeFullName 返回被调用函数的全名，如fmt.Errorf、(*bytes.Buffer).Write，接口方法为(pkg.Iface).Method
+func calleeFullName(info *types.Info, call *ast.CallExpr) string {
+	if callee := staticCalleeOf(info, call); callee != nil {
+		return callee.FullName()
+	}
+	if selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+		if selection := info.Selections[selector]; selection != nil {
+			if fn, ok := selection.Obj().(*types.Func); ok {
+				return fn.FullName()
+			}
+		}
+	}
+	return ""
+}
+
+// callName 返回调用在源码中的写法
+func (w *errorFuncWalker) callName(call *ast.CallExpr) string {
+	return types.ExprString(call.Fun)
+}
+
+// constString 返回常量字符串表达式的值
+func (w *errorFuncWalker) constString(expr ast.Expr) (string, bool) {
+	tv, ok := w.pkg.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// addFinding 记录问题
+func (w *errorFuncWalker) addFinding(kind string, node ast.Node, call, message string) {
+	position := w.pkg.Fset.Position(node.Pos())
+	w.flow.Findings = append(w.flow.Findings, &ErrorFinding{
+		Kind:      kind,
+		Call:      call,
+		Message:   message,
+		RFilePath: w.rFilePath,
+		Line:      position.Line,
+		Column:    position.Column,
+	})
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeErrorFlow(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"store/store.go": `package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type ValidationError struct {
+	Field string
+}
+
+func (e *ValidationError) Error() string { return e.Field }
+
+func find(id int) (string, error) {
+	if id == 0 {
+		return "", ErrNotFound
+	}
+	if id < 0 {
+		return "", &ValidationError{Field: "id"}
+	}
+	return "x", nil
+}
+
+func Load(id int) (string, error) {
+	name, err := find(id)
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func Save(path string) error {
+	os.Remove(path)
+	_ = os.Chmod(path, 0o644)
+	fmt.Println("saving", path)
+	if _, err := Load(1); err != nil {
+		return fmt.Errorf("加载失败: %v", err)
+	}
+	_, err := Load(2)
+	if err != nil {
+		return fmt.Errorf("加载失败: %w", err)
+	}
+	defer os.Remove(path)
+	return nil
+}
+
+func Run() error {
+	fn := func() error {
+		return os.Mkdir("tmp", 0o755)
+	}
+	return fn()
+}
+`,
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := AnalyzeErrorFlow(context.Background(), modInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flows := make(map[string]*FuncErrorFlow)
+	for _, flow := range report.Funcs {
+		flows[flow.Func] = flow
+	}
+	if find := flows["find"]; find == nil || strings.Join(find.Returns, ",") != "*example.com/app/store.ValidationError,example.com/app/store.ErrNotFound" || len(find.Findings) != 0 {
+		t.Fatalf("find分析错误: %+v", find)
+	}
+	load := flows["Load"]
+	if load == nil || len(load.Findings) != 1 || load.Findings[0].Kind != ErrorFindingUnwrapped || load.Findings[0].Call != "find" || load.Findings[0].Line != 30 {
+		t.Fatalf("Load分析错误: %+v", load)
+	}
+	if len(load.Returns) != 2 {
+		t.Errorf("Load应透传find的错误: %v", load.Returns)
+	}
+	save := flows["Save"]
+	if save == nil || len(save.Findings) != 3 {
+		t.Fatalf("Save分析错误: %+v", save)
+	}
+	kinds := make([]string, 0)
+	for _, finding := range save.Findings {
+		kinds = append(kinds, finding.Kind+":"+finding.Call)
+	}
+	if strings.Join(kinds, ",") != "ignored:os.Remove,blank:os.Chmod,errorf_no_wrap:fmt.Errorf" {
+		t.Errorf("Save问题错误: %v", kinds)
+	}
+	if strings.Join(save.Returns, ",") != strings.Join(load.Returns, ",") {
+		t.Errorf("Save应通过%%w透传Load的错误: %v", save.Returns)
+	}
+	if flows["Run$1"] == nil || flows["Run$1"].Findings[0].Call != "os.Mkdir" {
+		t.Errorf("匿名函数分析错误: %+v", flows["Run$1"])
+	}
+	if run := flows["Run"]; run == nil || run.Findings[0].Call != "fn" {
+		t.Errorf("Run分析错误: %+v", run)
+	}
+	if report.Counts[ErrorFindingUnwrapped] != 3 || report.Counts[ErrorFindingIgnored] != 1 {
+		t.Errorf("问题统计错误: %v", report.Counts)
+	}
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+)
+
+const (
+	ImportKindIntraModule = "intra_module" // 同模块内的包
+	ImportKindCrossModule = "cross_module" // 仓库内其他模块的包
+	ImportKindStdlib      = "stdlib"       // 标准库
+	ImportKindThirdParty  = "third_party"  // 第三方包
+)
+
+// ImportNode 表示导入图中的一个包
+type ImportNode struct {
+	Pkg        string        // 包路径
+	Module     string        // 所属仓库内模块路径，外部包为空
+	Kind       string        // 相对于仓库的包类型，仓库内的包为 intra_module
+	Imports    []*ImportEdge // 该包导入的包
+	ImportedBy []*ImportEdge // 导入该包的包
+}
+
+// FanOut 该包直接依赖的包数量
+func (n *ImportNode) FanOut() int {
+	return len(n.Imports)
+}
+
+// FanIn 直接依赖该包的包数量
+func (n *ImportNode) FanIn() int {
+	return len(n.ImportedBy)
+}
+
+// ImportEdge 表示一个包对另一个包的导入
+type ImportEdge struct {
+	From      string                // 导入方包路径
+	To        string                // 被导入包路径
+	Kind      string                // 导入类型
+	Positions []*vs.BaseAstPosition // 导入语句所在位置
+}
+
+// ImportGraph 包级别的导入关系图
+type ImportGraph struct {
+	Nodes map[string]*ImportNode
+	Edges []*ImportEdge
+}
+
+// LayerRule 分层规则，From匹配的包不允许导入To匹配的包
+type LayerRule struct {
+	From string // 导入方包匹配模式
+	To   string // 被导入包匹配模式
+	Line int    // 规则在规则文件中的行号
+}
+
+// LayerViolation 分层规则违规
+type LayerViolation struct {
+	Rule     *LayerRule
+	Edge     *ImportEdge
+	Position *vs.BaseAstPosition
+}
+
+// BuildImportGraph 根据仓库内所有模块的源码构建包级别导入图，测试文件不计入
+func BuildImportGraph(modules []*ModuleInfo) (*ImportGraph, error) {
+	graph := &ImportGraph{Nodes: make(map[string]*ImportNode)}
+	edgeMap := make(map[[2]string]*ImportEdge)
+	for _, modInfo := range modules {
+		fileSet := token.NewFileSet()
+		err := walkModuleGoFiles(modInfo.Dir, func(filePath, rFilePath string) error {
+			file, err := parser.ParseFile(fileSet, filePath, nil, parser.ImportsOnly)
+			if err != nil {
+				return fmt.Errorf("解析文件 %s 失败: %w", filePath, err)
+			}
+			fromPkg := modulePkgPath(modInfo.Path, filepath.Dir(rFilePath))
+			fromNode := graph.node(fromPkg, modInfo.Path, ImportKindIntraModule)
+			for _, imp := range file.Imports {
+				importPath := strings.Trim(imp.Path.Value, `"`)
+				kind, toModule := classifyImport(modInfo.Path, importPath, modules)
+				nodeKind := kind
+				if toModule != "" {
+					nodeKind = ImportKindIntraModule
+				}
+				toNode := graph.node(importPath, toModule, nodeKind)
+				key := [2]string{fromPkg, importPath}
+				edge, ok := edgeMap[key]
+				if !ok {
+					edge = &ImportEdge{From: fromPkg, To: importPath, Kind: kind}
+					edgeMap[key] = edge
+					graph.Edges = append(graph.Edges, edge)
+					fromNode.Imports = append(fromNode.Imports, edge)
+					toNode.ImportedBy = append(toNode.ImportedBy, edge)
+				}
+				position := fileSet.Position(imp.Pos())
+				edge.Positions = append(edge.Positions, &vs.BaseAstPosition{
+					RFilePath: rFilePath,
+					OffSet:    position.Offset,
+					Line:      position.Line,
+					Column:    position.Column,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return graph, err
+		}
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+	return graph, nil
+}
+
+func (g *ImportGraph) node(pkg, module, kind string) *ImportNode {
+	node, ok := g.Nodes[pkg]
+	if !ok {
+		node = &ImportNode{Pkg: pkg, Module: module, Kind: kind}
+		g.Nodes[pkg] = node
+	}
+	return node
+}
+
+// FindCycles 使用Tarjan算法查找仓库内包之间的导入环，每个环按包路径排序
+func (g *ImportGraph) FindCycles() [][]string {
+	index := 0
+	indices := make(map[string]int)
+	lowLinks := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	cycles := make([][]string, 0)
+	var strongConnect func(pkg string)
+	strongConnect = func(pkg string) {
+		indices[pkg] = index
+		lowLinks[pkg] = index
+		index++
+		stack = append(stack, pkg)
+		onStack[pkg] = true
+		selfLoop := false
+		for _, edge := range g.Nodes[pkg].Imports {
+			if g.Nodes[edge.To].Module == "" {
+				continue
+			}
+			if edge.To == pkg {
+				selfLoop = true
+			}
+			if _, visited := indices[edge.To]; !visited {
+				strongConnect(edge.To)
+				lowLinks[pkg] = min(lowLinks[pkg], lowLinks[edge.To])
+			} else if onStack[edge.To] {
+				lowLinks[pkg] = min(lowLinks[pkg], indices[edge.To])
+			}
+		}
+		if lowLinks[pkg] != indices[pkg] {
+			return
+		}
+		component := make([]string, 0)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == pkg {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+	for _, pkg := range g.sortedPkgs() {
+		if _, visited := indices[pkg]; !visited && g.Nodes[pkg].Module != "" {
+			strongConnect(pkg)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+// CheckLayers 检查仓库内包的导入是否违反分层规则
+func (g *ImportGraph) CheckLayers(rules []*LayerRule) []*LayerViolation {
+	violations := make([]*LayerViolation, 0)
+	for _, edge := range g.Edges {
+		fromNode := g.Nodes[edge.From]
+		toNode := g.Nodes[edge.To]
+		for _, rule := range rules {
+			if !matchLayerPattern(rule.From, fromNode) || !matchLayerPattern(rule.To, toNode) {
+				continue
+			}
+			for _, position := range edge.Positions {
+				violations = append(violations, &LayerViolation{Rule: rule, Edge: edge, Position: position})
+			}
+		}
+	}
+	return violations
+}
+
+func (g *ImportGraph) sortedPkgs() []string {
+	pkgs := make([]string, 0, len(g.Nodes))
+	for pkg := range g.Nodes {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// ParseLayerRules 解析分层规则，每行一条 "service/* -> handler/*"，表示左侧的包不允许导入右侧的包，#开头为注释
+func ParseLayerRules(r io.Reader) ([]*LayerRule, error) {
+	rules := make([]*LayerRule, 0)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		from, to, ok := strings.Cut(line, "->")
+		if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			return nil, fmt.Errorf("第%d行分层规则格式错误: %s", lineNum, line)
+		}
+		rules = append(rules, &LayerRule{From: strings.TrimSpace(from), To: strings.TrimSpace(to), Line: lineNum})
+	}
+	return rules, scanner.Err()
+}
+
+// matchLayerPattern 匹配包路径，模式可以是完整包路径或相对模块根目录的路径；
+// 结尾的 /* 和 /... 都匹配目录自身的包及所有子包，因此 "service/* -> handler/*" 同样约束service包本身，其余部分按 path.Match 匹配
+func matchLayerPattern(pattern string, node *ImportNode) bool {
+	candidates := []string{node.Pkg}
+	if node.Module != "" {
+		if rel := strings.TrimPrefix(strings.TrimPrefix(node.Pkg, node.Module), "/"); rel != node.Pkg {
+			candidates = append(candidates, rel)
+		}
+	}
+	for _, candidate := range candidates {
+		if matchPathPattern(pattern, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPathPattern(pattern, pkg string) bool {
+	prefix, ok := strings.CutSuffix(pattern, "/...")
+	if !ok {
+		prefix, ok = strings.CutSuffix(pattern, "/*")
+	}
+	if ok {
+		elems := strings.Split(pkg, "/")
+		prefixElems := strings.Split(prefix, "/")
+		if len(elems) < len(prefixElems) {
+			return false
+		}
+		matched, _ := path.Match(prefix, strings.Join(elems[:len(prefixElems)], "/"))
+		return matched
+	}
+	matched, _ := path.Match(pattern, pkg)
+	return matched
+}
+
+// classifyImport 判断导入包相对于当前模块的类型，仓库内的包同时返回所属模块路径
+func classifyImport(curModule, importPath string, modules []*ModuleInfo) (string, string) {
+	owner := ""
+	for _, modInfo := range modules {
+		if (importPath == modInfo.Path || strings.HasPrefix(importPath, modInfo.Path+"/")) && len(modInfo.Path) > len(owner) {
+			owner = modInfo.Path
+		}
+	}
+	switch {
+	case owner == curModule:
+		return ImportKindIntraModule, owner
+	case owner != "":
+		return ImportKindCrossModule, owner
+	case isStdlibPkg(importPath):
+		return ImportKindStdlib, ""
+	default:
+		return ImportKindThirdParty, ""
+	}
+}
+
+// isStdlibPkg 首段路径不含 . 的视为标准库
+func isStdlibPkg(pkgPath string) bool {
+	first, _, _ := strings.Cut(pkgPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// modulePkgPath 根据模块路径和相对目录拼接包路径
+func modulePkgPath(modPath, relDir string) string {
+	if relDir == "." || relDir == "" {
+		return modPath
+	}
+	return modPath + "/" + filepath.ToSlash(relDir)
+}
+
+// moduleGoFile 解析后的模块内非测试.go文件
+type moduleGoFile struct {
+	pkg       string // 所在包的导入路径
+	rFilePath string // 相对模块目录的文件路径
+	file      *ast.File
+	imports   map[string]string // 别名 -> 导入路径
+}
+
+// parseModuleGoFiles 按walkModuleGoFiles的顺序解析模块内非测试的.go文件，并记录每个文件的包路径和导入别名
+func parseModuleGoFiles(modInfo *ModuleInfo, fileSet *token.FileSet) ([]*moduleGoFile, error) {
+	files := make([]*moduleGoFile, 0)
+	err := walkModuleGoFiles(modInfo.Dir, func(filePath, rFilePath string) error {
+		file, err := parser.ParseFile(fileSet, filePath, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return fmt.Errorf("解析文件 %s 失败: %w", filePath, err)
+		}
+		goFile := &moduleGoFile{
+			pkg:       modulePkgPath(modInfo.Path, path.Dir(rFilePath)),
+			rFilePath: rFilePath,
+			file:      file,
+			imports:   make(map[string]string),
+		}
+		for _, importSpec := range file.Imports {
+			importPath := strings.Trim(importSpec.Path.Value, `"`)
+			goFile.imports[importLocalName(importSpec, importPath)] = importPath
+		}
+		files = append(files, goFile)
+		return nil
+	})
+	return files, err
+}
+
+// walkModuleGoFiles 遍历模块内非测试的.go文件，跳过隐藏目录、testdata、vendor以及嵌套模块
+func walkModuleGoFiles(modDir string, handle func(filePath, rFilePath string) error) error {
+	return filepath.WalkDir(modDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if filePath == modDir {
+				return nil
+			}
+			name := d.Name()
+			if strings.HasPrefix(name, ".") || name == "testdata" || name == "vendor" {
+				return fs.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(filePath, "go.mod")); err == nil {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".go") || strings.HasSuffix(d.Name(), "_test.go") {
+			return nil
+		}
+		rFilePath, err := filepath.Rel(modDir, filePath)
+		if err != nil {
+			return err
+		}
+		return handle(filePath, filepath.ToSlash(rFilePath))
+	})
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildImportGraph(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"app/go.mod":                  "module example.com/app\n\ngo 1.21\n",
+		"app/service/svc.go":          "package service\n\nimport (\n\t\"fmt\"\n\t\"example.com/app/handler\"\n\t\"example.com/lib/util\"\n)\n",
+		"app/handler/handler.go":      "package handler\n\nimport \"example.com/app/service\"\n",
+		"app/handler/handler2.go":     "package handler\n\nimport \"github.com/gin-gonic/gin\"\n",
+		"app/handler/handler_test.go": "package handler\n\nimport \"testing\"\n",
+		"lib/go.mod":                  "module example.com/lib\n\ngo 1.21\n",
+		"lib/util/util.go":            "package util\n\nimport \"strings\"\n",
+	})
+	modules, err := ParseRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := BuildImportGraph(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]string{
+		"fmt":                      ImportKindStdlib,
+		"example.com/app/handler":  ImportKindIntraModule,
+		"example.com/lib/util":     ImportKindCrossModule,
+		"github.com/gin-gonic/gin": ImportKindThirdParty,
+	}
+	for _, edge := range graph.Edges {
+		if want, ok := kinds[edge.To]; ok && edge.From == "example.com/app/service" && edge.Kind != want {
+			t.Errorf("%s: expected %s, got %s", edge.To, want, edge.Kind)
+		}
+		if edge.To == "testing" {
+			t.Errorf("test file imports should be excluded")
+		}
+	}
+	service := graph.Nodes["example.com/app/service"]
+	if service.FanOut() != 3 || service.FanIn() != 1 {
+		t.Errorf("unexpected fan-out/fan-in: %d/%d", service.FanOut(), service.FanIn())
+	}
+	cycles := graph.FindCycles()
+	if len(cycles) != 1 || strings.Join(cycles[0], ",") != "example.com/app/handler,example.com/app/service" {
+		t.Fatalf("unexpected cycles: %v", cycles)
+	}
+	rules, err := ParseLayerRules(strings.NewReader("# 业务层不依赖接入层\nservice/* -> handler/*\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations := graph.CheckLayers(rules)
+	if len(violations) != 1 {
+		t.Fatalf("expected one violation, got %d", len(violations))
+	}
+	if violations[0].Position.RFilePath != "service/svc.go" || violations[0].Position.Line != 5 {
+		t.Errorf("unexpected violation position: %+v", violations[0].Position)
+	}
+	patterns := map[string]bool{
+		"service/* service":       true,
+		"service/* service/user":  true,
+		"service/* services":      false,
+		"service/... service/a/b": true,
+		"service/... handler":     false,
+		"*/internal/* a/internal": true,
+		"service service/user":    false,
+	}
+	for input, want := range patterns {
+		pattern, pkg, _ := strings.Cut(input, " ")
+		if got := matchPathPattern(pattern, pkg); got != want {
+			t.Errorf("matchPathPattern(%q, %q) = %v, want %v", pattern, pkg, got, want)
+		}
+	}
+	if _, err := ParseLayerRules(strings.NewReader("service/*\n")); err == nil {
+		t.Errorf("expected error for malformed rule")
+	}
+}
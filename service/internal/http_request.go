@@ -13,27 +13,57 @@ var httpClient = http.Client{
 	Timeout: time.Second * 30,
 }
 
+// NewFileHttpClient 创建支持file协议的客户端，file://请求只能读取root目录下的文件，URL路径相对于root
+func NewFileHttpClient(root string) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.RegisterProtocol("file", http.NewFileTransport(http.Dir(root)))
+	return &http.Client{Timeout: httpClient.Timeout, Transport: transport}
+}
+
+// StatusError 响应状态码非200时返回的错误
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("response status code is not 200, status code: %d", e.StatusCode)
+}
+
 // SendHttpRequest 发送请求
 func SendHttpRequest[T any](ctx context.Context, request *http.Request) (*T, error) {
+	body, err := SendHttpRequestRaw(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	var t T
+	err = json.Unmarshal(body, &t)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal response body failed, err: %w", err)
+	}
+	return &t, nil
+}
+
+// SendHttpRequestRaw 发送请求并返回原始响应体
+func SendHttpRequestRaw(ctx context.Context, request *http.Request) ([]byte, error) {
+	return SendHttpRequestRawByClient(ctx, &httpClient, request)
+}
+
+// SendHttpRequestRawByClient 使用指定的客户端发送请求并返回原始响应体
+func SendHttpRequestRawByClient(ctx context.Context, client *http.Client, request *http.Request) ([]byte, error) {
 	if request == nil {
 		return nil, fmt.Errorf("request is nil")
 	}
-	resp, err := httpClient.Do(request)
+	resp, err := client.Do(request.WithContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("send request failed, err: %w", err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("response status code is not 200, status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("read response body failed, err: %w", err)
 	}
-	var t T
-	err = json.Unmarshal(body, &t)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshal response body failed, err: %w", err)
-	}
-	return &t, nil
+	return body, nil
 }
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+const (
+	// LicenseUnknown 无法识别的许可证
+	LicenseUnknown = "UNKNOWN"
+	// DefaultLicenseMinConfidence 识别结果的默认最低置信度
+	DefaultLicenseMinConfidence = 0.75
+
+	LicenseSourceModCache = "modcache"
+	LicenseSourceVendor   = "vendor"
+	LicenseSourceLocal    = "local"
+)
+
+// licenseSignature 许可证的特征短语，出现任一排除短语则不匹配
+type licenseSignature struct {
+	spdx     string
+	phrases  []string
+	excludes []string
+}
+
+// licenseSignatures 常见许可证的特征短语，均为归一化后的文本
+var licenseSignatures = []licenseSignature{
+	{spdx: "MIT", phrases: []string{
+		"permission is hereby granted free of charge to any person obtaining a copy",
+		"to deal in the software without restriction",
+		"the above copyright notice and this permission notice shall be included in all copies or substantial portions of the software",
+		"the software is provided as is without warranty of any kind",
+	}},
+	{spdx: "Apache-2.0", phrases: []string{
+		"apache license",
+		"version 2 0",
+		"terms and conditions for use reproduction and distribution",
+		"grant of copyright license",
+		"grant of patent license",
+	}},
+	{spdx: "BSD-3-Clause", phrases: []string{
+		"redistribution and use in source and binary forms with or without modification are permitted provided that the following conditions are met",
+		"redistributions of source code must retain the above copyright notice",
+		"redistributions in binary form must reproduce the above copyright notice",
+		"neither the name of",
+		"this software is provided by the copyright holders and contributors as is",
+	}},
+	{spdx: "BSD-2-Clause", phrases: []string{
+		"redistribution and use in source and binary forms with or without modification are permitted provided that the following conditions are met",
+		"redistributions of source code must retain the above copyright notice",
+		"redistributions in binary form must reproduce the above copyright notice",
+		"this software is provided by the copyright holders and contributors as is",
+	}, excludes: []string{"neither the name of"}},
+	{spdx: "ISC", phrases: []string{
+		"permission to use copy modify and or distribute this software for any purpose with or without fee is hereby granted",
+		"the above copyright notice and this permission notice appear in all copies",
+		"the software is provided as is and the author disclaims all warranties",
+	}},
+	{spdx: "MPL-2.0", phrases: []string{
+		"mozilla public license version 2 0",
+		"covered software",
+		"larger work",
+		"executable form",
+	}},
+	{spdx: "GPL-2.0", phrases: []string{
+		"gnu general public license",
+		"version 2 june 1991",
+		"everyone is permitted to copy and distribute verbatim copies",
+	}, excludes: []string{"lesser general public license", "library general public license"}},
+	{spdx: "GPL-3.0", phrases: []string{
+		"gnu general public license",
+		"version 3 29 june 2007",
+		"everyone is permitted to copy and distribute verbatim copies",
+	}, excludes: []string{"lesser general public license", "affero general public license"}},
+	{spdx: "LGPL-2.1", phrases: []string{
+		"gnu lesser general public license",
+		"version 2 1 february 1999",
+	}},
+	{spdx: "LGPL-3.0", phrases: []string{
+		"gnu lesser general public license",
+		"version 3 29 june 2007",
+	}},
+	{spdx: "AGPL-3.0", phrases: []string{
+		"gnu affero general public license",
+		"version 3 19 november 2007",
+	}},
+	{spdx: "Unlicense", phrases: []string{
+		"this is free and unencumbered software released into the public domain",
+		"anyone is free to copy modify publish use compile sell or distribute this software",
+	}},
+	{spdx: "CC0-1.0", phrases: []string{
+		"creative commons",
+		"cc0 1 0 universal",
+	}},
+	{spdx: "EPL-2.0", phrases: []string{
+		"eclipse public license v 2 0",
+		"accompanying program is provided under the terms of this eclipse public license",
+	}},
+}
+
+var licenseNormalizeRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// LicenseMatch 单个许可证文件的识别结果
+type LicenseMatch struct {
+	File       string  `json:"file"`       // 许可证文件相对依赖目录的路径
+	SPDX       string  `json:"spdx"`       // SPDX标识
+	Confidence float64 `json:"confidence"` // 置信度，命中特征短语的比例
+}
+
+// DependencyLicense 单个依赖的许可证信息
+type DependencyLicense struct {
+	Dependency
+	Dir        string          `json:"dir"`              // 依赖源码目录
+	Source     string          `json:"source"`           // 来源：modcache/vendor/local
+	Licenses   []*LicenseMatch `json:"licenses"`         // 识别出的许可证
+	Disallowed bool            `json:"disallowed"`       // 是否违反策略
+	Reason     string          `json:"reason,omitempty"` // 违反策略的原因
+	Error      string          `json:"error,omitempty"`  // 解析过程中的错误
+}
+
+// LicensePolicy 许可证策略，Allowed为空表示除Denied外都允许
+type LicensePolicy struct {
+	Allowed       []string `json:"allowed"`
+	Denied        []string `json:"denied"`
+	MinConfidence float64  `json:"min_confidence"`
+	AllowUnknown  bool     `json:"allow_unknown"`
+}
+
+// LicenseConfig 许可证扫描配置
+type LicenseConfig struct {
+	ModCacheDir string         // 模块缓存目录，默认为GOMODCACHE
+	Policy      *LicensePolicy // 许可证策略，为空则不做检查
+}
+
+// LicenseReport 模块的许可证报告
+type LicenseReport struct {
+	Module       string               `json:"module"`
+	Dependencies []*DependencyLicense `json:"dependencies"`
+	Summary      map[string]int       `json:"summary"`    // SPDX标识 -> 依赖数量
+	Violations   []*DependencyLicense `json:"violations"` // 违反策略的依赖
+}
+
+// LoadLicensePolicy 从JSON文件加载许可证策略
+func LoadLicensePolicy(path string) (*LicensePolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取许可证策略失败: %w", err)
+	}
+	policy := &LicensePolicy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("解析许可证策略失败: %w", err)
+	}
+	return policy, nil
+}
+
+// BuildLicenseReport 识别模块所有依赖的许可证，有vendor目录时优先从vendor读取
+func BuildLicenseReport(modInfo *ModuleInfo, cfg *LicenseConfig) *LicenseReport {
+	if cfg == nil {
+		cfg = &LicenseConfig{}
+	}
+	report := &LicenseReport{
+		Module:       modInfo.Path,
+		Dependencies: make([]*DependencyLicense, 0, len(modInfo.Requires)),
+		Summary:      make(map[string]int),
+		Violations:   make([]*DependencyLicense, 0),
+	}
+	vendorDir := filepath.Join(modInfo.Dir, "vendor")
+	_, vendorErr := os.Stat(vendorDir)
+	for _, req := range modInfo.Requires {
+		depLicense := &DependencyLicense{Dependency: req}
+		report.Dependencies = append(report.Dependencies, depLicense)
+		source := module.Version{Path: req.Path, Version: req.Version}
+		if replace := findReplaceRule(modInfo.Replaces, req); replace != nil {
+			if modfile.IsDirectoryPath(replace.NewPath) {
+				depLicense.Dir = replace.NewPath
+				if !filepath.IsAbs(depLicense.Dir) {
+					depLicense.Dir = filepath.Join(modInfo.Dir, replace.NewPath)
+				}
+				depLicense.Source = LicenseSourceLocal
+			}
+			source = module.Version{Path: replace.NewPath, Version: replace.NewVersion}
+		}
+		if depLicense.Dir == "" && vendorErr == nil {
+			depLicense.Dir = filepath.Join(vendorDir, filepath.FromSlash(req.Path))
+			depLicense.Source = LicenseSourceVendor
+		} else if depLicense.Dir == "" {
+			dir, err := resolveDependencyDir(context.Background(), source, &DepParseConfig{ModCacheDir: cfg.ModCacheDir})
+			if err != nil {
+				depLicense.Error = err.Error()
+			}
+			depLicense.Dir = dir
+			depLicense.Source = LicenseSourceModCache
+		}
+		if depLicense.Error == "" {
+			licenses, err := DetectLicenses(depLicense.Dir)
+			if err != nil {
+				depLicense.Error = err.Error()
+			}
+			depLicense.Licenses = licenses
+		}
+		if len(depLicense.Licenses) == 0 {
+			report.Summary[LicenseUnknown]++
+		}
+		for _, license := range depLicense.Licenses {
+			report.Summary[license.SPDX]++
+		}
+		if cfg.Policy != nil {
+			depLicense.Disallowed, depLicense.Reason = cfg.Policy.check(depLicense.Licenses)
+			if depLicense.Disallowed {
+				report.Violations = append(report.Violations, depLicense)
+			}
+		}
+	}
+	return report
+}
+
+// DetectLicenses 识别目录根部的所有许可证文件
+func DetectLicenses(dir string) ([]*LicenseMatch, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取目录 %s 失败: %w", dir, err)
+	}
+	matches := make([]*LicenseMatch, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !isLicenseFile(entry.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return matches, fmt.Errorf("读取许可证文件失败: %w", err)
+		}
+		spdx, confidence := ClassifyLicense(string(data))
+		matches = append(matches, &LicenseMatch{File: entry.Name(), SPDX: spdx, Confidence: confidence})
+	}
+	return matches, nil
+}
+
+// ClassifyLicense 根据特征短语识别许可证文本，返回SPDX标识和置信度
+func ClassifyLicense(text string) (string, float64) {
+	normalized := " " + strings.TrimSpace(licenseNormalizeRegexp.ReplaceAllString(strings.ToLower(text), " ")) + " "
+	bestSPDX, bestConfidence := LicenseUnknown, 0.0
+	for _, signature := range licenseSignatures {
+		excluded := false
+		for _, exclude := range signature.excludes {
+			if strings.Contains(normalized, " "+exclude+" ") {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			continue
+		}
+		matched := 0
+		for _, phrase := range signature.phrases {
+			if strings.Contains(normalized, " "+phrase+" ") {
+				matched++
+			}
+		}
+		confidence := float64(matched) / float64(len(signature.phrases))
+		if confidence > bestConfidence {
+			bestSPDX, bestConfidence = signature.spdx, confidence
+		}
+	}
+	if bestConfidence < 0.5 {
+		return LicenseUnknown, bestConfidence
+	}
+	return bestSPDX, bestConfidence
+}
+
+// check 只要有一个许可证满足策略即视为允许，适用于多许可证授权的依赖；
+// 允许无法识别的许可证时，仍需所有文件中都没有禁止的许可证，结果与文件顺序无关
+func (p *LicensePolicy) check(licenses []*LicenseMatch) (bool, string) {
+	minConfidence := p.MinConfidence
+	if minConfidence <= 0 {
+		minConfidence = DefaultLicenseMinConfidence
+	}
+	reasons := make([]string, 0)
+	unknown, denied := false, false
+	for _, license := range licenses {
+		spdx := license.SPDX
+		if license.Confidence < minConfidence {
+			spdx = LicenseUnknown
+		}
+		switch {
+		case spdx == LicenseUnknown:
+			unknown = true
+			if !p.AllowUnknown {
+				reasons = append(reasons, fmt.Sprintf("%s 无法识别", license.File))
+			}
+		case containsFold(p.Denied, spdx):
+			denied = true
+			reasons = append(reasons, fmt.Sprintf("%s 为禁止的许可证 %s", license.File, spdx))
+		case len(p.Allowed) > 0 && !containsFold(p.Allowed, spdx):
+			reasons = append(reasons, fmt.Sprintf("%s 的许可证 %s 不在允许列表中", license.File, spdx))
+		default:
+			return false, ""
+		}
+	}
+	if len(licenses) == 0 {
+		if p.AllowUnknown {
+			return false, ""
+		}
+		reasons = append(reasons, "未找到许可证文件")
+	}
+	if unknown && p.AllowUnknown && !denied {
+		return false, ""
+	}
+	sort.Strings(reasons)
+	return true, strings.Join(reasons, "; ")
+}
+
+func isLicenseFile(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, prefix := range []string{"LICENSE", "LICENCE", "COPYING", "UNLICENSE"} {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, target string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, target) {
+			return true
+		}
+	}
+	return false
+}
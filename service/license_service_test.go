@@ -0,0 +1,108 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+const testMITLicense = `MIT License
+
+Copyright (c) 2020 Someone
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software.
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED.
+`
+
+const testBSD2License = `Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice.
+2. Redistributions in binary form must reproduce the above copyright notice.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+`
+
+const testGPL3License = `                    GNU GENERAL PUBLIC LICENSE
+                       Version 3, 29 June 2007
+
+ Everyone is permitted to copy and distribute verbatim copies
+ of this license document, but changing it is not allowed.
+`
+
+func TestBuildLicenseReport(t *testing.T) {
+	modCacheDir := writeTestModule(t, map[string]string{
+		"example.com/mit@v1.0.0/LICENSE":      testMITLicense,
+		"example.com/bsd@v1.0.0/LICENSE.txt":  testBSD2License,
+		"example.com/gpl@v0.1.0/COPYING":      testGPL3License,
+		"example.com/none@v1.0.0/README.md":   "no license here",
+		"example.com/dual@v2.0.0/LICENSE-MIT": testMITLicense,
+		"example.com/dual@v2.0.0/LICENSE-GPL": testGPL3License,
+	})
+	policyDir := writeTestModule(t, map[string]string{
+		"policy.json": `{"allowed": ["MIT", "BSD-2-Clause", "BSD-3-Clause"], "denied": ["GPL-3.0"]}`,
+	})
+	policy, err := LoadLicensePolicy(filepath.Join(policyDir, "policy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	modInfo := &ModuleInfo{
+		Path: "example.com/app",
+		Dir:  t.TempDir(),
+		Requires: []Dependency{
+			{Path: "example.com/mit", Version: "v1.0.0"},
+			{Path: "example.com/bsd", Version: "v1.0.0"},
+			{Path: "example.com/gpl", Version: "v0.1.0"},
+			{Path: "example.com/none", Version: "v1.0.0"},
+			{Path: "example.com/dual", Version: "v2.0.0"},
+		},
+	}
+	report := BuildLicenseReport(modInfo, &LicenseConfig{ModCacheDir: modCacheDir, Policy: policy})
+	expected := map[string]string{
+		"example.com/mit": "MIT",
+		"example.com/bsd": "BSD-2-Clause",
+		"example.com/gpl": "GPL-3.0",
+	}
+	for _, dep := range report.Dependencies {
+		want, ok := expected[dep.Path]
+		if !ok {
+			continue
+		}
+		if len(dep.Licenses) != 1 || dep.Licenses[0].SPDX != want || dep.Licenses[0].Confidence != 1 {
+			t.Errorf("%s: expected %s, got %+v", dep.Path, want, dep.Licenses)
+		}
+	}
+	violations := make(map[string]bool)
+	for _, dep := range report.Violations {
+		violations[dep.Path] = true
+	}
+	if len(violations) != 2 || !violations["example.com/gpl"] || !violations["example.com/none"] {
+		t.Errorf("unexpected violations: %v", violations)
+	}
+	if report.Summary["MIT"] != 2 || report.Summary[LicenseUnknown] != 1 {
+		t.Errorf("unexpected summary: %v", report.Summary)
+	}
+	// 允许无法识别的许可证时，其他文件中禁止的许可证仍然生效，与文件顺序无关
+	lenient := &LicensePolicy{Denied: []string{"GPL-3.0"}, AllowUnknown: true}
+	unknown := &LicenseMatch{File: "LICENSE", SPDX: LicenseUnknown}
+	gpl := &LicenseMatch{File: "COPYING", SPDX: "GPL-3.0", Confidence: 1}
+	for _, licenses := range [][]*LicenseMatch{{unknown, gpl}, {gpl, unknown}} {
+		if violated, reason := lenient.check(licenses); !violated || reason != "COPYING 为禁止的许可证 GPL-3.0" {
+			t.Errorf("denied license should not be skipped: %v %q", violated, reason)
+		}
+	}
+	if violated, _ := lenient.check([]*LicenseMatch{unknown}); violated {
+		t.Errorf("unknown license should be allowed")
+	}
+	if spdx, _ := ClassifyLicense("all rights reserved"); spdx != LicenseUnknown {
+		t.Errorf("expected unknown license, got %s", spdx)
+	}
+}
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go/format"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+type MockStyle string
+
+const (
+	MockStyleGomock MockStyle = "gomock" // 与gomock兼容的mock
+	MockStyleFake   MockStyle = "fake"   // 记录调用并可配置返回函数的手写风格fake
+)
+
+const defaultGomockImport = "go.uber.org/mock/gomock"
+
+// MockConfig mock生成配置
+type MockConfig struct {
+	Dir          string    // 加载包的工作目录
+	Package      string    // 接口所在的包，可以是导入路径或相对路径
+	Interfaces   []string  // 需要生成的接口名，为空时生成包内所有导出接口
+	Style        MockStyle // 生成风格，默认为gomock
+	OutPackage   string    // 生成文件的包名，默认与接口所在包相同
+	OutPkgPath   string    // 生成文件所在包的导入路径，包名与接口所在包相同且未指定时视为同一个包
+	GomockImport string    // gomock的导入路径，默认为go.uber.org/mock/gomock
+	Source       string    // 写入文件头的生成命令说明
+}
+
+// mockParam 生成代码中的参数
+type mockParam struct {
+	Name     string // 参数名
+	Type     string // 声明时的类型，可变参数带...
+	DataType string // 作为值使用时的类型，可变参数为切片
+}
+
+// mockMethod 生成代码中的方法
+type mockMethod struct {
+	Name     string
+	Params   []mockParam
+	Results  []string
+	Variadic bool
+}
+
+// mockInterface 待生成的接口
+type mockInterface struct {
+	Name    string
+	Type    string // 限定后的接口类型
+	Methods []mockMethod
+}
+
+// mockImports 生成文件的导入管理，处理包名冲突
+type mockImports struct {
+	outPkgPath string
+	names      map[string]string // 导入路径 -> 包名
+	used       map[string]bool
+}
+
+func newMockImports(outPkgPath string) *mockImports {
+	return &mockImports{
+		outPkgPath: outPkgPath,
+		names:      make(map[string]string),
+		used:       make(map[string]bool),
+	}
+}
+
+// add 注册导入路径并返回生成代码中使用的包名
+func (m *mockImports) add(path, name string) string {
+	if path == m.outPkgPath {
+		return ""
+	}
+	if existing, ok := m.names[path]; ok {
+		return existing
+	}
+	alias := name
+	for i := 1; m.used[alias]; i++ {
+		alias = fmt.Sprintf("%s%d", name, i)
+	}
+	m.names[path] = alias
+	m.used[alias] = true
+	return alias
+}
+
+func (m *mockImports) qualifier(pkg *types.Package) string {
+	return m.add(pkg.Path(), pkg.Name())
+}
+
+func (m *mockImports) write(sb *strings.Builder) {
+	if len(m.names) == 0 {
+		return
+	}
+	sb.WriteString("import (\n")
+	paths := sortedMapKeys(m.names)
+	// 标准库在前，其余包在后
+	sort.SliceStable(paths, func(i, j int) bool {
+		return isStdlibPkg(paths[i]) && !isStdlibPkg(paths[j])
+	})
+	for i, path := range paths {
+		if i > 0 && isStdlibPkg(paths[i-1]) && !isStdlibPkg(path) {
+			sb.WriteString("\n")
+		}
+		alias := m.names[path]
+		if alias == path[strings.LastIndex(path, "/")+1:] {
+			fmt.Fprintf(sb, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(sb, "\t%s %q\n", alias, path)
+		}
+	}
+	sb.WriteString(")\n\n")
+}
+
+// GenerateMocks 为包中的接口生成gofmt格式化后的mock或fake代码
+func GenerateMocks(ctx context.Context, cfg *MockConfig) ([]byte, error) {
+	style := cfg.Style
+	if style == "" {
+		style = MockStyleGomock
+	}
+	if style != MockStyleGomock && style != MockStyleFake {
+		return nil, fmt.Errorf("不支持的mock风格: %s", style)
+	}
+	pkgs, err := LoadPackages(ctx, &LoadConfig{RepoPath: cfg.Dir, PkgPath: cfg.Package, LoadEnum: LoadSpecificPkg})
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 || pkgs[0].Types == nil {
+		return nil, fmt.Errorf("加载包 %s 失败", cfg.Package)
+	}
+	pkg := pkgs[0]
+	if len(pkg.Errors) > 0 {
+		return nil, fmt.Errorf("包 %s 存在错误: %w", pkg.PkgPath, pkg.Errors[0])
+	}
+	outPackage, outPkgPath := cfg.OutPackage, cfg.OutPkgPath
+	if outPackage == "" {
+		outPackage = pkg.Name
+	}
+	if outPkgPath == "" && outPackage == pkg.Name {
+		outPkgPath = pkg.PkgPath
+	}
+	imports := newMockImports(outPkgPath)
+	// 预留生成代码自身依赖的包名
+	if style == MockStyleGomock {
+		gomockImport := cfg.GomockImport
+		if gomockImport == "" {
+			gomockImport = defaultGomockImport
+		}
+		imports.add(gomockImport, "gomock")
+		imports.add("reflect", "reflect")
+	} else {
+		imports.add("sync", "sync")
+	}
+	ifaces, err := collectMockInterfaces(pkg, cfg.Interfaces, outPkgPath, imports)
+	if err != nil {
+		return nil, err
+	}
+	var body strings.Builder
+	for _, iface := range ifaces {
+		if style == MockStyleGomock {
+			writeGomock(&body, iface)
+		} else {
+			writeFake(&body, iface)
+		}
+	}
+	var sb strings.Builder
+	source := cfg.Source
+	if source == "" {
+		source = "static_parser mockgen"
+	}
+	fmt.Fprintf(&sb, "// Code generated by %s. DO NOT EDIT.\n", source)
+	names := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+	fmt.Fprintf(&sb, "// Source: %s (interfaces: %s)\n\n", pkg.PkgPath, strings.Join(names, ", "))
+	fmt.Fprintf(&sb, "package %s\n\n", outPackage)
+	imports.write(&sb)
+	sb.WriteString(body.String())
+	formatted, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return nil, fmt.Errorf("格式化生成代码失败: %w", err)
+	}
+	return formatted, nil
+}
+
+// collectMockInterfaces 从包中找出待生成的接口及其完整方法集
+func collectMockInterfaces(pkg *packages.Package, names []string, outPkgPath string, imports *mockImports) ([]*mockInterface, error) {
+	scope := pkg.Types.Scope()
+	if len(names) == 0 {
+		for _, name := range scope.Names() {
+			if typeName, ok := scope.Lookup(name).(*types.TypeName); ok && typeName.Exported() && !typeName.IsAlias() {
+				if iface, ok := typeName.Type().Underlying().(*types.Interface); ok && iface.IsMethodSet() {
+					names = append(names, name)
+				}
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("包 %s 中没有可生成的接口", pkg.PkgPath)
+		}
+	}
+	ifaces := make([]*mockInterface, 0, len(names))
+	for _, name := range names {
+		typeName, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("包 %s 中不存在类型 %s", pkg.PkgPath, name)
+		}
+		iface, ok := typeName.Type().Underlying().(*types.Interface)
+		if !ok {
+			return nil, fmt.Errorf("%s 不是接口类型", name)
+		}
+		if !iface.IsMethodSet() {
+			return nil, fmt.Errorf("接口 %s 是类型约束，无法生成mock", name)
+		}
+		if named, ok := typeName.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			return nil, fmt.Errorf("暂不支持为泛型接口 %s 生成mock", name)
+		}
+		mockIface := &mockInterface{
+			Name: name,
+			Type: types.TypeString(typeName.Type(), imports.qualifier),
+		}
+		for i := 0; i < iface.NumMethods(); i++ {
+			method := iface.Method(i)
+			if !method.Exported() && method.Pkg().Path() != outPkgPath {
+				return nil, fmt.Errorf("接口 %s 的方法 %s 未导出，只能在包 %s 中实现", name, method.Name(), method.Pkg().Path())
+			}
+			mockIface.Methods = append(mockIface.Methods, buildMockMethod(method, imports))
+		}
+		sort.Slice(mockIface.Methods, func(i, j int) bool {
+			return mockIface.Methods[i].Name < mockIface.Methods[j].Name
+		})
+		ifaces = append(ifaces, mockIface)
+	}
+	return ifaces, nil
+}
+
+func buildMockMethod(method *types.Func, imports *mockImports) mockMethod {
+	sig := method.Type().(*types.Signature)
+	mm := mockMethod{Name: method.Name(), Variadic: sig.Variadic()}
+	for i := 0; i < sig.Params().Len(); i++ {
+		paramType := sig.Params().At(i).Type()
+		dataType := types.TypeString(paramType, imports.qualifier)
+		declType := dataType
+		if mm.Variadic && i == sig.Params().Len()-1 {
+			declType = "..." + types.TypeString(paramType.(*types.Slice).Elem(), imports.qualifier)
+		}
+		mm.Params = append(mm.Params, mockParam{Name: fmt.Sprintf("arg%d", i), Type: declType, DataType: dataType})
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		mm.Results = append(mm.Results, types.TypeString(sig.Results().At(i).Type(), imports.qualifier))
+	}
+	return mm
+}
+
+func (m mockMethod) paramDecl() string {
+	parts := make([]string, 0, len(m.Params))
+	for _, param := range m.Params {
+		parts = append(parts, param.Name+" "+param.Type)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (m mockMethod) resultDecl() string {
+	switch len(m.Results) {
+	case 0:
+		return ""
+	case 1:
+		return m.Results[0]
+	default:
+		return "(" + strings.Join(m.Results, ", ") + ")"
+	}
+}
+
+// callArgs 返回转发调用时的实参列表
+func (m mockMethod) callArgs() string {
+	parts := make([]string, 0, len(m.Params))
+	for i, param := range m.Params {
+		if m.Variadic && i == len(m.Params)-1 {
+			parts = append(parts, param.Name+"...")
+		} else {
+			parts = append(parts, param.Name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func resultNames(count int) string {
+	names := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		names = append(names, fmt.Sprintf("ret%d", i))
+	}
+	return strings.Join(names, ", ")
+}
+
+func writeGomock(sb *strings.Builder, iface *mockInterface) {
+	mockName := "Mock" + iface.Name
+	recorderName := mockName + "MockRecorder"
+	fmt.Fprintf(sb, "// %s 是%s接口的mock\ntype %s struct {\n\tctrl *gomock.Controller\n\trecorder *%s\n}\n\n", mockName, iface.Name, mockName, recorderName)
+	fmt.Fprintf(sb, "// %s 是%s的调用预期记录器\ntype %s struct {\n\tmock *%s\n}\n\n", recorderName, mockName, recorderName, mockName)
+	fmt.Fprintf(sb, "// New%s 创建一个新的mock实例\nfunc New%s(ctrl *gomock.Controller) *%s {\n\tmock := &%s{ctrl: ctrl}\n\tmock.recorder = &%s{mock}\n\treturn mock\n}\n\n",
+		mockName, mockName, mockName, mockName, recorderName)
+	fmt.Fprintf(sb, "// EXPECT 返回用于声明调用预期的记录器\nfunc (m *%s) EXPECT() *%s {\n\treturn m.recorder\n}\n\n", mockName, recorderName)
+	for _, method := range iface.Methods {
+		fmt.Fprintf(sb, "// %s mock基础方法\nfunc (m *%s) %s(%s) %s {\n\tm.ctrl.T.Helper()\n", method.Name, mockName, method.Name, method.paramDecl(), method.resultDecl())
+		callArgs := ""
+		if method.Variadic {
+			fixed := make([]string, 0, len(method.Params)-1)
+			for _, param := range method.Params[:len(method.Params)-1] {
+				fixed = append(fixed, param.Name)
+			}
+			last := method.Params[len(method.Params)-1].Name
+			fmt.Fprintf(sb, "\tvarargs := []any{%s}\n\tfor _, a := range %s {\n\t\tvarargs = append(varargs, a)\n\t}\n", strings.Join(fixed, ", "), last)
+			callArgs = ", varargs..."
+		} else if len(method.Params) > 0 {
+			callArgs = ", " + method.callArgs()
+		}
+		if len(method.Results) == 0 {
+			fmt.Fprintf(sb, "\tm.ctrl.Call(m, %q%s)\n}\n\n", method.Name, callArgs)
+		} else {
+			fmt.Fprintf(sb, "\tret := m.ctrl.Call(m, %q%s)\n", method.Name, callArgs)
+			for i, result := range method.Results {
+				fmt.Fprintf(sb, "\tret%d, _ := ret[%d].(%s)\n", i, i, result)
+			}
+			fmt.Fprintf(sb, "\treturn %s\n}\n\n", resultNames(len(method.Results)))
+		}
+		// 记录器的参数统一为any，以便传入gomock.Matcher
+		recorderParams := make([]string, 0, len(method.Params))
+		for i, param := range method.Params {
+			if method.Variadic && i == len(method.Params)-1 {
+				recorderParams = append(recorderParams, param.Name+" ...any")
+			} else {
+				recorderParams = append(recorderParams, param.Name+" any")
+			}
+		}
+		fmt.Fprintf(sb, "// %s 声明对%s的调用预期\nfunc (mr *%s) %s(%s) *gomock.Call {\n\tmr.mock.ctrl.T.Helper()\n",
+			method.Name, method.Name, recorderName, method.Name, strings.Join(recorderParams, ", "))
+		methodType := fmt.Sprintf("reflect.TypeOf((*%s)(nil).%s)", mockName, method.Name)
+		if method.Variadic {
+			fixed := make([]string, 0, len(method.Params)-1)
+			for _, param := range method.Params[:len(method.Params)-1] {
+				fixed = append(fixed, param.Name)
+			}
+			last := method.Params[len(method.Params)-1].Name
+			fmt.Fprintf(sb, "\tvarargs := append([]any{%s}, %s...)\n", strings.Join(fixed, ", "), last)
+			fmt.Fprintf(sb, "\treturn mr.mock.ctrl.RecordCallWithMethodType(mr.mock, %q, %s, varargs...)\n}\n\n", method.Name, methodType)
+		} else {
+			fmt.Fprintf(sb, "\treturn mr.mock.ctrl.RecordCallWithMethodType(mr.mock, %q, %s%s)\n}\n\n", method.Name, methodType, callArgs)
+		}
+	}
+	fmt.Fprintf(sb, "var _ %s = (*%s)(nil)\n\n", iface.Type, mockName)
+}
+
+func writeFake(sb *strings.Builder, iface *mockInterface) {
+	fakeName := "Fake" + iface.Name
+	fmt.Fprintf(sb, "// %s 是%s接口的fake实现，记录每次调用的参数，可通过Stub字段配置返回\ntype %s struct {\n\tmu sync.Mutex\n", fakeName, iface.Name, fakeName)
+	for _, method := range iface.Methods {
+		fmt.Fprintf(sb, "\t%sStub func(%s) %s\n", method.Name, method.paramDecl(), method.resultDecl())
+		fields := make([]string, 0, len(method.Params))
+		for _, param := range method.Params {
+			fields = append(fields, param.Name+" "+param.DataType)
+		}
+		fmt.Fprintf(sb, "\t%s []%s\n", fakeCallsField(method.Name), fakeArgsStruct(fields))
+	}
+	sb.WriteString("}\n\n")
+	for _, method := range iface.Methods {
+		callsField := fakeCallsField(method.Name)
+		names := make([]string, 0, len(method.Params))
+		argTypes := make([]string, 0, len(method.Params))
+		fields := make([]string, 0, len(method.Params))
+		for _, param := range method.Params {
+			names = append(names, param.Name)
+			argTypes = append(argTypes, param.DataType)
+			fields = append(fields, param.Name+" "+param.DataType)
+		}
+		fmt.Fprintf(sb, "// %s 记录调用参数，设置了%sStub时返回其结果，否则返回零值\nfunc (fake *%s) %s(%s) %s {\n", method.Name, method.Name, fakeName, method.Name, method.paramDecl(), method.resultDecl())
+		fmt.Fprintf(sb, "\tfake.mu.Lock()\n\tfake.%s = append(fake.%s, %s{%s})\n\tstub := fake.%sStub\n\tfake.mu.Unlock()\n",
+			callsField, callsField, fakeArgsStruct(fields), strings.Join(names, ", "), method.Name)
+		if len(method.Results) == 0 {
+			fmt.Fprintf(sb, "\tif stub != nil {\n\t\tstub(%s)\n\t}\n}\n\n", method.callArgs())
+		} else {
+			fmt.Fprintf(sb, "\tif stub != nil {\n\t\treturn stub(%s)\n\t}\n", method.callArgs())
+			for i, result := range method.Results {
+				fmt.Fprintf(sb, "\tvar ret%d %s\n", i, result)
+			}
+			fmt.Fprintf(sb, "\treturn %s\n}\n\n", resultNames(len(method.Results)))
+		}
+		fmt.Fprintf(sb, "// %sCallCount 返回%s被调用的次数\nfunc (fake *%s) %sCallCount() int {\n\tfake.mu.Lock()\n\tdefer fake.mu.Unlock()\n\treturn len(fake.%s)\n}\n\n",
+			method.Name, method.Name, fakeName, method.Name, callsField)
+		if len(method.Params) > 0 {
+			argsReturn := strings.Join(argTypes, ", ")
+			if len(argTypes) > 1 {
+				argsReturn = "(" + argsReturn + ")"
+			}
+			values := make([]string, 0, len(names))
+			for _, name := range names {
+				values = append(values, "args."+name)
+			}
+			fmt.Fprintf(sb, "// %sArgsForCall 返回第i次调用%s时的参数\nfunc (fake *%s) %sArgsForCall(i int) %s {\n\tfake.mu.Lock()\n\tdefer fake.mu.Unlock()\n\targs := fake.%s[i]\n\treturn %s\n}\n\n",
+				method.Name, method.Name, fakeName, method.Name, argsReturn, callsField, strings.Join(values, ", "))
+		}
+		if len(method.Results) > 0 {
+			results := make([]string, 0, len(method.Results))
+			for i, result := range method.Results {
+				results = append(results, fmt.Sprintf("ret%d %s", i, result))
+			}
+			fmt.Fprintf(sb, "// %sReturns 配置%s固定返回给定的值\nfunc (fake *%s) %sReturns(%s) {\n\tfake.mu.Lock()\n\tdefer fake.mu.Unlock()\n\tfake.%sStub = func(%s) %s {\n\t\treturn %s\n\t}\n}\n\n",
+				method.Name, method.Name, fakeName, method.Name, strings.Join(results, ", "), method.Name, method.paramDecl(), method.resultDecl(), resultNames(len(method.Results)))
+		}
+	}
+	fmt.Fprintf(sb, "var _ %s = (*%s)(nil)\n\n", iface.Type, fakeName)
+}
+
+// fakeCallsField 返回fake中记录调用参数的字段名，后缀与生成的方法名不同，避免未导出方法的字段与XxxArgsForCall方法重名
+func fakeCallsField(methodName string) string {
+	return strings.ToLower(methodName[:1]) + methodName[1:] + "ArgsForCallRecords"
+}
+
+// fakeArgsStruct 返回保存一次调用参数的匿名结构体类型
+func fakeArgsStruct(fields []string) string {
+	if len(fields) == 0 {
+		return "struct{}"
+	}
+	return "struct{\n" + strings.Join(fields, "\n") + "\n}"
+}
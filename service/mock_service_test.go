@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testMockStore = `package store
+
+import (
+	"context"
+	"io"
+)
+
+type Closer interface {
+	io.Closer
+}
+
+// Store 存储接口
+type Store interface {
+	Closer
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(key string, values ...[]byte) error
+	Watch(keys []string) <-chan map[string]int
+	Reset()
+}
+
+type cache interface {
+	get(key string) []byte
+}
+
+type Number interface {
+	~int | ~int64
+}
+`
+
+// testGomockStub 模拟gomock的API，用于在无网络环境下类型检查生成代码
+const testGomockStub = `package gomock
+
+import "reflect"
+
+type TestHelper interface{ Helper() }
+
+type Controller struct{ T TestHelper }
+
+type Call struct{}
+
+func (c *Controller) Call(receiver any, method string, args ...any) []any { return nil }
+
+func (c *Controller) RecordCallWithMethodType(receiver any, method string, methodType reflect.Type, args ...any) *Call {
+	return nil
+}
+`
+
+func TestGenerateMocks(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod":           "module example.com/app\n\ngo 1.21\n",
+		"store/store.go":   testMockStore,
+		"gomock/gomock.go": testGomockStub,
+		"mocks/doc.go":     "package mocks\n",
+	})
+	visitor, err := ParseSingleFile("example.com/app/store", "store/store.go", filepath.Join(dir, "store", "store.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := visitor.FileStructs[1]
+	if !store.IsInterface || len(store.Methods) != 4 || len(store.Embeds) != 1 || store.Doc != "Store 存储接口\n" {
+		t.Fatalf("unexpected interface info: %+v", store)
+	}
+	if put := store.Methods[1]; put.Name != "Put" || put.Params[1].Type != "...[]byte" {
+		t.Errorf("unexpected method signature: %+v", put.Params[1])
+	}
+	if watch := store.Methods[2]; watch.Results[0].Type != "<-chan map[string]int" {
+		t.Errorf("unexpected method result: %+v", watch.Results[0])
+	}
+	ctx := context.Background()
+	fake, err := GenerateMocks(ctx, &MockConfig{Dir: dir, Package: "./store", Interfaces: []string{"Store"}, Style: MockStyleFake})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package store", "type FakeStore struct", "func(arg0 string, arg1 ...[]byte) error",
+		"func (fake *FakeStore) GetArgsForCall(i int) (context.Context, string)", "func (fake *FakeStore) CloseReturns(ret0 error)",
+		"var _ Store = (*FakeStore)(nil)"} {
+		if !strings.Contains(string(fake), want) {
+			t.Errorf("fake output missing %q:\n%s", want, fake)
+		}
+	}
+	mock, err := GenerateMocks(ctx, &MockConfig{
+		Dir:          dir,
+		Package:      "./store",
+		OutPackage:   "mocks",
+		OutPkgPath:   "example.com/app/mocks",
+		GomockImport: "example.com/app/gomock",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"func NewMockStore(ctrl *gomock.Controller) *MockStore", "func NewMockCloser(",
+		"func (mr *MockStoreMockRecorder) Put(arg0 any, arg1 ...any) *gomock.Call", "ret0, _ := ret[0].(<-chan map[string]int)"} {
+		if !strings.Contains(string(mock), want) {
+			t.Errorf("gomock output missing %q:\n%s", want, mock)
+		}
+	}
+	if strings.Contains(string(mock), "MockNumber") {
+		t.Error("constraint interface should be skipped")
+	}
+	// 未导出方法记录参数的字段不能与getArgsForCall方法重名
+	fakeCache, err := GenerateMocks(ctx, &MockConfig{Dir: dir, Package: "./store", Interfaces: []string{"cache"}, Style: MockStyleFake})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(fakeCache), "func (fake *Fakecache) getArgsForCall(i int) string") {
+		t.Errorf("fake output missing getArgsForCall:\n%s", fakeCache)
+	}
+	// 生成的代码需要能通过类型检查
+	if err := os.WriteFile(filepath.Join(dir, "store", "fake_store.go"), fake, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "store", "fake_cache.go"), fakeCache, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mocks", "mock_store.go"), mock, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pkgs, err := LoadPackages(ctx, &LoadConfig{RepoPath: dir, LoadEnum: LoadCurrentRepo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			t.Errorf("generated code in %s does not compile: %v", pkg.PkgPath, pkg.Errors)
+		}
+	}
+	if _, err := GenerateMocks(ctx, &MockConfig{Dir: dir, Package: "./store", Interfaces: []string{"Number"}}); err == nil {
+		t.Error("expected error for constraint interface")
+	}
+}
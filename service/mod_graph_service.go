@@ -0,0 +1,196 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// ModuleNode 表示模块依赖图中的一个模块
+type ModuleNode struct {
+	Path  string `json:"path"`          // 模块路径
+	Dir   string `json:"dir,omitempty"` // 仓库内模块所在目录
+	Local bool   `json:"local"`         // 是否为仓库内的模块
+}
+
+// ModuleEdge 表示一个模块对另一个模块的依赖
+type ModuleEdge struct {
+	From            string `json:"from"`                       // 依赖方模块路径
+	To              string `json:"to"`                         // 生效的被依赖模块路径，替换后为新路径
+	RequiredPath    string `json:"required_path"`              // go.mod中require的模块路径
+	RequiredVersion string `json:"required_version"`           // go.mod中require的版本
+	Version         string `json:"version"`                    // 替换后生效的版本，本地替换为空
+	Indirect        bool   `json:"indirect"`                   // 是否为间接依赖
+	Replaced        bool   `json:"replaced"`                   // 是否被replace
+	LocalDir        string `json:"local_dir,omitempty"`        // 本地路径替换的目标目录
+	LocalReplace    bool   `json:"local_replace"`              // 是否替换为本地目录
+	UnresolvedLocal bool   `json:"unresolved_local,omitempty"` // 本地替换目录不是仓库内的模块
+}
+
+// ModuleGraph 仓库级别的模块依赖图
+type ModuleGraph struct {
+	Nodes map[string]*ModuleNode `json:"nodes"`
+	Edges []*ModuleEdge          `json:"edges"`
+}
+
+// VersionSkew 同一个依赖在仓库内不同模块中被依赖的版本不一致
+type VersionSkew struct {
+	Path     string              `json:"path"`     // 依赖模块路径
+	Versions map[string][]string `json:"versions"` // 版本 -> 依赖该版本的模块
+	Latest   string              `json:"latest"`   // 仓库内使用的最高版本
+}
+
+// BuildModuleGraph 根据各模块go.mod中的require和replace构建模块依赖图
+func BuildModuleGraph(modules []*ModuleInfo) *ModuleGraph {
+	graph := &ModuleGraph{Nodes: make(map[string]*ModuleNode)}
+	dirModules := make(map[string]*ModuleInfo)
+	for _, modInfo := range modules {
+		if modInfo.Path == "" {
+			continue
+		}
+		graph.Nodes[modInfo.Path] = &ModuleNode{Path: modInfo.Path, Dir: modInfo.Dir, Local: true}
+		dirModules[filepath.Clean(modInfo.Dir)] = modInfo
+	}
+	for _, modInfo := range modules {
+		if modInfo.Path == "" {
+			continue
+		}
+		for _, req := range modInfo.Requires {
+			edge := &ModuleEdge{
+				From:            modInfo.Path,
+				To:              req.Path,
+				RequiredPath:    req.Path,
+				RequiredVersion: req.Version,
+				Version:         req.Version,
+				Indirect:        req.Indirect,
+			}
+			if replace := findReplaceRule(modInfo.Replaces, req); replace != nil {
+				edge.Replaced = true
+				edge.To = replace.NewPath
+				edge.Version = replace.NewVersion
+				if modfile.IsDirectoryPath(replace.NewPath) {
+					edge.LocalReplace = true
+					edge.LocalDir = replace.NewPath
+					if !filepath.IsAbs(edge.LocalDir) {
+						edge.LocalDir = filepath.Join(modInfo.Dir, replace.NewPath)
+					}
+					edge.LocalDir = filepath.Clean(edge.LocalDir)
+					if sibling, ok := dirModules[edge.LocalDir]; ok {
+						edge.To = sibling.Path
+					} else {
+						edge.UnresolvedLocal = true
+						edge.To = req.Path
+					}
+				}
+			}
+			if _, ok := graph.Nodes[edge.To]; !ok {
+				graph.Nodes[edge.To] = &ModuleNode{Path: edge.To}
+			}
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+	return graph
+}
+
+// findReplaceRule 查找作用于某个依赖的replace，带版本的规则优先
+func findReplaceRule(replaces []ReplaceRule, req Dependency) *ReplaceRule {
+	var matched *ReplaceRule
+	for i := range replaces {
+		replace := &replaces[i]
+		if replace.OldPath != req.Path {
+			continue
+		}
+		if replace.OldVersion == req.Version {
+			return replace
+		}
+		if replace.OldVersion == "" {
+			matched = replace
+		}
+	}
+	return matched
+}
+
+// FindVersionSkew 查找仓库内同一依赖被不同模块依赖为不同版本的情况，本地替换的依赖不参与比较
+func (g *ModuleGraph) FindVersionSkew() []*VersionSkew {
+	versionMap := make(map[string]map[string][]string)
+	for _, edge := range g.Edges {
+		if edge.LocalReplace || (g.Nodes[edge.To] != nil && g.Nodes[edge.To].Local) {
+			continue
+		}
+		if versionMap[edge.To] == nil {
+			versionMap[edge.To] = make(map[string][]string)
+		}
+		versionMap[edge.To][edge.Version] = append(versionMap[edge.To][edge.Version], edge.From)
+	}
+	skews := make([]*VersionSkew, 0)
+	for path, versions := range versionMap {
+		if len(versions) < 2 {
+			continue
+		}
+		skew := &VersionSkew{Path: path, Versions: versions}
+		for version := range versions {
+			if skew.Latest == "" || semver.Compare(version, skew.Latest) > 0 {
+				skew.Latest = version
+			}
+		}
+		skews = append(skews, skew)
+	}
+	sort.Slice(skews, func(i, j int) bool {
+		return skews[i].Path < skews[j].Path
+	})
+	return skews
+}
+
+// WriteJSON 以JSON格式输出模块依赖图
+func (g *ModuleGraph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g)
+}
+
+// WriteDOT 以Graphviz DOT格式输出模块依赖图，仓库内模块为方框，间接依赖为虚线
+func (g *ModuleGraph) WriteDOT(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("digraph modules {\n")
+	sb.WriteString("\trankdir=LR;\n")
+	paths := make([]string, 0, len(g.Nodes))
+	for path := range g.Nodes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		shape := "ellipse"
+		if g.Nodes[path].Local {
+			shape = "box"
+		}
+		sb.WriteString(fmt.Sprintf("\t%q [shape=%s];\n", path, shape))
+	}
+	for _, edge := range g.Edges {
+		label := edge.Version
+		if edge.LocalReplace {
+			label = "=> " + edge.LocalDir
+		} else if edge.Replaced {
+			label = "=> " + edge.To + " " + edge.Version
+		}
+		style := "solid"
+		if edge.Indirect {
+			style = "dashed"
+		}
+		sb.WriteString(fmt.Sprintf("\t%q -> %q [label=%q, style=%s];\n", edge.From, edge.To, label, style))
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBuildModuleGraph(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"api/go.mod": `module example.com/api
+
+go 1.21
+
+require (
+	example.com/common v0.0.0
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.3.0 // indirect
+)
+
+replace example.com/common => ../common
+`,
+		"worker/go.mod": `module example.com/worker
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.8.0
+	golang.org/x/text v0.3.0 // indirect
+)
+`,
+		"common/go.mod": "module example.com/common\n\ngo 1.21\n",
+	})
+	modules, err := ParseRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := BuildModuleGraph(modules)
+	var local, indirect *ModuleEdge
+	for _, edge := range graph.Edges {
+		if edge.From == "example.com/api" && edge.RequiredPath == "example.com/common" {
+			local = edge
+		}
+		if edge.From == "example.com/api" && edge.RequiredPath == "golang.org/x/text" {
+			indirect = edge
+		}
+	}
+	if local == nil || !local.LocalReplace || local.To != "example.com/common" || local.UnresolvedLocal {
+		t.Fatalf("local replace not resolved: %+v", local)
+	}
+	if indirect == nil || !indirect.Indirect {
+		t.Fatalf("indirect edge not marked: %+v", indirect)
+	}
+	skews := graph.FindVersionSkew()
+	if len(skews) != 1 || skews[0].Path != "github.com/pkg/errors" || skews[0].Latest != "v0.9.1" {
+		t.Fatalf("unexpected skews: %+v", skews)
+	}
+	if got := skews[0].Versions["v0.8.0"]; len(got) != 1 || got[0] != "example.com/worker" {
+		t.Errorf("unexpected skew users: %v", got)
+	}
+	var dot bytes.Buffer
+	if err := graph.WriteDOT(&dot); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dot.String(), `"example.com/api" -> "golang.org/x/text" [label="v0.3.0", style=dashed];`) {
+		t.Errorf("unexpected dot output:\n%s", dot.String())
+	}
+	var out bytes.Buffer
+	if err := graph.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded ModuleGraph
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Edges) != len(graph.Edges) {
+		t.Errorf("json round trip lost edges")
+	}
+}
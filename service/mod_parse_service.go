@@ -18,16 +18,36 @@ import (
 
 // ModuleInfo 表示一个Go模块的信息
 type ModuleInfo struct {
-	Path         string        // 模块路径
-	Dir          string        // 模块所在目录
-	GoVersion    string        // Go版本
-	Requires     []Dependency  // 直接依赖
-	Replaces     []ReplaceRule // 替换规则
-	Imports      []string      // 导入的包（从.go文件中提取）
-	Error        error         // 解析过程中发生的错误
-	PkgFuncMap   map[string][]*vs.FuncInfo
-	PkgVarMap    map[string][]*vs.VarInfo
-	PkgStructMap map[string][]*vs.StructInfo
+	Path          string           // 模块路径
+	Version       string           // 模块版本，仅依赖模块有值
+	Dir           string           // 模块所在目录
+	ThirdParty    bool             // 是否为第三方依赖模块
+	GoVersion     string           // Go版本
+	Requires      []Dependency     // 直接依赖
+	Replaces      []ReplaceRule    // 替换规则
+	Imports       []string         // 导入的包（从.go文件中提取）
+	TestImports   []string         // 测试文件导入的包
+	Error         error            // 解析过程中发生的错误
+	ModuleLine    int              // module指令所在行
+	Deprecated    string           // module指令上的弃用说明
+	GoVersionLine int              // go指令所在行
+	Toolchain     string           // toolchain指令指定的工具链
+	ToolchainLine int              // toolchain指令所在行
+	Excludes      []ExcludeRule    // 排除的版本
+	Retracts      []RetractRule    // 撤回的版本
+	Godebugs      []GodebugSetting // godebug设置
+	Tools         []ToolDirective  // tool指令声明的工具包
+	Ignores       []IgnoreRule     // ignore指令忽略的目录
+	Sums          []SumEntry       // go.sum中的校验和
+	Vendor        *VendorInfo      // vendor/modules.txt的内容，没有vendor目录时为空
+	VendorModules []*ModuleInfo    // vendor中的依赖模块，调用AppendVendorModules后才有值
+	PkgFuncMap    map[string][]*vs.FuncInfo
+	PkgVarMap     map[string][]*vs.VarInfo
+	PkgStructMap  map[string][]*vs.StructInfo
+	// 测试文件中的声明，外部测试包以"包路径_test"为键，调用AppendModuleInfo后才有值
+	PkgTestFuncMap   map[string][]*vs.FuncInfo
+	PkgTestVarMap    map[string][]*vs.VarInfo
+	PkgTestStructMap map[string][]*vs.StructInfo
 }
 
 // Dependency 表示模块的依赖
@@ -35,6 +55,7 @@ type Dependency struct {
 	Path     string // 依赖路径
 	Version  string // 依赖版本
 	Indirect bool   // 是否为间接依赖
+	Line     int    // 在go.mod中的行号
 }
 
 // ReplaceRule 表示模块的替换规则
@@ -43,6 +64,41 @@ type ReplaceRule struct {
 	OldVersion string // 原版本
 	NewPath    string // 新路径
 	NewVersion string // 新版本
+	Line       int    // 在go.mod中的行号
+}
+
+// ExcludeRule 表示模块的exclude指令
+type ExcludeRule struct {
+	Path    string // 模块路径
+	Version string // 排除的版本
+	Line    int    // 在go.mod中的行号
+}
+
+// RetractRule 表示模块的retract指令，单个版本时Low与High相同
+type RetractRule struct {
+	Low       string // 撤回区间下界
+	High      string // 撤回区间上界
+	Rationale string // 撤回原因
+	Line      int    // 在go.mod中的行号
+}
+
+// GodebugSetting 表示模块的godebug指令
+type GodebugSetting struct {
+	Key   string // 设置项
+	Value string // 设置值
+	Line  int    // 在go.mod中的行号
+}
+
+// ToolDirective 表示模块的tool指令
+type ToolDirective struct {
+	Path string // 工具包路径
+	Line int    // 在go.mod中的行号
+}
+
+// IgnoreRule 表示模块的ignore指令
+type IgnoreRule struct {
+	Path string // 忽略的目录
+	Line int    // 在go.mod中的行号
 }
 
 // ParseRepo 匹配仓库信息
@@ -58,7 +114,7 @@ func ParseRepo(repoPath string) ([]*ModuleInfo, error) {
 func ParseModule(dir string) (*ModuleInfo, error) {
 	start := time.Now()
 	defer func() {
-		fmt.Printf("ParseModule dir:%s cost: %v\n", dir, time.Since(start))
+		log.Printf("ParseModule dir:%s cost: %v", dir, time.Since(start))
 	}()
 	info := &ModuleInfo{
 		Dir:          dir,
@@ -70,16 +126,23 @@ func ParseModule(dir string) (*ModuleInfo, error) {
 	modPath := filepath.Join(dir, "go.mod")
 	data, err := os.ReadFile(modPath)
 	if err != nil {
-		return info, fmt.Errorf("读取go.mod失败: %v", err)
+		return info, fmt.Errorf("读取go.mod失败: %w", err)
 	}
 	// 解析go.mod文件
 	modeFile, err := modfile.Parse(modPath, data, nil)
 	if err != nil {
-		return info, fmt.Errorf("解析go.mod失败: %v", err)
+		return info, fmt.Errorf("解析go.mod失败: %w", err)
 	}
 	info.Path = modeFile.Module.Mod.Path
+	info.ModuleLine = syntaxLine(modeFile.Module.Syntax)
+	info.Deprecated = modeFile.Module.Deprecated
 	if modeFile.Go != nil {
 		info.GoVersion = modeFile.Go.Version
+		info.GoVersionLine = syntaxLine(modeFile.Go.Syntax)
+	}
+	if modeFile.Toolchain != nil {
+		info.Toolchain = modeFile.Toolchain.Name
+		info.ToolchainLine = syntaxLine(modeFile.Toolchain.Syntax)
 	}
 	// 匹配mod文件中内容
 	AppendModuleInfo(info)
@@ -89,6 +152,7 @@ func ParseModule(dir string) (*ModuleInfo, error) {
 			Path:     req.Mod.Path,
 			Version:  req.Mod.Version,
 			Indirect: req.Indirect,
+			Line:     syntaxLine(req.Syntax),
 		})
 	}
 	// 解析替换规则
@@ -98,24 +162,98 @@ func ParseModule(dir string) (*ModuleInfo, error) {
 			OldVersion: replace.Old.Version,
 			NewPath:    replace.New.Path,
 			NewVersion: replace.New.Version,
+			Line:       syntaxLine(replace.Syntax),
+		})
+	}
+	// 解析其余指令
+	for _, exclude := range modeFile.Exclude {
+		info.Excludes = append(info.Excludes, ExcludeRule{
+			Path:    exclude.Mod.Path,
+			Version: exclude.Mod.Version,
+			Line:    syntaxLine(exclude.Syntax),
+		})
+	}
+	for _, retract := range modeFile.Retract {
+		info.Retracts = append(info.Retracts, RetractRule{
+			Low:       retract.Low,
+			High:      retract.High,
+			Rationale: retract.Rationale,
+			Line:      syntaxLine(retract.Syntax),
+		})
+	}
+	for _, godebug := range modeFile.Godebug {
+		info.Godebugs = append(info.Godebugs, GodebugSetting{
+			Key:   godebug.Key,
+			Value: godebug.Value,
+			Line:  syntaxLine(godebug.Syntax),
+		})
+	}
+	for _, tool := range modeFile.Tool {
+		info.Tools = append(info.Tools, ToolDirective{
+			Path: tool.Path,
+			Line: syntaxLine(tool.Syntax),
+		})
+	}
+	for _, ignore := range modeFile.Ignore {
+		info.Ignores = append(info.Ignores, IgnoreRule{
+			Path: ignore.Path,
+			Line: syntaxLine(ignore.Syntax),
 		})
 	}
+	// 解析go.sum
+	sums, err := ParseGoSum(filepath.Join(dir, "go.sum"))
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("警告: 解析目录 %s 中的go.sum失败: %v", dir, err)
+	}
+	info.Sums = sums
+	// 解析vendor/modules.txt
+	vendorInfo, err := ParseVendorModulesTxt(filepath.Join(dir, "vendor", "modules.txt"))
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("警告: 解析目录 %s 中的vendor/modules.txt失败: %v", dir, err)
+	}
+	info.Vendor = vendorInfo
 	// 解析目录中所有.go文件的导入
 	imports, err := ParseImportsFromDir(dir)
 	if err != nil {
 		log.Printf("警告: 解析目录 %s 中的导入失败: %v", dir, err)
 	}
 	info.Imports = imports
+	testImports, err := ParseTestImportsFromDir(dir)
+	if err != nil {
+		log.Printf("警告: 解析目录 %s 中测试文件的导入失败: %v", dir, err)
+	}
+	info.TestImports = testImports
 	return info, nil
 }
 
-// AppendModuleInfo 解析模块中的所有.go文件
+// syntaxLine 返回go.mod语法节点所在的行号
+func syntaxLine(line *modfile.Line) int {
+	if line == nil {
+		return 0
+	}
+	return line.Start.Line
+}
+
+// AppendModuleInfo 解析模块中的所有.go文件，测试文件中的声明单独存放
 func AppendModuleInfo(modInfo *ModuleInfo) {
+	if modInfo.PkgTestFuncMap == nil {
+		modInfo.PkgTestFuncMap = make(map[string][]*vs.FuncInfo)
+		modInfo.PkgTestVarMap = make(map[string][]*vs.VarInfo)
+		modInfo.PkgTestStructMap = make(map[string][]*vs.StructInfo)
+	}
 	filepath.Walk(modInfo.Dir, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if info.IsDir() {
+			// 与go命令一致，跳过testdata和隐藏目录
+			if path != modInfo.Dir && (info.Name() == "testdata" || strings.HasPrefix(info.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			// vendor中的代码属于依赖模块，通过AppendVendorModules单独解析
+			if path == filepath.Join(modInfo.Dir, "vendor") {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		if !strings.HasSuffix(info.Name(), ".go") {
@@ -137,6 +275,25 @@ func AppendModuleInfo(modInfo *ModuleInfo) {
 		if err != nil {
 			return err
 		}
+		if strings.HasSuffix(info.Name(), "_test.go") {
+			testPkg := curPkg
+			if strings.HasSuffix(fileFuncVisitor.File.Name.Name, "_test") {
+				testPkg = curPkg + "_test"
+				for _, funcInfo := range fileFuncVisitor.FileFuncInfos {
+					funcInfo.Pkg = testPkg
+				}
+				for _, varInfo := range fileFuncVisitor.FilePkgVars {
+					varInfo.Pkg = testPkg
+				}
+				for _, structInfo := range fileFuncVisitor.FileStructs {
+					structInfo.Pkg = testPkg
+				}
+			}
+			modInfo.PkgTestFuncMap[testPkg] = append(modInfo.PkgTestFuncMap[testPkg], fileFuncVisitor.FileFuncInfos...)
+			modInfo.PkgTestVarMap[testPkg] = append(modInfo.PkgTestVarMap[testPkg], fileFuncVisitor.FilePkgVars...)
+			modInfo.PkgTestStructMap[testPkg] = append(modInfo.PkgTestStructMap[testPkg], fileFuncVisitor.FileStructs...)
+			return nil
+		}
 		modInfo.PkgFuncMap[curPkg] = append(modInfo.PkgFuncMap[curPkg], fileFuncVisitor.FileFuncInfos...)
 		modInfo.PkgVarMap[curPkg] = append(modInfo.PkgVarMap[curPkg], fileFuncVisitor.FilePkgVars...)
 		modInfo.PkgStructMap[curPkg] = append(modInfo.PkgStructMap[curPkg], fileFuncVisitor.FileStructs...)
@@ -162,20 +319,33 @@ func DeductRelativeDir(parentDir, childPath string) (string, error) {
 
 // 从目录中的所有.go文件解析导入的包
 func ParseImportsFromDir(dir string) ([]string, error) {
+	return parseImportsFromDir(dir, false)
+}
+
+// ParseTestImportsFromDir 解析目录中所有_test.go文件的导入
+func ParseTestImportsFromDir(dir string) ([]string, error) {
+	return parseImportsFromDir(dir, true)
+}
+
+func parseImportsFromDir(dir string, tests bool) ([]string, error) {
 	var imports []string
 	fset := token.NewFileSet()
 	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		// 跳过非.go文件和测试文件
-		if d.IsDir() || !strings.HasSuffix(d.Name(), ".go") || strings.HasSuffix(d.Name(), "_test.go") {
+		// 跳过模块根目录下的vendor
+		if d.IsDir() && path == filepath.Join(dir, "vendor") {
+			return fs.SkipDir
+		}
+		// 只解析.go文件，按需选择测试文件或非测试文件
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".go") || strings.HasSuffix(d.Name(), "_test.go") != tests {
 			return nil
 		}
 		// 解析文件
 		file, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
 		if err != nil {
-			return fmt.Errorf("解析文件 %s 失败: %v", path, err)
+			return fmt.Errorf("解析文件 %s 失败: %w", path, err)
 		}
 		// 提取导入
 		for _, imp := range file.Imports {
@@ -18,3 +18,69 @@ func TestParseRepo(t *testing.T) {
 		t.Log(module)
 	}
 }
+
+func TestParseModuleDirectives(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": `// Deprecated: use example.com/demo/v2 instead.
+module example.com/demo
+
+go 1.24
+
+toolchain go1.24.2
+
+godebug default=go1.21
+
+require github.com/pkg/errors v0.9.1
+
+exclude github.com/pkg/errors v0.9.0
+
+replace github.com/pkg/errors => github.com/pkg/errors v0.9.1
+
+retract (
+	v1.0.1 // 发布错误
+	[v1.1.0, v1.1.3]
+)
+
+tool golang.org/x/tools/cmd/stringer
+
+ignore ./node_modules
+`,
+	})
+	info, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Deprecated != "use example.com/demo/v2 instead." || info.ModuleLine != 2 || info.GoVersionLine != 4 {
+		t.Errorf("unexpected module directive: %q line %d, go line %d", info.Deprecated, info.ModuleLine, info.GoVersionLine)
+	}
+	if info.Toolchain != "go1.24.2" || info.ToolchainLine != 6 {
+		t.Errorf("unexpected toolchain: %s line %d", info.Toolchain, info.ToolchainLine)
+	}
+	if len(info.Godebugs) != 1 || info.Godebugs[0] != (GodebugSetting{Key: "default", Value: "go1.21", Line: 8}) {
+		t.Errorf("unexpected godebug: %+v", info.Godebugs)
+	}
+	if len(info.Requires) != 1 || info.Requires[0].Line != 10 || len(info.Replaces) != 1 || info.Replaces[0].Line != 14 {
+		t.Errorf("unexpected require/replace lines: %+v %+v", info.Requires, info.Replaces)
+	}
+	if len(info.Excludes) != 1 || info.Excludes[0] != (ExcludeRule{Path: "github.com/pkg/errors", Version: "v0.9.0", Line: 12}) {
+		t.Errorf("unexpected exclude: %+v", info.Excludes)
+	}
+	expectedRetracts := []RetractRule{
+		{Low: "v1.0.1", High: "v1.0.1", Rationale: "发布错误", Line: 17},
+		{Low: "v1.1.0", High: "v1.1.3", Line: 18},
+	}
+	if len(info.Retracts) != len(expectedRetracts) {
+		t.Fatalf("unexpected retracts: %+v", info.Retracts)
+	}
+	for i, retract := range expectedRetracts {
+		if info.Retracts[i] != retract {
+			t.Errorf("retract %d: expected %+v, got %+v", i, retract, info.Retracts[i])
+		}
+	}
+	if len(info.Tools) != 1 || info.Tools[0] != (ToolDirective{Path: "golang.org/x/tools/cmd/stringer", Line: 21}) {
+		t.Errorf("unexpected tool: %+v", info.Tools)
+	}
+	if len(info.Ignores) != 1 || info.Ignores[0].Path != "./node_modules" || info.Ignores[0].Line != 23 {
+		t.Errorf("unexpected ignore: %+v", info.Ignores)
+	}
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Silhouette-sophist/static_parser/service/internal"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	// DefaultGoProxy 未配置GOPROXY时使用的代理
+	DefaultGoProxy = "https://proxy.golang.org,direct"
+	// DefaultProxyCacheTTL 版本列表和最新版本等可变响应的缓存时间
+	DefaultProxyCacheTTL = time.Hour
+)
+
+// ProxyConfig 模块代理配置，为空的字段从同名环境变量读取
+type ProxyConfig struct {
+	GoProxy   string        // 代理列表，格式同GOPROXY
+	GoNoProxy string        // 不走代理的模块匹配模式，格式同GONOPROXY
+	GoPrivate string        // 私有模块匹配模式，格式同GOPRIVATE
+	CacheDir  string        // 响应缓存目录，默认在用户缓存目录下
+	CacheTTL  time.Duration // 可变响应的缓存时间
+}
+
+// ModuleVersionInfo 对应代理协议中 .info 和 @latest 的响应
+type ModuleVersionInfo struct {
+	Version string    // 版本号
+	Time    time.Time // 提交时间
+}
+
+// OutdatedDependency 依赖的最新版本检查结果
+type OutdatedDependency struct {
+	Dependency
+	Latest   string // 代理上的最新版本
+	Outdated bool   // 是否落后于最新版本
+	Error    error  // 查询过程中的错误
+}
+
+// ModuleProxyClient 基于GOPROXY协议的模块代理客户端
+type ModuleProxyClient struct {
+	proxies  []proxyEntry
+	noProxy  string
+	cacheDir string
+	cacheTTL time.Duration
+}
+
+type proxyEntry struct {
+	url         string
+	fallbackAny bool         // 以 | 分隔时任意错误都尝试下一个代理，以 , 分隔时仅在404/410时尝试
+	client      *http.Client // file://代理使用以代理目录为根的独立客户端，为空时使用共享客户端
+}
+
+// errProxyNotFound 所有代理都没有找到对应内容
+var errProxyNotFound = errors.New("模块代理中不存在")
+
+// NewModuleProxyClient 创建模块代理客户端
+func NewModuleProxyClient(cfg *ProxyConfig) (*ModuleProxyClient, error) {
+	if cfg == nil {
+		cfg = &ProxyConfig{}
+	}
+	goProxy := firstNonEmpty(cfg.GoProxy, os.Getenv("GOPROXY"), DefaultGoProxy)
+	goPrivate := firstNonEmpty(cfg.GoPrivate, os.Getenv("GOPRIVATE"))
+	client := &ModuleProxyClient{
+		noProxy:  firstNonEmpty(cfg.GoNoProxy, os.Getenv("GONOPROXY"), goPrivate),
+		cacheDir: cfg.CacheDir,
+		cacheTTL: cfg.CacheTTL,
+	}
+	if client.cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("获取用户缓存目录失败: %w", err)
+		}
+		client.cacheDir = filepath.Join(userCacheDir, "static_parser", "proxy")
+	}
+	if client.cacheTTL <= 0 {
+		client.cacheTTL = DefaultProxyCacheTTL
+	}
+	for goProxy != "" {
+		var entry proxyEntry
+		idx := strings.IndexAny(goProxy, ",|")
+		if idx < 0 {
+			entry.url, goProxy = goProxy, ""
+		} else {
+			entry.url, entry.fallbackAny, goProxy = goProxy[:idx], goProxy[idx] == '|', goProxy[idx+1:]
+		}
+		entry.url = strings.TrimSpace(entry.url)
+		if entry.url == "" {
+			continue
+		}
+		if entry.url == "off" {
+			break
+		}
+		if strings.HasPrefix(entry.url, "file://") {
+			proxyURL, err := url.Parse(entry.url)
+			if err != nil {
+				return nil, fmt.Errorf("解析代理地址 %s 失败: %w", entry.url, err)
+			}
+			entry.client = internal.NewFileHttpClient(filepath.FromSlash(proxyURL.Path))
+			entry.url = "file:///"
+		}
+		client.proxies = append(client.proxies, entry)
+	}
+	return client, nil
+}
+
+// List 查询模块的所有已知版本
+func (c *ModuleProxyClient) List(ctx context.Context, modPath string) ([]string, error) {
+	data, err := c.fetch(ctx, modPath, "@v/list", true)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		if version := strings.TrimSpace(line); version != "" {
+			versions = append(versions, strings.Fields(version)[0])
+		}
+	}
+	semver.Sort(versions)
+	return versions, nil
+}
+
+// Info 查询模块某个版本的元信息
+func (c *ModuleProxyClient) Info(ctx context.Context, modPath, version string) (*ModuleVersionInfo, error) {
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.fetch(ctx, modPath, "@v/"+escapedVersion+".info", false)
+	if err != nil {
+		return nil, err
+	}
+	return decodeVersionInfo(data)
+}
+
+// Latest 查询模块的最新版本，代理不支持 @latest 时根据版本列表选择
+func (c *ModuleProxyClient) Latest(ctx context.Context, modPath string) (*ModuleVersionInfo, error) {
+	data, err := c.fetch(ctx, modPath, "@latest", true)
+	if err == nil {
+		return decodeVersionInfo(data)
+	}
+	if !errors.Is(err, errProxyNotFound) {
+		return nil, err
+	}
+	versions, listErr := c.List(ctx, modPath)
+	if listErr != nil {
+		return nil, listErr
+	}
+	latest := latestVersion(versions)
+	if latest == "" {
+		return nil, fmt.Errorf("模块 %s 没有可用版本: %w", modPath, errProxyNotFound)
+	}
+	return c.Info(ctx, modPath, latest)
+}
+
+// GoMod 获取模块某个版本的go.mod内容
+func (c *ModuleProxyClient) GoMod(ctx context.Context, modPath, version string) ([]byte, error) {
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetch(ctx, modPath, "@v/"+escapedVersion+".mod", false)
+}
+
+// DownloadZip 下载模块某个版本的源码压缩包，返回缓存中的文件路径
+func (c *ModuleProxyClient) DownloadZip(ctx context.Context, modPath, version string) (string, error) {
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	rel := "@v/" + escapedVersion + ".zip"
+	if _, err := c.fetch(ctx, modPath, rel, false); err != nil {
+		return "", err
+	}
+	return c.cachePath(modPath, rel)
+}
+
+// CheckOutdated 检查模块的每个依赖是否落后于代理上的最新版本，本地路径替换的依赖不检查
+func (c *ModuleProxyClient) CheckOutdated(ctx context.Context, modInfo *ModuleInfo) []*OutdatedDependency {
+	results := make([]*OutdatedDependency, 0, len(modInfo.Requires))
+	for _, req := range modInfo.Requires {
+		result := &OutdatedDependency{Dependency: req}
+		results = append(results, result)
+		modPath, version := req.Path, req.Version
+		if replace := findReplaceRule(modInfo.Replaces, req); replace != nil {
+			if modfile.IsDirectoryPath(replace.NewPath) {
+				continue
+			}
+			modPath, version = replace.NewPath, replace.NewVersion
+		}
+		latest, err := c.Latest(ctx, modPath)
+		if err != nil {
+			result.Error = err
+			continue
+		}
+		result.Latest = latest.Version
+		result.Outdated = semver.Compare(latest.Version, version) > 0
+	}
+	return results
+}
+
+// fetch 按代理列表顺序请求，mutable为true的内容在缓存过期后重新请求
+func (c *ModuleProxyClient) fetch(ctx context.Context, modPath, rel string, mutable bool) ([]byte, error) {
+	cacheFile, err := c.cachePath(modPath, rel)
+	if err != nil {
+		return nil, err
+	}
+	// 匹配GONOPROXY的模块也不读取代理缓存
+	if module.MatchPrefixPatterns(c.noProxy, modPath) {
+		return nil, fmt.Errorf("模块 %s 匹配GONOPROXY/GOPRIVATE，不通过代理获取", modPath)
+	}
+	if stat, err := os.Stat(cacheFile); err == nil && (!mutable || time.Since(stat.ModTime()) < c.cacheTTL) {
+		return os.ReadFile(cacheFile)
+	}
+	escapedPath, err := module.EscapePath(modPath)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, proxy := range c.proxies {
+		if proxy.url == "direct" {
+			// 保留前面代理返回的不存在错误，调用方据此降级
+			if lastErr == nil {
+				lastErr = fmt.Errorf("模块 %s 需要direct模式获取，暂不支持", modPath)
+			}
+			continue
+		}
+		requestURL := strings.TrimSuffix(proxy.url, "/") + "/" + escapedPath + "/" + rel
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("构造请求 %s 失败: %w", requestURL, err)
+		}
+		var data []byte
+		if proxy.client != nil {
+			data, err = internal.SendHttpRequestRawByClient(ctx, proxy.client, request)
+		} else {
+			data, err = internal.SendHttpRequestRaw(ctx, request)
+		}
+		if err == nil {
+			if err := writeCacheFile(cacheFile, data); err != nil {
+				return nil, err
+			}
+			return data, nil
+		}
+		var statusErr *internal.StatusError
+		if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone) {
+			lastErr = fmt.Errorf("%s %s: %w", modPath, rel, errProxyNotFound)
+			continue
+		}
+		if !proxy.fallbackAny {
+			return nil, fmt.Errorf("请求 %s 失败: %w", requestURL, err)
+		}
+		lastErr = fmt.Errorf("请求 %s 失败: %w", requestURL, err)
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("没有可用的模块代理")
+	}
+	return nil, lastErr
+}
+
+// cachePath 缓存目录结构与代理协议一致，可直接作为file://代理使用
+func (c *ModuleProxyClient) cachePath(modPath, rel string) (string, error) {
+	escapedPath, err := module.EscapePath(modPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.cacheDir, filepath.FromSlash(escapedPath), filepath.FromSlash(rel)), nil
+}
+
+func writeCacheFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("写入缓存失败: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入缓存失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入缓存失败: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func decodeVersionInfo(data []byte) (*ModuleVersionInfo, error) {
+	info := &ModuleVersionInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("解析版本信息失败: %w", err)
+	}
+	return info, nil
+}
+
+// latestVersion 优先选择最高的正式版本，没有正式版本时选择最高的预发布版本
+func latestVersion(versions []string) string {
+	latest, latestPre := "", ""
+	for _, version := range versions {
+		if !semver.IsValid(version) {
+			continue
+		}
+		if semver.Prerelease(version) == "" {
+			if semver.Compare(version, latest) > 0 {
+				latest = version
+			}
+		} else if semver.Compare(version, latestPre) > 0 {
+			latestPre = version
+		}
+	}
+	if latest != "" {
+		return latest
+	}
+	return latestPre
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
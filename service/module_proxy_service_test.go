@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModuleProxyClient(t *testing.T) {
+	ctx := context.Background()
+	proxyDir := writeTestModule(t, map[string]string{
+		"example.com/dep/@v/list":           "v1.0.0\nv1.2.0\nv1.3.0-rc.1\n",
+		"example.com/dep/@v/v1.0.0.info":    `{"Version":"v1.0.0","Time":"2024-01-01T00:00:00Z"}`,
+		"example.com/dep/@v/v1.2.0.info":    `{"Version":"v1.2.0","Time":"2024-06-01T00:00:00Z"}`,
+		"example.com/dep/@v/v1.2.0.mod":     "module example.com/dep\n",
+		"example.com/dep/@v/v1.2.0.zip":     "zip",
+		"github.com/!big!corp/tool/@v/list": "v0.1.0\n",
+	})
+	cacheDir := t.TempDir()
+	client, err := NewModuleProxyClient(&ProxyConfig{
+		GoProxy:   "file:///nonexistent|file://" + filepath.ToSlash(proxyDir),
+		GoPrivate: "corp.internal",
+		CacheDir:  cacheDir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := client.List(ctx, "example.com/dep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(versions, ",") != "v1.0.0,v1.2.0,v1.3.0-rc.1" {
+		t.Errorf("unexpected versions: %v", versions)
+	}
+	latest, err := client.Latest(ctx, "example.com/dep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version != "v1.2.0" || latest.Time.Year() != 2024 {
+		t.Errorf("unexpected latest: %+v", latest)
+	}
+	// 默认的 ...,direct 配置下，代理返回不存在时仍然根据版本列表选择
+	directClient, err := NewModuleProxyClient(&ProxyConfig{
+		GoProxy:  "file://" + filepath.ToSlash(proxyDir) + ",direct",
+		CacheDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest, err := directClient.Latest(ctx, "example.com/dep"); err != nil || latest.Version != "v1.2.0" {
+		t.Errorf("unexpected latest with direct fallback: %+v %v", latest, err)
+	}
+	goMod, err := client.GoMod(ctx, "example.com/dep", "v1.2.0")
+	if err != nil || string(goMod) != "module example.com/dep\n" {
+		t.Errorf("unexpected go.mod: %q %v", goMod, err)
+	}
+	zipPath, err := client.DownloadZip(ctx, "example.com/dep", "v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(zipPath, cacheDir) {
+		t.Errorf("zip should be cached, got %s", zipPath)
+	}
+	if _, err := client.List(ctx, "github.com/BigCorp/tool"); err != nil {
+		t.Errorf("escaped module path not resolved: %v", err)
+	}
+	// 删除代理中的文件后仍然可以从缓存读取
+	if err := os.RemoveAll(filepath.Join(proxyDir, "example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Info(ctx, "example.com/dep", "v1.2.0"); err != nil {
+		t.Errorf("expected cached info, got %v", err)
+	}
+	// 私有模块即使存在缓存也不通过代理获取
+	privateInfo := filepath.Join(cacheDir, "corp.internal", "secret", "@v", "v1.0.0.info")
+	if err := writeCacheFile(privateInfo, []byte(`{"Version":"v1.0.0"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Info(ctx, "corp.internal/secret", "v1.0.0"); err == nil {
+		t.Errorf("private module should not be fetched through proxy")
+	}
+	outdated := client.CheckOutdated(ctx, &ModuleInfo{
+		Requires: []Dependency{
+			{Path: "example.com/dep", Version: "v1.0.0"},
+			{Path: "example.com/local", Version: "v0.0.0"},
+		},
+		Replaces: []ReplaceRule{{OldPath: "example.com/local", NewPath: "../local"}},
+	})
+	if len(outdated) != 2 || !outdated[0].Outdated || outdated[0].Latest != "v1.2.0" || outdated[0].Error != nil {
+		t.Errorf("unexpected outdated result: %+v", outdated[0])
+	}
+	if outdated[1].Outdated || outdated[1].Error != nil {
+		t.Errorf("local replace should be skipped: %+v", outdated[1])
+	}
+}
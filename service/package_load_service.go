@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -20,16 +21,19 @@ type LoadConfig struct {
 	RepoPath string
 	PkgPath  string
 	LoadEnum LoadEnum
+	Tests    bool // 是否同时加载测试文件
 }
 
-func LoadPackages(ctx context.Context, loadConfig *LoadConfig) {
+// LoadPackages 按配置加载包，返回带类型信息和语法树的包列表
+func LoadPackages(ctx context.Context, loadConfig *LoadConfig) ([]*packages.Package, error) {
 	// 配置加载选项
 	cfg := &packages.Config{
 		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
 			packages.NeedImports | packages.NeedDeps | packages.NeedTypes |
-			packages.NeedSyntax | packages.NeedTypesInfo,
-		Tests: false,               // 包含测试包
-		Dir:   loadConfig.RepoPath, // 当前目录作为基准
+			packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedTypesSizes,
+		Tests:   loadConfig.Tests,    // 包含测试包
+		Dir:     loadConfig.RepoPath, // 当前目录作为基准
+		Context: ctx,
 	}
 	// 加载包
 	loadPatterns := make([]string, 0)
@@ -45,18 +49,35 @@ func LoadPackages(ctx context.Context, loadConfig *LoadConfig) {
 	}
 	pkgs, err := packages.Load(cfg, loadPatterns...)
 	if err != nil {
-		fmt.Printf("加载包失败: %v\n", err)
+		return nil, fmt.Errorf("加载包失败: %w", err)
 	}
-	// 检查加载过程中是否有错误
+	// 检查加载过程中是否有错误，错误和包信息都输出到标准错误，不影响命令在标准输出上的结果
 	if packages.PrintErrors(pkgs) > 0 {
-		fmt.Printf("加载包过程中存在错误\n")
+		log.Printf("加载包过程中存在错误")
 	}
 	// 打印加载的包信息
-	fmt.Printf("成功加载 %d 个包\n", len(pkgs))
+	log.Printf("成功加载 %d 个包", len(pkgs))
 	for _, pkg := range pkgs {
-		fmt.Printf("\n包名: %s\n", pkg.Name)
-		fmt.Printf("导入路径: %s\n", pkg.PkgPath)
-		fmt.Printf("Go源文件数量: %d\n", len(pkg.GoFiles))
-		fmt.Printf("依赖数量: %d\n", len(pkg.Imports))
+		log.Printf("包名: %s 导入路径: %s Go源文件数量: %d 依赖数量: %d", pkg.Name, pkg.PkgPath, len(pkg.GoFiles), len(pkg.Imports))
 	}
+	return pkgs, nil
+}
+
+// packagesClosure 返回包自身及其直接或间接导入的所有包路径
+func packagesClosure(root *packages.Package) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0)
+	var visit func(pkg *packages.Package)
+	visit = func(pkg *packages.Package) {
+		if seen[pkg.PkgPath] {
+			return
+		}
+		seen[pkg.PkgPath] = true
+		result = append(result, pkg.PkgPath)
+		for _, imp := range pkg.Imports {
+			visit(imp)
+		}
+	}
+	visit(root)
+	return result
 }
@@ -0,0 +1,386 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+	"github.com/google/pprof/profile"
+)
+
+// FuncCost 单个函数的开销，Flat为函数自身开销，Cum包含其调用的函数
+type FuncCost struct {
+	Pkg       string
+	Name      string // 函数名，方法为Type.Method，匿名函数为Parent$N
+	RFilePath string
+	Flat      int64
+	Cum       int64
+	FuncInfo  *vs.FuncInfo
+}
+
+// TypeCost 结构体所有方法（包括方法内的匿名函数）的开销
+type TypeCost struct {
+	Pkg  string
+	Type string
+	Flat int64
+	Cum  int64
+}
+
+// PkgCost 包的开销，包括模块外的包
+type PkgCost struct {
+	Pkg  string
+	Flat int64
+	Cum  int64
+}
+
+// PprofReport pprof采样映射到模块符号后的开销报告
+type PprofReport struct {
+	Module     string
+	SampleType string
+	Unit       string
+	Total      int64
+	Unmatched  int64 // 叶子帧无法映射到模块函数的开销
+	Funcs      []*FuncCost
+	Types      []*TypeCost
+	Pkgs       []*PkgCost
+}
+
+// LoadPprofProfile 读取CPU、heap等pprof文件，支持gzip压缩格式
+func LoadPprofProfile(profilePath string) (*profile.Profile, error) {
+	file, err := os.Open(profilePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开pprof文件失败: %w", err)
+	}
+	defer file.Close()
+	prof, err := profile.Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("解析pprof文件失败: %w", err)
+	}
+	return prof, nil
+}
+
+// pprofFrame 调用栈中的一帧映射到的符号
+type pprofFrame struct {
+	pkg      string
+	funcCost *FuncCost
+	typeCost *TypeCost
+}
+
+// BuildPprofReport 将pprof采样按文件行号（或函数名）映射到FuncInfo，统计函数、结构体方法和包的flat与cum开销，
+// sampleType为空时使用profile的默认采样类型，如cpu、inuse_space
+func BuildPprofReport(modInfo *ModuleInfo, prof *profile.Profile, sampleType string) (*PprofReport, error) {
+	if sampleType == "" {
+		sampleType = prof.DefaultSampleType
+	}
+	valueIndex := len(prof.SampleType) - 1
+	if sampleType != "" {
+		valueIndex = -1
+		for i, st := range prof.SampleType {
+			if st.Type == sampleType {
+				valueIndex = i
+				break
+			}
+		}
+		if valueIndex < 0 {
+			return nil, fmt.Errorf("pprof中不存在采样类型: %s", sampleType)
+		}
+	}
+	if valueIndex < 0 {
+		return nil, fmt.Errorf("pprof中没有采样类型")
+	}
+	report := &PprofReport{
+		Module:     modInfo.Path,
+		SampleType: prof.SampleType[valueIndex].Type,
+		Unit:       prof.SampleType[valueIndex].Unit,
+	}
+	symbolizer := newFuncSymbolizer(modInfo)
+	funcCosts := make(map[*vs.FuncInfo]*FuncCost)
+	typeCosts := make(map[string]*TypeCost)
+	pkgCosts := make(map[string]*PkgCost)
+	frameCache := make(map[*profile.Line]*pprofFrame)
+	resolve := func(line *profile.Line) *pprofFrame {
+		if frame, ok := frameCache[line]; ok {
+			return frame
+		}
+		frame := &pprofFrame{}
+		if line.Function != nil {
+			frame.pkg = runtimeFuncPkg(line.Function.Name)
+			if funcInfo := symbolizer.locate(line.Function.Name, line.Function.Filename, int(line.Line)); funcInfo != nil {
+				frame.pkg = funcInfo.Pkg
+				funcCost, ok := funcCosts[funcInfo]
+				if !ok {
+					funcCost = &FuncCost{
+						Pkg:       funcInfo.Pkg,
+						Name:      skeletonDisplayName(funcInfo),
+						RFilePath: funcInfo.RFilePath,
+						FuncInfo:  funcInfo,
+					}
+					funcCosts[funcInfo] = funcCost
+				}
+				frame.funcCost = funcCost
+				if typeName := symbolizer.ownerType(funcInfo); typeName != "" {
+					key := funcInfo.Pkg + "." + typeName
+					typeCost, ok := typeCosts[key]
+					if !ok {
+						typeCost = &TypeCost{Pkg: funcInfo.Pkg, Type: typeName}
+						typeCosts[key] = typeCost
+					}
+					frame.typeCost = typeCost
+				}
+			}
+		}
+		if frame.pkg != "" {
+			if _, ok := pkgCosts[frame.pkg]; !ok {
+				pkgCosts[frame.pkg] = &PkgCost{Pkg: frame.pkg}
+			}
+		}
+		frameCache[line] = frame
+		return frame
+	}
+	for _, sample := range prof.Sample {
+		value := sample.Value[valueIndex]
+		if value == 0 {
+			continue
+		}
+		report.Total += value
+		// 递归调用时同一符号在一个采样中只累计一次cum
+		seenFuncs := make(map[*FuncCost]bool)
+		seenTypes := make(map[*TypeCost]bool)
+		seenPkgs := make(map[string]bool)
+		leaf := true
+		for _, location := range sample.Location {
+			// 内联展开时Line[0]是最内层的函数
+			for i := range location.Line {
+				frame := resolve(&location.Line[i])
+				if leaf {
+					leaf = false
+					if frame.funcCost != nil {
+						frame.funcCost.Flat += value
+					} else {
+						report.Unmatched += value
+					}
+					if frame.typeCost != nil {
+						frame.typeCost.Flat += value
+					}
+					if frame.pkg != "" {
+						pkgCosts[frame.pkg].Flat += value
+					}
+				}
+				if frame.funcCost != nil && !seenFuncs[frame.funcCost] {
+					seenFuncs[frame.funcCost] = true
+					frame.funcCost.Cum += value
+				}
+				if frame.typeCost != nil && !seenTypes[frame.typeCost] {
+					seenTypes[frame.typeCost] = true
+					frame.typeCost.Cum += value
+				}
+				if frame.pkg != "" && !seenPkgs[frame.pkg] {
+					seenPkgs[frame.pkg] = true
+					pkgCosts[frame.pkg].Cum += value
+				}
+			}
+		}
+		if leaf {
+			report.Unmatched += value
+		}
+	}
+	for _, funcCost := range funcCosts {
+		report.Funcs = append(report.Funcs, funcCost)
+	}
+	sort.Slice(report.Funcs, func(i, j int) bool {
+		if report.Funcs[i].Flat != report.Funcs[j].Flat {
+			return report.Funcs[i].Flat > report.Funcs[j].Flat
+		}
+		if report.Funcs[i].Cum != report.Funcs[j].Cum {
+			return report.Funcs[i].Cum > report.Funcs[j].Cum
+		}
+		return report.Funcs[i].Pkg+"."+report.Funcs[i].Name < report.Funcs[j].Pkg+"."+report.Funcs[j].Name
+	})
+	for _, key := range sortedMapKeys(typeCosts) {
+		report.Types = append(report.Types, typeCosts[key])
+	}
+	sort.SliceStable(report.Types, func(i, j int) bool {
+		return report.Types[i].Cum > report.Types[j].Cum
+	})
+	for _, pkg := range sortedMapKeys(pkgCosts) {
+		report.Pkgs = append(report.Pkgs, pkgCosts[pkg])
+	}
+	sort.SliceStable(report.Pkgs, func(i, j int) bool {
+		return report.Pkgs[i].Cum > report.Pkgs[j].Cum
+	})
+	return report, nil
+}
+
+// funcSymbolizer 将运行时的函数名和文件行号（pprof、panic堆栈）映射到模块中的函数
+type funcSymbolizer struct {
+	modInfo      *ModuleInfo
+	fileFuncs    map[string][]*vs.FuncInfo
+	files        []string                // 按长度降序，优先匹配更长的相对路径
+	nameFuncs    map[string]*vs.FuncInfo // 包路径.Type.Method -> 具名函数
+	closureFuncs map[string]*vs.FuncInfo // 包路径.Type.Method.func1.2 -> 匿名函数
+}
+
+func newFuncSymbolizer(modInfo *ModuleInfo) *funcSymbolizer {
+	symbolizer := &funcSymbolizer{
+		modInfo:      modInfo,
+		fileFuncs:    moduleFileFuncs(modInfo),
+		nameFuncs:    make(map[string]*vs.FuncInfo),
+		closureFuncs: make(map[string]*vs.FuncInfo),
+	}
+	symbolizer.files = sortedMapKeys(symbolizer.fileFuncs)
+	sort.SliceStable(symbolizer.files, func(i, j int) bool {
+		return len(symbolizer.files[i]) > len(symbolizer.files[j])
+	})
+	for _, funcs := range symbolizer.fileFuncs {
+		for _, funcInfo := range funcs {
+			if !strings.Contains(funcInfo.Name, "$") {
+				key := funcInfo.Pkg + "." + skeletonDisplayName(funcInfo)
+				symbolizer.nameFuncs[key] = funcInfo
+				symbolizer.indexClosures(key, funcInfo, funcs)
+			}
+		}
+	}
+	return symbolizer
+}
+
+// indexClosures 按编译器的规则为具名函数内的匿名函数编号：直接嵌套的依次为func1、func2，更深层的为func1.1
+func (s *funcSymbolizer) indexClosures(key string, parent *vs.FuncInfo, funcs []*vs.FuncInfo) {
+	type closureNode struct {
+		funcInfo *vs.FuncInfo
+		name     string
+		children int
+	}
+	// funcs已按起始位置排序
+	stack := []*closureNode{{funcInfo: parent, name: key}}
+	for _, funcInfo := range funcs {
+		if !strings.Contains(funcInfo.Name, "$") || !funcContains(parent, funcInfo) {
+			continue
+		}
+		for len(stack) > 1 && !funcContains(stack[len(stack)-1].funcInfo, funcInfo) {
+			stack = stack[:len(stack)-1]
+		}
+		top := stack[len(stack)-1]
+		top.children++
+		name := fmt.Sprintf("%s.%d", top.name, top.children)
+		if len(stack) == 1 {
+			name = fmt.Sprintf("%s.func%d", top.name, top.children)
+		}
+		s.closureFuncs[name] = funcInfo
+		stack = append(stack, &closureNode{funcInfo: funcInfo, name: name})
+	}
+}
+
+// funcContains 判断inner是否定义在outer内部
+func funcContains(outer, inner *vs.FuncInfo) bool {
+	return outer != inner && outer.StartPosition.OffSet <= inner.StartPosition.OffSet && outer.EndPosition.OffSet >= inner.EndPosition.OffSet
+}
+
+// locate 优先按源文件路径后缀和行号定位最内层函数，profile或堆栈来自其他机器时路径前缀可能不同；
+// 没有文件信息时按函数名定位
+func (s *funcSymbolizer) locate(funcName, fileName string, line int) *vs.FuncInfo {
+	// 模块外的函数不按文件匹配，避免runtime/panic.go等与模块内同名文件混淆；main包的函数名不含模块路径
+	if pkg := runtimeFuncPkg(funcName); funcName != "" && pkg != "main" && !isModulePkg(s.modInfo.Path, pkg) {
+		return nil
+	}
+	byName, exact := s.locateByName(funcName)
+	fileName = strings.ReplaceAll(fileName, "\\", "/")
+	if fileName != "" && line > 0 {
+		for _, rFilePath := range s.files {
+			if fileName == rFilePath || strings.HasSuffix(fileName, "/"+rFilePath) {
+				funcInfo := LocateFuncInfo(s.fileFuncs[rFilePath], line, 0)
+				// 行号处于函数内嵌套的匿名函数中时（如调用匿名函数的一行），以函数名为准
+				if funcInfo != nil && exact && funcContains(byName, funcInfo) {
+					return byName
+				}
+				if funcInfo != nil {
+					return funcInfo
+				}
+				break
+			}
+		}
+	}
+	return byName
+}
+
+// locateByName 按运行时函数名查找函数，如 pkg.(*T).Method.func1 对应 Method$1，
+// 无法对应到具体匿名函数或为go、defer包装函数时返回外层函数，此时exact为false
+func (s *funcSymbolizer) locateByName(name string) (*vs.FuncInfo, bool) {
+	pkg := runtimeFuncPkg(name)
+	if !isModulePkg(s.modInfo.Path, pkg) {
+		return nil, false
+	}
+	symbol := strings.TrimPrefix(stripTypeArgs(name), pkg+".")
+	parts := strings.Split(symbol, ".")
+	exact := true
+	// 去掉go和defer语句生成的包装函数 .gowrapN、.deferwrapN
+	for len(parts) > 1 {
+		last := parts[len(parts)-1]
+		if !strings.HasPrefix(last, "gowrap") && !strings.HasPrefix(last, "deferwrap") {
+			break
+		}
+		parts = parts[:len(parts)-1]
+		exact = false
+	}
+	base := len(parts)
+	for i, part := range parts {
+		if i > 0 && strings.HasPrefix(part, "func") && strings.Trim(part[len("func"):], "0123456789") == "" {
+			base = i
+			break
+		}
+	}
+	if len(parts[:base]) == 2 {
+		parts[0] = strings.TrimSuffix(strings.TrimPrefix(parts[0], "(*"), ")")
+	}
+	key := pkg + "." + strings.Join(parts, ".")
+	if base == len(parts) {
+		funcInfo := s.nameFuncs[key]
+		return funcInfo, exact && funcInfo != nil
+	}
+	if funcInfo, ok := s.closureFuncs[key]; ok {
+		return funcInfo, exact
+	}
+	return s.nameFuncs[pkg+"."+strings.Join(parts[:base], ".")], false
+}
+
+// ownerType 返回方法（或方法内匿名函数）所属的类型，普通函数返回空
+func (s *funcSymbolizer) ownerType(funcInfo *vs.FuncInfo) string {
+	if funcInfo.Receiver != nil {
+		return receiverTypeName(funcInfo.Receiver)
+	}
+	if !strings.Contains(funcInfo.Name, "$") {
+		return ""
+	}
+	for _, candidate := range s.fileFuncs[filepath.ToSlash(funcInfo.RFilePath)] {
+		if candidate.Receiver == nil || strings.Contains(candidate.Name, "$") {
+			continue
+		}
+		if candidate.StartPosition.OffSet <= funcInfo.StartPosition.OffSet && candidate.EndPosition.OffSet >= funcInfo.EndPosition.OffSet {
+			return receiverTypeName(candidate.Receiver)
+		}
+	}
+	return ""
+}
+
+// runtimeFuncPkg 从运行时函数名中解析包路径，如 example.com/app/calc.(*Calc).Add
+func runtimeFuncPkg(name string) string {
+	name = stripTypeArgs(name)
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return ""
+	}
+	return name[:slash+1+dot]
+}
+
+// stripTypeArgs 去掉泛型函数名中的类型参数 [...]
+func stripTypeArgs(name string) string {
+	if index := strings.Index(name, "["); index >= 0 {
+		if end := strings.LastIndex(name, "]"); end > index {
+			return name[:index] + name[end+1:]
+		}
+	}
+	return name
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestBuildPprofReport(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"calc/calc.go": `package calc
+
+type Calc struct{}
+
+func (c *Calc) Add(a, b int) int {
+	return a + b
+}
+
+func (c *Calc) Sum(values []int) int {
+	total := 0
+	each(values, func(v int) {
+		total = c.Add(total, v)
+	})
+	return total
+}
+
+func each(values []int, fn func(int)) {
+	for _, v := range values {
+		fn(v)
+	}
+}
+`,
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := "/build/src/app/calc/calc.go"
+	newFunc := func(id uint64, name, fileName string) *profile.Function {
+		return &profile.Function{ID: id, Name: name, SystemName: name, Filename: fileName}
+	}
+	funcs := []*profile.Function{
+		newFunc(1, "example.com/app/calc.(*Calc).Add", file),
+		newFunc(2, "example.com/app/calc.(*Calc).Sum.func1", file),
+		newFunc(3, "example.com/app/calc.each", file),
+		newFunc(4, "example.com/app/calc.(*Calc).Sum", file),
+		newFunc(5, "runtime.mallocgc", "/usr/local/go/src/runtime/malloc.go"),
+		// 没有文件信息时按函数名匹配
+		newFunc(6, "example.com/app/calc.(*Calc).Sum", ""),
+	}
+	newLocation := func(id uint64, lines ...profile.Line) *profile.Location {
+		return &profile.Location{ID: id, Address: id, Line: lines}
+	}
+	locations := []*profile.Location{
+		// Add内联到闭包中
+		newLocation(1, profile.Line{Function: funcs[0], Line: 6}, profile.Line{Function: funcs[1], Line: 12}),
+		newLocation(2, profile.Line{Function: funcs[2], Line: 19}),
+		newLocation(3, profile.Line{Function: funcs[3], Line: 11}),
+		newLocation(4, profile.Line{Function: funcs[4], Line: 100}),
+		newLocation(5, profile.Line{Function: funcs[5]}),
+	}
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     10,
+		Sample: []*profile.Sample{
+			{Location: []*profile.Location{locations[0], locations[1], locations[2]}, Value: []int64{1, 30}},
+			{Location: []*profile.Location{locations[3], locations[1], locations[2]}, Value: []int64{1, 20}},
+			{Location: []*profile.Location{locations[1], locations[4]}, Value: []int64{1, 5}},
+		},
+		Location: locations,
+		Function: funcs,
+	}
+	var buf bytes.Buffer
+	if err := prof.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	profilePath := filepath.Join(t.TempDir(), "cpu.pprof")
+	if err := os.WriteFile(profilePath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadPprofProfile(profilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := BuildPprofReport(modInfo, loaded, "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 55 || report.Unit != "nanoseconds" || report.Unmatched != 20 {
+		t.Errorf("unexpected totals: %+v", report)
+	}
+	costs := make(map[string]*FuncCost)
+	for _, funcCost := range report.Funcs {
+		costs[funcCost.Name] = funcCost
+	}
+	expect := map[string][2]int64{
+		"Calc.Add": {30, 30},
+		"Sum$1":    {0, 30},
+		"each":     {5, 55},
+		"Calc.Sum": {0, 55},
+	}
+	for name, values := range expect {
+		if costs[name] == nil || costs[name].Flat != values[0] || costs[name].Cum != values[1] {
+			t.Errorf("%s: expected flat/cum %v, got %+v", name, values, costs[name])
+		}
+	}
+	if len(report.Types) != 1 || report.Types[0].Type != "Calc" || report.Types[0].Flat != 30 || report.Types[0].Cum != 55 {
+		t.Errorf("unexpected type costs: %+v", report.Types[0])
+	}
+	pkgs := make(map[string]*PkgCost)
+	for _, pkgCost := range report.Pkgs {
+		pkgs[pkgCost.Pkg] = pkgCost
+	}
+	if pkgs["example.com/app/calc"].Cum != 55 || pkgs["example.com/app/calc"].Flat != 35 || pkgs["runtime"].Flat != 20 {
+		t.Errorf("unexpected package costs: %+v %+v", pkgs["example.com/app/calc"], pkgs["runtime"])
+	}
+	if _, err := BuildPprofReport(modInfo, loaded, "alloc_space"); err == nil {
+		t.Error("expected error for unknown sample type")
+	}
+}
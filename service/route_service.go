@@ -0,0 +1,954 @@
+package service
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+)
+
+const (
+	RouteFrameworkNetHTTP = "net/http"
+	RouteFrameworkGin     = "gin"
+	RouteFrameworkEcho    = "echo"
+	RouteFrameworkHertz   = "hertz"
+	RouteFrameworkChi     = "chi"
+
+	// RouteMethodAny 匹配任意HTTP方法的路由
+	RouteMethodAny = "ANY"
+
+	// routeMaxDepth 跟踪路由参数传入模块内函数的最大深度
+	routeMaxDepth = 8
+)
+
+// routeImportFrameworks 框架的导入路径
+var routeImportFrameworks = map[string]string{
+	"net/http":                                  RouteFrameworkNetHTTP,
+	"github.com/gin-gonic/gin":                  RouteFrameworkGin,
+	"github.com/labstack/echo":                  RouteFrameworkEcho,
+	"github.com/labstack/echo/v4":               RouteFrameworkEcho,
+	"github.com/cloudwego/hertz/pkg/app/server": RouteFrameworkHertz,
+	"github.com/cloudwego/hertz/pkg/route":      RouteFrameworkHertz,
+	"github.com/go-chi/chi":                     RouteFrameworkChi,
+	"github.com/go-chi/chi/v5":                  RouteFrameworkChi,
+}
+
+// routeConstructors 创建路由的函数及其默认注册的中间件
+var routeConstructors = map[string]map[string][]string{
+	RouteFrameworkNetHTTP: {"NewServeMux": nil},
+	RouteFrameworkGin:     {"New": nil, "Default": {"gin.Logger()", "gin.Recovery()"}},
+	RouteFrameworkEcho:    {"New": nil},
+	RouteFrameworkHertz:   {"New": nil, "Default": {"recovery.Recovery()"}},
+	RouteFrameworkChi:     {"NewRouter": nil, "NewMux": nil},
+}
+
+// routeTypes 作为参数或字面量出现时视为路由的类型
+var routeTypes = map[string][]string{
+	RouteFrameworkNetHTTP: {"ServeMux"},
+	RouteFrameworkGin:     {"Engine", "RouterGroup", "IRouter", "IRoutes"},
+	RouteFrameworkEcho:    {"Echo", "Group"},
+	RouteFrameworkHertz:   {"Hertz", "Engine", "RouterGroup", "IRouter", "IRoutes"},
+	RouteFrameworkChi:     {"Router", "Mux"},
+}
+
+// routeHandlerAdapters 将函数转换为handler的适配函数，不视为中间件
+var routeHandlerAdapters = map[string]bool{
+	"http.HandlerFunc": true,
+	"gin.WrapF":        true,
+	"gin.WrapH":        true,
+	"echo.WrapHandler": true,
+}
+
+var httpMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "CONNECT", "TRACE"}
+
+// Route 一条HTTP路由
+type Route struct {
+	Framework   string       `json:"framework"`              // 路由框架
+	Method      string       `json:"method"`                 // HTTP方法，任意方法为ANY
+	Path        string       `json:"path"`                   // 组合了分组前缀的完整路径
+	Handler     string       `json:"handler"`                // handler表达式
+	HandlerPkg  string       `json:"handler_pkg,omitempty"`  // handler所在的包
+	HandlerFunc string       `json:"handler_func,omitempty"` // handler函数名，方法为Type.Method，匿名函数为Parent$N
+	Middlewares []string     `json:"middlewares,omitempty"`  // 按执行顺序排列的中间件
+	RFilePath   string       `json:"file"`                   // 注册路由的文件
+	Line        int          `json:"line"`                   // 注册路由的行号
+	Register    string       `json:"register"`               // 注册路由的函数
+	HandlerInfo *vs.FuncInfo `json:"-"`
+}
+
+// routerState 路由或路由分组在静态分析中的状态
+type routerState struct {
+	framework   string
+	prefix      string   // 分组前缀
+	middlewares []string // 当前已注册的中间件
+	mountParent *routerState
+	mountPath   string // chi的Mount可能在子路由注册路由之后，因此挂载前缀在最后计算
+}
+
+// group 创建分组，分组会复制当前的中间件
+func (r *routerState) group(prefix string, middlewares []string) *routerState {
+	return &routerState{
+		framework:   r.framework,
+		prefix:      joinRoutePath(r.prefix, prefix),
+		middlewares: append(append([]string{}, r.middlewares...), middlewares...),
+		mountParent: r.mountParent,
+		mountPath:   r.mountPath,
+	}
+}
+
+// mountPrefix 返回挂载到父路由的前缀
+func (r *routerState) mountPrefix() string {
+	if r.mountParent == nil {
+		return ""
+	}
+	return joinRoutePath(r.mountParent.mountPrefix()+r.mountParent.prefix, r.mountPath)
+}
+
+// mountMiddlewares 返回父路由的中间件
+func (r *routerState) mountMiddlewares() []string {
+	if r.mountParent == nil {
+		return nil
+	}
+	return append(r.mountParent.mountMiddlewares(), r.mountParent.middlewares...)
+}
+
+// pendingRoute 尚未计算挂载前缀的路由
+type pendingRoute struct {
+	route       *Route
+	state       *routerState
+	path        string
+	middlewares []string
+}
+
+type routeFuncDecl struct {
+	file     *moduleGoFile
+	decl     *ast.FuncDecl
+	key      string // 包路径.函数名，方法为包路径.Type.Method
+	funcInfo *vs.FuncInfo
+}
+
+type routeDiscovery struct {
+	modInfo    *ModuleInfo
+	fileSet    *token.FileSet
+	funcs      map[string]*routeFuncDecl
+	methods    map[string][]*routeFuncDecl // 方法名 -> 方法
+	consts     stringConsts
+	fileFuncs  map[string][]*vs.FuncInfo
+	called     map[*routeFuncDecl]bool // 以路由为参数被调用过的函数
+	defaultMux *routerState
+	pending    []*pendingRoute
+}
+
+// routeScope 变量作用域，键为变量或字段表达式
+type routeScope struct {
+	vars   map[string]*routerState
+	parent *routeScope
+}
+
+func newRouteScope(parent *routeScope) *routeScope {
+	return &routeScope{vars: make(map[string]*routerState), parent: parent}
+}
+
+func (s *routeScope) lookup(key string) *routerState {
+	for scope := s; scope != nil; scope = scope.parent {
+		if state, ok := scope.vars[key]; ok {
+			return state
+		}
+	}
+	return nil
+}
+
+// DiscoverRoutes 发现模块中通过net/http、gin、echo、hertz和chi注册的路由，组合分组前缀和中间件，
+// 并将handler解析到FuncInfo；路由作为参数传给模块内函数时会跟踪到被调用的函数中
+func DiscoverRoutes(modInfo *ModuleInfo) ([]*Route, error) {
+	d := &routeDiscovery{
+		modInfo:   modInfo,
+		fileSet:   token.NewFileSet(),
+		funcs:     make(map[string]*routeFuncDecl),
+		methods:   make(map[string][]*routeFuncDecl),
+		consts:    make(stringConsts),
+		fileFuncs: moduleFileFuncs(modInfo),
+		called:    make(map[*routeFuncDecl]bool),
+	}
+	d.defaultMux = &routerState{framework: RouteFrameworkNetHTTP}
+	funcInfos := make(map[string]*vs.FuncInfo)
+	for _, funcs := range modInfo.PkgFuncMap {
+		for _, funcInfo := range funcs {
+			if !strings.Contains(funcInfo.Name, "$") {
+				funcInfos[funcInfo.Pkg+"."+skeletonDisplayName(funcInfo)] = funcInfo
+			}
+		}
+	}
+	decls := make([]*routeFuncDecl, 0)
+	files, err := parseModuleGoFiles(modInfo, d.fileSet)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		for _, decl := range file.file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				d.consts.collect(file.pkg, decl)
+			case *ast.FuncDecl:
+				name := decl.Name.Name
+				if decl.Recv != nil && len(decl.Recv.List) > 0 {
+					name = recvExprName(decl.Recv.List[0].Type) + "." + name
+				}
+				key := file.pkg + "." + name
+				funcDecl := &routeFuncDecl{file: file, decl: decl, key: key, funcInfo: funcInfos[key]}
+				d.funcs[funcDecl.key] = funcDecl
+				if decl.Recv != nil {
+					d.methods[decl.Name.Name] = append(d.methods[decl.Name.Name], funcDecl)
+				}
+				decls = append(decls, funcDecl)
+			}
+		}
+	}
+	// 每个函数都作为入口分析，被其他函数以路由为参数调用过的函数的结果以调用方为准
+	roots := make(map[*routeFuncDecl][]*pendingRoute)
+	for _, funcDecl := range decls {
+		if funcDecl.decl.Body == nil {
+			continue
+		}
+		before := len(d.pending)
+		walker := &routeWalker{d: d, stack: map[*routeFuncDecl]bool{funcDecl: true}, consumed: make(map[*ast.CallExpr]bool)}
+		walker.walkFunc(funcDecl, nil)
+		roots[funcDecl] = d.pending[before:]
+	}
+	routes := make([]*Route, 0)
+	for _, funcDecl := range decls {
+		if d.called[funcDecl] {
+			continue
+		}
+		for _, pending := range roots[funcDecl] {
+			route := pending.route
+			route.Path = joinRoutePath(pending.state.mountPrefix(), pending.path)
+			route.Middlewares = append(pending.state.mountMiddlewares(), pending.middlewares...)
+			routes = append(routes, route)
+		}
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes, nil
+}
+
+// stringConsts 包级字符串常量和变量，键为包路径.名称
+type stringConsts map[string]string
+
+// collect 收集声明中以字符串字面量或其拼接初始化的常量和变量
+func (c stringConsts) collect(pkg string, decl *ast.GenDecl) {
+	if decl.Tok != token.CONST && decl.Tok != token.VAR {
+		return
+	}
+	for _, spec := range decl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok || len(valueSpec.Names) != len(valueSpec.Values) {
+			continue
+		}
+		for i, name := range valueSpec.Names {
+			// 引用了其他常量的常量只能在被引用的常量已收集时计算
+			if value, ok := c.eval(valueSpec.Values[i], pkg, nil); ok {
+				c[pkg+"."+name.Name] = value
+			}
+		}
+	}
+}
+
+// eval 计算字符串表达式，支持字面量、常量和拼接，无法计算的部分替换为<表达式>并返回false
+func (c stringConsts) eval(expr ast.Expr, pkg string, imports map[string]string) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.STRING {
+			if value, err := strconv.Unquote(e.Value); err == nil {
+				return value, true
+			}
+		}
+	case *ast.Ident:
+		if value, ok := c[pkg+"."+e.Name]; ok {
+			return value, true
+		}
+	case *ast.SelectorExpr:
+		if ident, ok := e.X.(*ast.Ident); ok {
+			if value, ok := c[imports[ident.Name]+"."+e.Sel.Name]; ok {
+				return value, true
+			}
+		}
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD {
+			x, xOK := c.eval(e.X, pkg, imports)
+			y, yOK := c.eval(e.Y, pkg, imports)
+			return x + y, xOK && yOK
+		}
+	case *ast.ParenExpr:
+		return c.eval(e.X, pkg, imports)
+	}
+	return "<" + types.ExprString(expr) + ">", false
+}
+
+// importLocalName 返回导入包在文件中的名字，未指定别名时取路径最后一段，忽略/vN版本后缀
+func importLocalName(importSpec *ast.ImportSpec, importPath string) string {
+	if importSpec.Name != nil {
+		return importSpec.Name.Name
+	}
+	parts := strings.Split(importPath, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
+	}
+	return name
+}
+
+type routeWalker struct {
+	d        *routeDiscovery
+	fn       *routeFuncDecl
+	stack    map[*routeFuncDecl]bool
+	consumed map[*ast.CallExpr]bool // 已在赋值或返回语句中分析过的调用，遍历到调用本身时跳过
+}
+
+// walkFunc 分析函数体，bound为调用方传入的路由参数
+func (w *routeWalker) walkFunc(funcDecl *routeFuncDecl, bound map[int]*routerState) *routerState {
+	prev := w.fn
+	w.fn = funcDecl
+	defer func() { w.fn = prev }()
+	scope := newRouteScope(nil)
+	index := 0
+	for _, field := range funcDecl.decl.Type.Params.List {
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{nil}
+		}
+		for _, name := range names {
+			state := bound[index]
+			if state == nil {
+				state = w.typeRouter(field.Type)
+			}
+			if name != nil && state != nil {
+				scope.vars[name.Name] = state
+			}
+			index++
+		}
+	}
+	return w.walk(funcDecl.decl.Body, scope)
+}
+
+// walk 按源码顺序分析语句，返回return语句中返回的路由
+func (w *routeWalker) walk(node ast.Node, scope *routeScope) *routerState {
+	var returned *routerState
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				if len(n.Lhs) == len(n.Rhs) {
+					if state := w.routerOf(n.Rhs[i], scope); state != nil {
+						scope.vars[types.ExprString(lhs)] = state
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				var state *routerState
+				if len(n.Values) == len(n.Names) {
+					state = w.routerOf(n.Values[i], scope)
+				}
+				if state == nil && n.Type != nil && len(n.Values) == 0 {
+					state = w.typeRouter(n.Type)
+				}
+				if state != nil {
+					scope.vars[name.Name] = state
+				}
+			}
+		case *ast.ReturnStmt:
+			for _, result := range n.Results {
+				if state := w.routerOf(result, scope); state != nil && returned == nil {
+					returned = state
+				}
+			}
+		case *ast.CallExpr:
+			return !w.handleCall(n, scope)
+		}
+		return true
+	})
+	return returned
+}
+
+// handleCall 处理路由注册、分组和中间件调用，返回true表示已处理完参数
+func (w *routeWalker) handleCall(call *ast.CallExpr, scope *routeScope) bool {
+	if w.consumed[call] {
+		return true
+	}
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		w.followCall(call, scope)
+		return false
+	}
+	var state *routerState
+	if ident, ok := selector.X.(*ast.Ident); ok && w.fn.file.imports[ident.Name] == "net/http" && scope.lookup(ident.Name) == nil {
+		if selector.Sel.Name != "Handle" && selector.Sel.Name != "HandleFunc" {
+			return false
+		}
+		state = w.d.defaultMux
+	} else {
+		state = w.routerOf(selector.X, scope)
+	}
+	if state == nil {
+		state = w.implicitRouter(call, selector, scope)
+	}
+	if state == nil {
+		w.followCall(call, scope)
+		return false
+	}
+	name := selector.Sel.Name
+	switch {
+	case name == "Use" || name == "Pre" && state.framework == RouteFrameworkEcho:
+		state.middlewares = append(state.middlewares, exprStrings(call.Args)...)
+		return true
+	case state.framework == RouteFrameworkChi && (name == "Route" || name == "Group"):
+		funcLit, ok := call.Args[len(call.Args)-1].(*ast.FuncLit)
+		if !ok {
+			return false
+		}
+		prefix := ""
+		if name == "Route" && len(call.Args) == 2 {
+			prefix = w.evalString(call.Args[0])
+		}
+		w.walkRouterFuncLit(funcLit, state.group(prefix, nil), scope)
+		return true
+	case state.framework == RouteFrameworkChi && name == "Mount" && len(call.Args) == 2:
+		if sub := w.routerOf(call.Args[1], scope); sub != nil && sub != state {
+			sub.mountParent, sub.mountPath = state, w.evalString(call.Args[0])
+		}
+		return true
+	}
+	w.addRoutes(state, name, call, scope)
+	return false
+}
+
+// implicitRouter 接收者无法确定时（如结构体字段），若文件只导入了一个路由框架且调用形如注册路由，则视为该框架的路由
+func (w *routeWalker) implicitRouter(call *ast.CallExpr, selector *ast.SelectorExpr, scope *routeScope) *routerState {
+	if len(call.Args) < 2 {
+		return nil
+	}
+	if ident, ok := selector.X.(*ast.Ident); ok {
+		if _, isImport := w.fn.file.imports[ident.Name]; isImport {
+			return nil
+		}
+	}
+	framework := ""
+	for _, importPath := range w.fn.file.imports {
+		if f, ok := routeImportFrameworks[importPath]; ok && f != RouteFrameworkNetHTTP {
+			if framework != "" && framework != f {
+				return nil
+			}
+			framework = f
+		}
+	}
+	if framework == "" || routeMethod(framework, selector.Sel.Name) == "" {
+		return nil
+	}
+	if lit, ok := call.Args[0].(*ast.BasicLit); !ok || lit.Kind != token.STRING || !strings.HasPrefix(lit.Value, `"/`) {
+		return nil
+	}
+	state := &routerState{framework: framework}
+	scope.vars[types.ExprString(selector.X)] = state
+	return state
+}
+
+// walkRouterFuncLit 以新的作用域分析chi的Route/Group回调
+func (w *routeWalker) walkRouterFuncLit(funcLit *ast.FuncLit, state *routerState, scope *routeScope) {
+	child := newRouteScope(scope)
+	if params := funcLit.Type.Params.List; len(params) == 1 && len(params[0].Names) == 1 {
+		child.vars[params[0].Names[0].Name] = state
+	}
+	w.walk(funcLit.Body, child)
+}
+
+// routeMethod 返回注册方法对应的HTTP方法，需要从参数中读取方法时返回"*"，不是注册方法时返回空
+func routeMethod(framework, name string) string {
+	switch framework {
+	case RouteFrameworkNetHTTP:
+		if name == "Handle" || name == "HandleFunc" {
+			return RouteMethodAny
+		}
+	case RouteFrameworkGin, RouteFrameworkHertz, RouteFrameworkEcho:
+		switch name {
+		case "Any":
+			return RouteMethodAny
+		case "Handle", "Add", "Match":
+			if name == "Add" && framework != RouteFrameworkEcho || name == "Handle" && framework == RouteFrameworkEcho {
+				return ""
+			}
+			return "*"
+		}
+		for _, method := range httpMethods {
+			if name == method {
+				return method
+			}
+		}
+	case RouteFrameworkChi:
+		switch name {
+		case "Handle", "HandleFunc":
+			return RouteMethodAny
+		case "Method", "MethodFunc":
+			return "*"
+		}
+		for _, method := range httpMethods {
+			if name == method[:1]+strings.ToLower(method[1:]) {
+				return method
+			}
+		}
+	}
+	return ""
+}
+
+// addRoutes 解析注册调用的方法、路径、handler和中间件
+func (w *routeWalker) addRoutes(state *routerState, name string, call *ast.CallExpr, scope *routeScope) {
+	method := routeMethod(state.framework, name)
+	if method == "" {
+		return
+	}
+	args := call.Args
+	methods := []string{method}
+	if method == "*" {
+		if len(args) == 0 {
+			return
+		}
+		methods = w.evalMethods(args[0])
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return
+	}
+	routePath := w.evalString(args[0])
+	var handler ast.Expr
+	var middlewares []string
+	switch state.framework {
+	case RouteFrameworkGin, RouteFrameworkHertz:
+		// 最后一个是handler，之前的是路由级中间件
+		handler = args[len(args)-1]
+		middlewares = exprStrings(args[1 : len(args)-1])
+	case RouteFrameworkEcho:
+		handler = args[1]
+		middlewares = exprStrings(args[2:])
+	default:
+		handler = args[1]
+	}
+	if state.framework == RouteFrameworkNetHTTP {
+		// Go 1.22起模式可以带方法和主机名，如 "GET example.com/users/{id}"
+		if method, rest, ok := strings.Cut(routePath, " "); ok {
+			methods, routePath = []string{method}, strings.TrimSpace(rest)
+		}
+		if index := strings.Index(routePath, "/"); index > 0 {
+			routePath = routePath[index:]
+		}
+	}
+	handlerInfo, wrappers := w.resolveHandler(handler, scope)
+	position := w.d.fileSet.Position(call.Pos())
+	for _, method := range methods {
+		route := &Route{
+			Framework:   state.framework,
+			Method:      method,
+			Handler:     types.ExprString(handler),
+			RFilePath:   w.fn.file.rFilePath,
+			Line:        position.Line,
+			Register:    w.fn.key,
+			HandlerInfo: handlerInfo,
+		}
+		if handlerInfo != nil {
+			route.HandlerPkg, route.HandlerFunc = handlerInfo.Pkg, skeletonDisplayName(handlerInfo)
+		}
+		allMiddlewares := append(append(append([]string{}, state.middlewares...), middlewares...), wrappers...)
+		w.d.pending = append(w.d.pending, &pendingRoute{
+			route:       route,
+			state:       state,
+			path:        joinRoutePath(state.prefix, routePath),
+			middlewares: allMiddlewares,
+		})
+	}
+}
+
+// routerOf 判断表达式是否为路由，构造函数和分组会创建新的路由状态
+func (w *routeWalker) routerOf(expr ast.Expr, scope *routeScope) *routerState {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return w.routerOf(e.X, scope)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return w.routerOf(e.X, scope)
+		}
+	case *ast.CompositeLit:
+		return w.typeRouter(e.Type)
+	case *ast.Ident:
+		return scope.lookup(e.Name)
+	case *ast.SelectorExpr:
+		if ident, ok := e.X.(*ast.Ident); ok && w.fn.file.imports[ident.Name] == "net/http" && e.Sel.Name == "DefaultServeMux" {
+			return w.d.defaultMux
+		}
+		return scope.lookup(types.ExprString(e))
+	case *ast.CallExpr:
+		selector, ok := e.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return w.calledRouter(e, scope)
+		}
+		if ident, ok := selector.X.(*ast.Ident); ok && scope.lookup(ident.Name) == nil {
+			if framework, ok := routeImportFrameworks[w.fn.file.imports[ident.Name]]; ok {
+				if middlewares, ok := routeConstructors[framework][selector.Sel.Name]; ok {
+					return &routerState{framework: framework, middlewares: append([]string{}, middlewares...)}
+				}
+				return nil
+			}
+		}
+		parent := w.routerOf(selector.X, scope)
+		if parent == nil {
+			return w.calledRouter(e, scope)
+		}
+		switch {
+		case parent.framework == RouteFrameworkChi && selector.Sel.Name == "With":
+			return parent.group("", exprStrings(e.Args))
+		case parent.framework == RouteFrameworkChi && selector.Sel.Name == "Group" && len(e.Args) == 1:
+			sub := parent.group("", nil)
+			w.consumed[e] = true
+			if funcLit, ok := e.Args[0].(*ast.FuncLit); ok {
+				w.walkRouterFuncLit(funcLit, sub, scope)
+			}
+			return sub
+		case parent.framework == RouteFrameworkChi && selector.Sel.Name == "Route" && len(e.Args) == 2:
+			sub := parent.group(w.evalString(e.Args[0]), nil)
+			w.consumed[e] = true
+			if funcLit, ok := e.Args[1].(*ast.FuncLit); ok {
+				w.walkRouterFuncLit(funcLit, sub, scope)
+			}
+			return sub
+		case parent.framework != RouteFrameworkChi && parent.framework != RouteFrameworkNetHTTP && selector.Sel.Name == "Group" && len(e.Args) > 0:
+			return parent.group(w.evalString(e.Args[0]), exprStrings(e.Args[1:]))
+		}
+	}
+	return nil
+}
+
+// typeRouter 根据类型表达式判断是否为路由类型
+func (w *routeWalker) typeRouter(expr ast.Expr) *routerState {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	ident, ok := selector.X.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	framework, ok := routeImportFrameworks[w.fn.file.imports[ident.Name]]
+	if !ok {
+		return nil
+	}
+	for _, typeName := range routeTypes[framework] {
+		if selector.Sel.Name == typeName {
+			return &routerState{framework: framework}
+		}
+	}
+	return nil
+}
+
+// calledRouter 调用模块内返回路由的函数时，分析该函数并返回其创建的路由
+func (w *routeWalker) calledRouter(call *ast.CallExpr, scope *routeScope) *routerState {
+	callee := w.resolveFunc(call.Fun)
+	if callee == nil || callee.decl.Body == nil || w.stack[callee] || len(w.stack) >= routeMaxDepth {
+		return nil
+	}
+	results := callee.decl.Type.Results
+	if results == nil || len(results.List) == 0 || w.typeRouter(results.List[0].Type) == nil {
+		return nil
+	}
+	w.d.called[callee] = true
+	w.consumed[call] = true
+	w.stack[callee] = true
+	defer delete(w.stack, callee)
+	return w.walkFunc(callee, w.boundArgs(call, scope))
+}
+
+// followCall 路由作为参数传给模块内函数时，在调用方的上下文中分析被调用的函数
+func (w *routeWalker) followCall(call *ast.CallExpr, scope *routeScope) {
+	bound := w.boundArgs(call, scope)
+	if len(bound) == 0 {
+		return
+	}
+	callee := w.resolveFunc(call.Fun)
+	if callee == nil || callee.decl.Body == nil {
+		return
+	}
+	w.d.called[callee] = true
+	if w.stack[callee] || len(w.stack) >= routeMaxDepth {
+		return
+	}
+	w.stack[callee] = true
+	defer delete(w.stack, callee)
+	w.walkFunc(callee, bound)
+}
+
+// boundArgs 返回调用参数中的路由，键为参数位置
+func (w *routeWalker) boundArgs(call *ast.CallExpr, scope *routeScope) map[int]*routerState {
+	bound := make(map[int]*routerState)
+	for i, arg := range call.Args {
+		// 参数中的构造和分组调用会在遍历参数时再次处理，这里只查找已有的路由
+		switch arg.(type) {
+		case *ast.Ident, *ast.SelectorExpr:
+			if state := w.routerOf(arg, scope); state != nil {
+				bound[i] = state
+			}
+		}
+	}
+	return bound
+}
+
+// resolveFunc 将函数表达式解析为模块内的函数声明，方法按方法名查找，同名方法优先取同包且唯一的
+func (w *routeWalker) resolveFunc(expr ast.Expr) *routeFuncDecl {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return w.d.funcs[w.fn.file.pkg+"."+e.Name]
+	case *ast.SelectorExpr:
+		if ident, ok := e.X.(*ast.Ident); ok {
+			if importPath, ok := w.fn.file.imports[ident.Name]; ok {
+				return w.d.funcs[importPath+"."+e.Sel.Name]
+			}
+		}
+		candidates := w.d.methods[e.Sel.Name]
+		samePkg := make([]*routeFuncDecl, 0)
+		for _, candidate := range candidates {
+			if candidate.file.pkg == w.fn.file.pkg {
+				samePkg = append(samePkg, candidate)
+			}
+		}
+		if len(samePkg) == 1 {
+			return samePkg[0]
+		}
+		if len(samePkg) == 0 && len(candidates) == 1 {
+			return candidates[0]
+		}
+	}
+	return nil
+}
+
+// resolveHandler 将handler表达式解析为FuncInfo，handler被其他函数包装时（如auth(h)）返回包装函数作为中间件
+func (w *routeWalker) resolveHandler(expr ast.Expr, scope *routeScope) (*vs.FuncInfo, []string) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return w.resolveHandler(e.X, scope)
+	case *ast.FuncLit:
+		position := w.d.fileSet.Position(e.Pos())
+		return LocateFuncInfo(w.d.fileFuncs[w.fn.file.rFilePath], position.Line, position.Column), nil
+	case *ast.CallExpr:
+		if len(e.Args) > 0 {
+			if funcInfo, wrappers := w.resolveHandler(e.Args[len(e.Args)-1], scope); funcInfo != nil {
+				if fun := types.ExprString(e.Fun); !routeHandlerAdapters[fun] {
+					wrappers = append([]string{fun}, wrappers...)
+				}
+				return funcInfo, wrappers
+			}
+		}
+		// 返回handler的工厂函数
+		if callee := w.resolveFunc(e.Fun); callee != nil {
+			return callee.funcInfo, nil
+		}
+	case *ast.Ident, *ast.SelectorExpr:
+		if callee := w.resolveFunc(e); callee != nil {
+			return callee.funcInfo, nil
+		}
+	}
+	return nil, nil
+}
+
+// evalString 计算路径表达式，无法计算的部分为<表达式>
+func (w *routeWalker) evalString(expr ast.Expr) string {
+	value, _ := w.d.consts.eval(expr, w.fn.file.pkg, w.fn.file.imports)
+	return value
+}
+
+// evalMethods 计算方法参数，支持"GET"、http.MethodGet以及[]string{...}
+func (w *routeWalker) evalMethods(expr ast.Expr) []string {
+	if lit, ok := expr.(*ast.CompositeLit); ok {
+		methods := make([]string, 0, len(lit.Elts))
+		for _, elt := range lit.Elts {
+			methods = append(methods, w.evalMethods(elt)...)
+		}
+		return methods
+	}
+	if selector, ok := expr.(*ast.SelectorExpr); ok && strings.HasPrefix(selector.Sel.Name, "Method") {
+		return []string{strings.ToUpper(strings.TrimPrefix(selector.Sel.Name, "Method"))}
+	}
+	return []string{strings.ToUpper(w.evalString(expr))}
+}
+
+// exprStrings 将表达式转换为源码字符串
+func exprStrings(exprs []ast.Expr) []string {
+	result := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		result = append(result, types.ExprString(expr))
+	}
+	return result
+}
+
+// joinRoutePath 拼接分组前缀和相对路径，与gin一致保留相对路径结尾的/
+func joinRoutePath(prefix, relPath string) string {
+	if relPath == "" {
+		if prefix == "" {
+			return "/"
+		}
+		return prefix
+	}
+	if prefix == "" {
+		if !strings.HasPrefix(relPath, "/") {
+			return "/" + relPath
+		}
+		return relPath
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(relPath, "/")
+}
+
+// OpenAPIDoc OpenAPI 3文档骨架
+type OpenAPIDoc struct {
+	OpenAPI string                                  `json:"openapi"` // OpenAPI版本
+	Info    OpenAPIInfo                             `json:"info"`    // 文档信息
+	Paths   map[string]map[string]*OpenAPIOperation `json:"paths"`   // 路径 -> 小写方法 -> 操作
+}
+
+// OpenAPIInfo 文档信息
+type OpenAPIInfo struct {
+	Title   string `json:"title"`   // 标题
+	Version string `json:"version"` // 版本
+}
+
+// OpenAPIOperation 一个接口操作，x-前缀的字段记录handler和中间件
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`             // 操作ID，文档内唯一
+	Summary     string                      `json:"summary,omitempty"`       // handler注释的第一行
+	Tags        []string                    `json:"tags,omitempty"`          // handler所在包的包名
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`    // 路径参数
+	Responses   map[string]*OpenAPIResponse `json:"responses"`               // 响应
+	Handler     string                      `json:"x-handler"`               // handler函数或表达式
+	Middlewares []string                    `json:"x-middlewares,omitempty"` // 中间件
+}
+
+// OpenAPIParameter 接口参数
+type OpenAPIParameter struct {
+	Name     string            `json:"name"`     // 参数名
+	In       string            `json:"in"`       // 参数位置
+	Required bool              `json:"required"` // 是否必填
+	Schema   map[string]string `json:"schema"`   // 参数类型
+}
+
+// OpenAPIResponse 接口响应
+type OpenAPIResponse struct {
+	Description string `json:"description"` // 响应描述
+}
+
+// BuildOpenAPISkeleton 根据路由生成OpenAPI文档骨架，ANY路由展开为所有标准方法，框架的路径参数统一转换为{name}
+func BuildOpenAPISkeleton(routes []*Route, title, version string) *OpenAPIDoc {
+	doc := &OpenAPIDoc{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: title, Version: version},
+		Paths:   make(map[string]map[string]*OpenAPIOperation),
+	}
+	operationIDs := make(map[string]int)
+	for _, route := range routes {
+		openAPIPath, params := openAPIPathParams(route.Path)
+		methods := []string{route.Method}
+		if route.Method == RouteMethodAny {
+			methods = httpMethods[:7]
+		}
+		for _, method := range methods {
+			method = strings.ToLower(method)
+			operations, ok := doc.Paths[openAPIPath]
+			if !ok {
+				operations = make(map[string]*OpenAPIOperation)
+				doc.Paths[openAPIPath] = operations
+			}
+			if _, exists := operations[method]; exists {
+				continue
+			}
+			operation := &OpenAPIOperation{
+				Responses:   map[string]*OpenAPIResponse{"200": {Description: "OK"}},
+				Handler:     route.Handler,
+				Middlewares: route.Middlewares,
+			}
+			baseID := method + openAPIIdentifier(openAPIPath)
+			if route.HandlerInfo != nil {
+				operation.Handler = route.HandlerPkg + "." + route.HandlerFunc
+				if !strings.Contains(route.HandlerFunc, "$") {
+					baseID = strings.ReplaceAll(route.HandlerFunc, ".", "_")
+				}
+				operation.Summary, _, _ = strings.Cut(strings.TrimSpace(route.HandlerInfo.Doc), "\n")
+				operation.Tags = []string{path.Base(route.HandlerPkg)}
+			}
+			operationIDs[baseID]++
+			operation.OperationID = baseID
+			if count := operationIDs[baseID]; count > 1 {
+				operation.OperationID = fmt.Sprintf("%s_%d", baseID, count)
+			}
+			for _, param := range params {
+				operation.Parameters = append(operation.Parameters, &OpenAPIParameter{
+					Name:     param,
+					In:       "path",
+					Required: true,
+					Schema:   map[string]string{"type": "string"},
+				})
+			}
+			operations[method] = operation
+		}
+	}
+	return doc
+}
+
+// openAPIPathParams 将:id、*path、{id:[0-9]+}、{path...}等路径参数转换为{name}，并返回参数名
+func openAPIPathParams(routePath string) (string, []string) {
+	segments := strings.Split(routePath, "/")
+	params := make([]string, 0)
+	for i, segment := range segments {
+		name := ""
+		switch {
+		case strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") && len(segment) > 1:
+			name = segment[1:]
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			name, _, _ = strings.Cut(segment[1:len(segment)-1], ":")
+			name = strings.TrimSuffix(name, "...")
+		case segment == "*":
+			name = "wildcard"
+		}
+		if name != "" {
+			segments[i] = "{" + name + "}"
+			params = append(params, name)
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// openAPIIdentifier 将路径转换为驼峰标识符，用于没有具名handler时的operationId
+func openAPIIdentifier(openAPIPath string) string {
+	var builder strings.Builder
+	upper := true
+	for _, r := range openAPIPath {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			if upper && r >= 'a' && r <= 'z' {
+				r -= 'a' - 'A'
+			}
+			builder.WriteRune(r)
+			upper = false
+			continue
+		}
+		upper = true
+	}
+	if builder.Len() == 0 {
+		return "Root"
+	}
+	return builder.String()
+}
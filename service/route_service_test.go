@@ -0,0 +1,155 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiscoverRoutes(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"handler/user.go": `package handler
+
+import "github.com/gin-gonic/gin"
+
+const UserPrefix = "/users"
+
+type UserHandler struct{}
+
+// Get 查询用户
+// 返回用户详情
+func (h *UserHandler) Get(c *gin.Context) {}
+
+func (h *UserHandler) Create(c *gin.Context) {}
+
+func Auth() gin.HandlerFunc { return nil }
+`,
+		"router/gin.go": `package router
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"example.com/app/handler"
+)
+
+func NewEngine() *gin.Engine {
+	r := gin.Default()
+	r.GET("/ping", func(c *gin.Context) {})
+	api := r.Group("/api/v1", handler.Auth())
+	registerUsers(api)
+	return r
+}
+
+func registerUsers(g *gin.RouterGroup) {
+	h := &handler.UserHandler{}
+	users := g.Group(handler.UserPrefix)
+	users.Use(gin.Logger())
+	users.GET("/:id", h.Get)
+	users.Match([]string{"PUT", "PATCH"}, "/:id", limit(), h.Create)
+}
+
+func limit() gin.HandlerFunc { return nil }
+`,
+		"router/chi.go": `package router
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func NewChi() http.Handler {
+	r := chi.NewRouter()
+	r.Use(logger)
+	admin := chi.NewRouter()
+	admin.Get("/stats/{name:[a-z]+}", stats)
+	r.Route("/files", func(r chi.Router) {
+		r.With(auth).Post("/*", upload)
+	})
+	r.Mount("/admin", admin)
+	return r
+}
+
+func logger(next http.Handler) http.Handler { return next }
+
+func auth(next http.Handler) http.Handler { return next }
+
+func stats(w http.ResponseWriter, r *http.Request) {}
+
+func upload(w http.ResponseWriter, r *http.Request) {}
+`,
+		"main.go": `package main
+
+import "net/http"
+
+func health(w http.ResponseWriter, r *http.Request) {}
+
+func main() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET example.com/items/{id}", health)
+	http.Handle("/metrics", http.HandlerFunc(health))
+}
+`,
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes, err := DiscoverRoutes(modInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]*Route)
+	for _, route := range routes {
+		got[route.Method+" "+route.Path] = route
+	}
+	want := map[string]struct {
+		handler     string
+		middlewares string
+	}{
+		"GET /ping":                      {"NewEngine$1", "gin.Logger(),gin.Recovery()"},
+		"GET /api/v1/users/:id":          {"UserHandler.Get", "gin.Logger(),gin.Recovery(),handler.Auth(),gin.Logger()"},
+		"PUT /api/v1/users/:id":          {"UserHandler.Create", "gin.Logger(),gin.Recovery(),handler.Auth(),gin.Logger(),limit()"},
+		"PATCH /api/v1/users/:id":        {"UserHandler.Create", "gin.Logger(),gin.Recovery(),handler.Auth(),gin.Logger(),limit()"},
+		"GET /admin/stats/{name:[a-z]+}": {"stats", "logger"},
+		"POST /files/*":                  {"upload", "logger,auth"},
+		"GET /items/{id}":                {"health", ""},
+		"ANY /metrics":                   {"health", ""},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("期望%d条路由，实际%d条", len(want), len(routes))
+	}
+	for key, expected := range want {
+		route, ok := got[key]
+		if !ok {
+			t.Fatalf("缺少路由%s", key)
+		}
+		if route.HandlerFunc != expected.handler || route.HandlerInfo == nil {
+			t.Errorf("%s的handler为%s，期望%s", key, route.HandlerFunc, expected.handler)
+		}
+		if middlewares := strings.Join(route.Middlewares, ","); middlewares != expected.middlewares {
+			t.Errorf("%s的中间件为%s，期望%s", key, middlewares, expected.middlewares)
+		}
+	}
+	if route := got["GET /api/v1/users/:id"]; route.Register != "example.com/app/router.registerUsers" || route.RFilePath != "router/gin.go" || route.Line != 21 {
+		t.Errorf("注册位置错误: %s %s:%d", route.Register, route.RFilePath, route.Line)
+	}
+
+	doc := BuildOpenAPISkeleton(routes, "app", "1.0.0")
+	operation := doc.Paths["/api/v1/users/{id}"]["get"]
+	if operation == nil || operation.OperationID != "UserHandler_Get" || operation.Summary != "Get 查询用户" {
+		t.Fatalf("OpenAPI操作错误: %+v", operation)
+	}
+	if len(operation.Parameters) != 1 || operation.Parameters[0].Name != "id" || operation.Tags[0] != "handler" {
+		t.Errorf("OpenAPI参数错误: %+v", operation.Parameters)
+	}
+	if doc.Paths["/api/v1/users/{id}"]["put"].OperationID != "UserHandler_Create_2" {
+		t.Errorf("operationId未去重: %s", doc.Paths["/api/v1/users/{id}"]["put"].OperationID)
+	}
+	if _, ok := doc.Paths["/admin/stats/{name}"]["get"]; !ok {
+		t.Errorf("正则路径参数未转换")
+	}
+	if len(doc.Paths["/metrics"]) != 7 {
+		t.Errorf("ANY路由应展开为7个方法，实际%d个", len(doc.Paths["/metrics"]))
+	}
+}
@@ -0,0 +1,562 @@
+package service
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+)
+
+const (
+	RPCFrameworkGRPC  = "grpc"
+	RPCFrameworkKitex = "kitex"
+
+	RPCStreamNone   = ""
+	RPCStreamClient = "client"
+	RPCStreamServer = "server"
+	RPCStreamBidi   = "bidi"
+
+	// rpcMaxDepth 追踪变量赋值和嵌入字段的最大深度
+	rpcMaxDepth = 5
+)
+
+var (
+	grpcRegisterRegexp    = regexp.MustCompile(`^Register(\w+)Server$`)
+	grpcServiceNameRegexp = regexp.MustCompile(`ServiceName:\s*"([^"]+)"`)
+	kitexServiceRegexp    = regexp.MustCompile(`serviceName\s*:?=\s*"([^"]+)"`)
+	// grpcGenericStreamRegexp protoc-gen-go-grpc v1.5起流式方法使用泛型流类型
+	grpcGenericStreamRegexp = regexp.MustCompile(`(Bidi|Client|Server)StreamingServer\[([^\]]*)\]`)
+)
+
+// kitexServerImport kitex生成的服务端代码引用的包
+const kitexServerImport = "github.com/cloudwego/kitex/server"
+
+// RPCService 生成代码中的RPC服务
+type RPCService struct {
+	Framework     string             `json:"framework"`     // grpc或kitex
+	Name          string             `json:"name"`          // 服务名，gRPC为ServiceDesc中的全名，如helloworld.Greeter
+	Interface     string             `json:"interface"`     // 服务接口，包路径.接口名
+	Methods       []*RPCMethod       `json:"methods"`       // 按接口声明顺序排列的RPC方法
+	Registrations []*RPCRegistration `json:"registrations"` // 模块内注册服务的调用
+	InterfaceInfo *vs.StructInfo     `json:"-"`
+}
+
+// RPCMethod 一个RPC方法及其实现
+type RPCMethod struct {
+	Name         string         `json:"name"`               // 方法名
+	FullName     string         `json:"full_name"`          // gRPC为/服务名/方法名，kitex为服务名.方法名
+	Request      string         `json:"request,omitempty"`  // 请求类型，多个参数时以逗号分隔
+	Response     string         `json:"response,omitempty"` // 响应类型
+	Stream       string         `json:"stream,omitempty"`   // 流式类型：client、server或bidi
+	Impls        []*RPCImpl     `json:"impls"`              // 实现该方法的函数
+	MethodInfo   *vs.FuncInfo   `json:"-"`
+	RequestInfo  *vs.StructInfo `json:"-"`
+	ResponseInfo *vs.StructInfo `json:"-"`
+}
+
+// RPCImpl RPC方法的实现
+type RPCImpl struct {
+	Type          string       `json:"type"`                    // 实现类型，包路径.类型名
+	Func          string       `json:"func"`                    // 实现方法，包路径.Type.Method
+	Unimplemented bool         `json:"unimplemented,omitempty"` // 由生成的Unimplemented类型兜底实现
+	Inferred      bool         `json:"inferred,omitempty"`      // 未找到注册调用，按方法集推断的实现
+	FuncInfo      *vs.FuncInfo `json:"-"`
+}
+
+// RPCRegistration 注册服务的调用
+type RPCRegistration struct {
+	Register  string `json:"register"`       // 发起注册的函数
+	Call      string `json:"call"`           // 注册函数，如pb.RegisterGreeterServer
+	Impl      string `json:"impl,omitempty"` // 实现类型，无法确定时为空
+	RFilePath string `json:"file"`           // 注册调用所在的文件
+	Line      int    `json:"line"`           // 注册调用的行号
+}
+
+// rpcRegisterFunc 生成代码中的服务注册函数
+type rpcRegisterFunc struct {
+	service *RPCService
+	implArg int // 实现参数的位置
+}
+
+type rpcDiscovery struct {
+	modInfo   *ModuleInfo
+	structs   map[string]*vs.StructInfo          // 包路径.类型名 -> 类型
+	funcs     map[string]*vs.FuncInfo            // 包路径.函数名 -> 函数
+	methods   map[string]map[string]*vs.FuncInfo // 包路径.类型名 -> 方法名 -> 方法
+	registers map[string]*rpcRegisterFunc
+	services  map[string]*RPCService // 框架:接口 -> 服务
+}
+
+// DiscoverRPCServices 从模块内生成的gRPC和Kitex代码中发现服务接口和注册函数，再扫描注册调用确定实现类型，
+// 将每个RPC方法关联到实现方法以及请求、响应结构体；找不到注册调用的服务按方法集推断实现
+func DiscoverRPCServices(modInfo *ModuleInfo) ([]*RPCService, error) {
+	d := &rpcDiscovery{
+		modInfo:   modInfo,
+		structs:   make(map[string]*vs.StructInfo),
+		funcs:     make(map[string]*vs.FuncInfo),
+		methods:   make(map[string]map[string]*vs.FuncInfo),
+		registers: make(map[string]*rpcRegisterFunc),
+		services:  make(map[string]*RPCService),
+	}
+	for pkg, structs := range modInfo.PkgStructMap {
+		for _, structInfo := range structs {
+			d.structs[pkg+"."+structInfo.Name] = structInfo
+		}
+	}
+	for pkg, funcs := range modInfo.PkgFuncMap {
+		for _, funcInfo := range funcs {
+			if strings.Contains(funcInfo.Name, "$") {
+				continue
+			}
+			if funcInfo.Receiver == nil {
+				d.funcs[pkg+"."+funcInfo.Name] = funcInfo
+				continue
+			}
+			typeKey := pkg + "." + receiverTypeName(funcInfo.Receiver)
+			if d.methods[typeKey] == nil {
+				d.methods[typeKey] = make(map[string]*vs.FuncInfo)
+			}
+			d.methods[typeKey][funcInfo.Name] = funcInfo
+		}
+	}
+	for _, key := range sortedMapKeys(d.funcs) {
+		d.collectRegisterFunc(d.funcs[key])
+	}
+	if err := d.scanRegistrations(); err != nil {
+		return nil, err
+	}
+	services := make([]*RPCService, 0, len(d.services))
+	for _, key := range sortedMapKeys(d.services) {
+		service := d.services[key]
+		d.linkImpls(service)
+		services = append(services, service)
+	}
+	sort.SliceStable(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+	return services, nil
+}
+
+// collectRegisterFunc 识别gRPC的RegisterXxxServer以及kitex的NewServer、RegisterService
+func (d *rpcDiscovery) collectRegisterFunc(funcInfo *vs.FuncInfo) {
+	framework, implArg := "", -1
+	if match := grpcRegisterRegexp.FindStringSubmatch(funcInfo.Name); match != nil && len(funcInfo.Params) >= 2 {
+		if qualifyRPCType(funcInfo.Pkg, funcInfo.Params[1].BaseType) == funcInfo.Pkg+"."+match[1]+"Server" {
+			framework, implArg = RPCFrameworkGRPC, 1
+		}
+	} else if slices.Contains(funcInfo.Imports, kitexServerImport) {
+		switch {
+		case funcInfo.Name == "NewServer" && len(funcInfo.Params) >= 1:
+			framework, implArg = RPCFrameworkKitex, 0
+		case funcInfo.Name == "RegisterService" && len(funcInfo.Params) >= 2:
+			framework, implArg = RPCFrameworkKitex, 1
+		}
+	}
+	if framework == "" {
+		return
+	}
+	ifaceKey := qualifyRPCType(funcInfo.Pkg, funcInfo.Params[implArg].BaseType)
+	iface, ok := d.structs[ifaceKey]
+	if !ok || !iface.IsInterface {
+		return
+	}
+	serviceKey := framework + ":" + ifaceKey
+	service, ok := d.services[serviceKey]
+	if !ok {
+		service = &RPCService{
+			Framework:     framework,
+			Name:          d.serviceName(framework, funcInfo.Pkg, iface),
+			Interface:     ifaceKey,
+			Registrations: make([]*RPCRegistration, 0),
+			InterfaceInfo: iface,
+		}
+		service.Methods = d.serviceMethods(service)
+		d.services[serviceKey] = service
+	}
+	d.registers[funcInfo.Pkg+"."+funcInfo.Name] = &rpcRegisterFunc{service: service, implArg: implArg}
+}
+
+// serviceName gRPC从ServiceDesc中读取服务全名，kitex从生成的serviceInfo中读取，找不到时使用接口名
+func (d *rpcDiscovery) serviceName(framework, registerPkg string, iface *vs.StructInfo) string {
+	if framework == RPCFrameworkGRPC {
+		name := strings.TrimSuffix(iface.Name, "Server")
+		for _, varInfo := range d.modInfo.PkgVarMap[registerPkg] {
+			if varInfo.Name != name+"_ServiceDesc" && varInfo.Name != "_"+name+"_serviceDesc" {
+				continue
+			}
+			if match := grpcServiceNameRegexp.FindStringSubmatch(varInfo.Content); match != nil {
+				return match[1]
+			}
+		}
+		return name
+	}
+	for _, funcInfo := range d.modInfo.PkgFuncMap[registerPkg] {
+		if match := kitexServiceRegexp.FindStringSubmatch(funcInfo.Content); match != nil {
+			return match[1]
+		}
+	}
+	return iface.Name
+}
+
+// serviceMethods 解析接口中导出的方法，区分普通调用和流式调用
+func (d *rpcDiscovery) serviceMethods(service *RPCService) []*RPCMethod {
+	iface := service.InterfaceInfo
+	methods := make([]*RPCMethod, 0, len(iface.Methods))
+	for _, methodInfo := range iface.Methods {
+		if !token.IsExported(methodInfo.Name) {
+			continue
+		}
+		method := &RPCMethod{Name: methodInfo.Name, Impls: make([]*RPCImpl, 0), MethodInfo: methodInfo}
+		if service.Framework == RPCFrameworkGRPC {
+			method.FullName = "/" + service.Name + "/" + methodInfo.Name
+		} else {
+			method.FullName = service.Name + "." + methodInfo.Name
+		}
+		params, results := methodInfo.Params, methodInfo.Results
+		if len(params) > 0 && params[0].BaseType == "context.Context" {
+			method.Request = d.rpcTypes(iface.Pkg, params[1:])
+			if len(results) > 1 {
+				method.Response = d.rpcTypes(iface.Pkg, results[:len(results)-1])
+			}
+		} else if len(params) > 0 {
+			var request string
+			method.Stream, request, method.Response = d.streamTypes(iface.Pkg, params[len(params)-1])
+			if method.Stream == RPCStreamServer || method.Stream == RPCStreamNone {
+				request = d.rpcTypes(iface.Pkg, params[:len(params)-1])
+			}
+			method.Request = request
+		}
+		method.RequestInfo, method.ResponseInfo = d.structs[method.Request], d.structs[method.Response]
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+// streamTypes 根据流参数判断流式类型，返回流式类型以及流中的请求、响应类型
+func (d *rpcDiscovery) streamTypes(pkg string, stream *vs.VarInfo) (string, string, string) {
+	content := stream.Type
+	streamInfo, ok := d.structs[qualifyRPCType(pkg, stream.BaseType)]
+	if ok && !streamInfo.IsInterface {
+		content = streamInfo.Content
+	}
+	if match := grpcGenericStreamRegexp.FindStringSubmatch(content); match != nil {
+		args := strings.Split(match[2], ",")
+		for i := range args {
+			args[i] = qualifyRPCType(pkg, strings.TrimPrefix(strings.TrimSpace(args[i]), "*"))
+		}
+		switch {
+		case match[1] == "Server":
+			return RPCStreamServer, "", args[0]
+		case len(args) == 2:
+			return strings.ToLower(match[1]), args[0], args[1]
+		}
+	}
+	if !ok || !streamInfo.IsInterface {
+		return RPCStreamNone, "", ""
+	}
+	var request, response string
+	hasSend, hasSendAndClose := false, false
+	for _, methodInfo := range streamInfo.Methods {
+		switch {
+		case methodInfo.Name == "Recv" && len(methodInfo.Results) > 0:
+			request = d.rpcTypes(streamInfo.Pkg, methodInfo.Results[:1])
+		case methodInfo.Name == "Send" && len(methodInfo.Params) > 0:
+			hasSend, response = true, d.rpcTypes(streamInfo.Pkg, methodInfo.Params[:1])
+		case methodInfo.Name == "SendAndClose" && len(methodInfo.Params) > 0:
+			hasSendAndClose, response = true, d.rpcTypes(streamInfo.Pkg, methodInfo.Params[:1])
+		}
+	}
+	switch {
+	case hasSendAndClose:
+		return RPCStreamClient, request, response
+	case hasSend && request != "":
+		return RPCStreamBidi, request, response
+	case hasSend:
+		return RPCStreamServer, "", response
+	}
+	return RPCStreamNone, "", ""
+}
+
+// rpcTypes 将参数类型转换为带包路径的类型名，多个参数以逗号分隔
+func (d *rpcDiscovery) rpcTypes(pkg string, vars []*vs.VarInfo) string {
+	names := make([]string, 0, len(vars))
+	for _, varInfo := range vars {
+		names = append(names, qualifyRPCType(pkg, varInfo.BaseType))
+	}
+	return strings.Join(names, ", ")
+}
+
+// qualifyRPCType 同包类型补全包路径，导入类型的BaseType已经包含包路径
+func qualifyRPCType(pkg, baseType string) string {
+	if baseType == "" || strings.Contains(baseType, ".") || types.Universe.Lookup(baseType) != nil {
+		return baseType
+	}
+	return pkg + "." + baseType
+}
+
+// scanRegistrations 扫描模块内对注册函数的调用，确定实现类型
+func (d *rpcDiscovery) scanRegistrations() error {
+	if len(d.registers) == 0 {
+		return nil
+	}
+	fileSet := token.NewFileSet()
+	files, err := parseModuleGoFiles(d.modInfo, fileSet)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		pkg := file.pkg
+		resolver := &rpcResolver{d: d, pkg: pkg, imports: file.imports}
+		for _, decl := range file.file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Body == nil {
+				continue
+			}
+			register := pkg + "." + funcDecl.Name.Name
+			if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
+				register = pkg + "." + recvExprName(funcDecl.Recv.List[0].Type) + "." + funcDecl.Name.Name
+			}
+			// 参数按其声明类型记录，便于解析以参数传入的实现
+			resolver.vars = make(map[string]ast.Expr)
+			for _, field := range funcDecl.Type.Params.List {
+				for _, name := range field.Names {
+					resolver.vars[name.Name] = &ast.CompositeLit{Type: field.Type}
+				}
+			}
+			ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+				switch n := node.(type) {
+				case *ast.AssignStmt:
+					if len(n.Lhs) == len(n.Rhs) {
+						for i, lhs := range n.Lhs {
+							if ident, ok := lhs.(*ast.Ident); ok {
+								resolver.vars[ident.Name] = n.Rhs[i]
+							}
+						}
+					}
+				case *ast.ValueSpec:
+					for i, name := range n.Names {
+						if len(n.Values) == len(n.Names) {
+							resolver.vars[name.Name] = n.Values[i]
+						} else if n.Type != nil {
+							resolver.vars[name.Name] = &ast.CompositeLit{Type: n.Type}
+						}
+					}
+				case *ast.CallExpr:
+					registerFunc, call := resolver.registerFunc(n.Fun)
+					if registerFunc == nil || len(n.Args) <= registerFunc.implArg {
+						return true
+					}
+					registration := &RPCRegistration{
+						Register:  register,
+						Call:      call,
+						Impl:      resolver.implType(n.Args[registerFunc.implArg], 0),
+						RFilePath: file.rFilePath,
+						Line:      fileSet.Position(n.Pos()).Line,
+					}
+					if iface, ok := d.structs[registration.Impl]; ok && iface.IsInterface {
+						registration.Impl = ""
+					}
+					registerFunc.service.Registrations = append(registerFunc.service.Registrations, registration)
+				}
+				return true
+			})
+		}
+	}
+	return nil
+}
+
+type rpcResolver struct {
+	d       *rpcDiscovery
+	pkg     string
+	imports map[string]string
+	vars    map[string]ast.Expr
+}
+
+// registerFunc 判断调用的函数是否为生成的注册函数
+func (r *rpcResolver) registerFunc(fun ast.Expr) (*rpcRegisterFunc, string) {
+	switch e := fun.(type) {
+	case *ast.Ident:
+		return r.d.registers[r.pkg+"."+e.Name], e.Name
+	case *ast.SelectorExpr:
+		if ident, ok := e.X.(*ast.Ident); ok {
+			if importPath, ok := r.imports[ident.Name]; ok {
+				return r.d.registers[importPath+"."+e.Sel.Name], ident.Name + "." + e.Sel.Name
+			}
+		}
+	}
+	return nil, ""
+}
+
+// implType 推断实现参数的类型，支持字面量、new、构造函数以及局部变量
+func (r *rpcResolver) implType(expr ast.Expr, depth int) string {
+	if depth > rpcMaxDepth {
+		return ""
+	}
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return r.implType(e.X, depth+1)
+	case *ast.UnaryExpr:
+		return r.implType(e.X, depth+1)
+	case *ast.StarExpr:
+		return r.typeName(e.X)
+	case *ast.CompositeLit:
+		return r.typeName(e.Type)
+	case *ast.Ident:
+		if value, ok := r.vars[e.Name]; ok {
+			return r.implType(value, depth+1)
+		}
+	case *ast.CallExpr:
+		if ident, ok := e.Fun.(*ast.Ident); ok && ident.Name == "new" && len(e.Args) == 1 {
+			return r.typeName(e.Args[0])
+		}
+		funcInfo := r.d.funcs[r.typeName(e.Fun)]
+		if funcInfo != nil && len(funcInfo.Results) > 0 {
+			return qualifyRPCType(funcInfo.Pkg, funcInfo.Results[0].BaseType)
+		}
+	}
+	return ""
+}
+
+// typeName 将类型或函数表达式转换为包路径.名称
+func (r *rpcResolver) typeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return r.typeName(e.X)
+	case *ast.Ident:
+		return qualifyRPCType(r.pkg, e.Name)
+	case *ast.SelectorExpr:
+		if ident, ok := e.X.(*ast.Ident); ok {
+			if importPath, ok := r.imports[ident.Name]; ok {
+				return importPath + "." + e.Sel.Name
+			}
+		}
+	case *ast.IndexExpr:
+		return r.typeName(e.X)
+	case *ast.IndexListExpr:
+		return r.typeName(e.X)
+	}
+	return ""
+}
+
+// linkImpls 将注册的实现类型关联到每个方法，没有可确定的实现时按方法集推断
+func (d *rpcDiscovery) linkImpls(service *RPCService) {
+	implTypes := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, registration := range service.Registrations {
+		if registration.Impl != "" && !seen[registration.Impl] {
+			seen[registration.Impl] = true
+			implTypes = append(implTypes, registration.Impl)
+		}
+	}
+	inferred := len(implTypes) == 0
+	if inferred {
+		implTypes = d.inferImplTypes(service)
+	}
+	for _, method := range service.Methods {
+		for _, implType := range implTypes {
+			funcInfo := d.findMethod(implType, method.Name, 0)
+			if funcInfo == nil {
+				continue
+			}
+			method.Impls = append(method.Impls, &RPCImpl{
+				Type:          implType,
+				Func:          funcInfo.Pkg + "." + skeletonDisplayName(funcInfo),
+				Unimplemented: strings.HasPrefix(receiverTypeName(funcInfo.Receiver), "Unimplemented"),
+				Inferred:      inferred,
+				FuncInfo:      funcInfo,
+			})
+		}
+	}
+}
+
+// findMethod 查找类型的方法，包括通过嵌入字段提升的方法
+func (d *rpcDiscovery) findMethod(typeKey, name string, depth int) *vs.FuncInfo {
+	if funcInfo, ok := d.methods[typeKey][name]; ok {
+		return funcInfo
+	}
+	structInfo, ok := d.structs[typeKey]
+	if !ok || structInfo.IsInterface || depth > rpcMaxDepth {
+		return nil
+	}
+	for _, field := range structInfo.Fields {
+		if field.Name != "_" {
+			continue
+		}
+		if funcInfo := d.findMethod(qualifyRPCType(structInfo.Pkg, field.BaseType), name, depth+1); funcInfo != nil {
+			return funcInfo
+		}
+	}
+	return nil
+}
+
+// inferImplTypes 查找自身声明了服务全部方法且签名一致的类型，忽略生成的Unimplemented和Unsafe类型，
+// 签名比较可以排除多了CallOption参数的生成客户端
+func (d *rpcDiscovery) inferImplTypes(service *RPCService) []string {
+	if len(service.Methods) == 0 {
+		return nil
+	}
+	result := make([]string, 0)
+	for _, typeKey := range sortedMapKeys(d.methods) {
+		typeName := typeKey[strings.LastIndex(typeKey, ".")+1:]
+		if strings.HasPrefix(typeName, "Unimplemented") || strings.HasPrefix(typeName, "Unsafe") {
+			continue
+		}
+		matched := true
+		for _, method := range service.Methods {
+			if funcInfo, ok := d.methods[typeKey][method.Name]; !ok || !rpcSignatureMatch(method.MethodInfo, funcInfo) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, typeKey)
+		}
+	}
+	return result
+}
+
+// rpcSignatureMatch 比较接口方法与实现方法的参数和结果类型，包内类型补全包路径后比较
+func rpcSignatureMatch(ifaceMethod, funcInfo *vs.FuncInfo) bool {
+	if len(ifaceMethod.Params) != len(funcInfo.Params) || len(ifaceMethod.Results) != len(funcInfo.Results) {
+		return false
+	}
+	for i, param := range ifaceMethod.Params {
+		if rpcQualifiedType(param) != rpcQualifiedType(funcInfo.Params[i]) {
+			return false
+		}
+	}
+	for i, result := range ifaceMethod.Results {
+		if rpcQualifiedType(result) != rpcQualifiedType(funcInfo.Results[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// rpcQualifiedType 返回参数的完整类型，包内声明的类型补全包路径
+func rpcQualifiedType(varInfo *vs.VarInfo) string {
+	qualified := qualifyRPCType(varInfo.Pkg, varInfo.BaseType)
+	if qualified == varInfo.BaseType {
+		return varInfo.Type
+	}
+	return strings.Replace(varInfo.Type, varInfo.BaseType, qualified, 1)
+}
+
+// FindRPCMethod 按方法名查找RPC方法，支持/pkg.Service/Method、pkg.Service.Method和Service.Method
+func FindRPCMethod(services []*RPCService, name string) (*RPCService, *RPCMethod) {
+	name = strings.ReplaceAll(strings.TrimPrefix(name, "/"), "/", ".")
+	for _, service := range services {
+		for _, method := range service.Methods {
+			fullName := service.Name + "." + method.Name
+			if fullName == name || strings.HasSuffix(fullName, "."+name) {
+				return service, method
+			}
+		}
+	}
+	return nil, nil
+}
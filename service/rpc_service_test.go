@@ -0,0 +1,198 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestDiscoverRPCServices(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"pb/greeter.pb.go": `package pb
+
+type HelloRequest struct {
+	Name string
+}
+
+type HelloReply struct {
+	Message string
+}
+`,
+		"pb/greeter_grpc.pb.go": `package pb
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+)
+
+type GreeterServer interface {
+	SayHello(context.Context, *HelloRequest) (*HelloReply, error)
+	ListHello(*HelloRequest, grpc.ServerStreamingServer[HelloReply]) error
+	Chat(Greeter_ChatServer) error
+	mustEmbedUnimplementedGreeterServer()
+}
+
+type UnimplementedGreeterServer struct{}
+
+func (UnimplementedGreeterServer) SayHello(context.Context, *HelloRequest) (*HelloReply, error) {
+	return nil, nil
+}
+func (UnimplementedGreeterServer) ListHello(*HelloRequest, grpc.ServerStreamingServer[HelloReply]) error {
+	return nil
+}
+func (UnimplementedGreeterServer) Chat(Greeter_ChatServer) error             { return nil }
+func (UnimplementedGreeterServer) mustEmbedUnimplementedGreeterServer() {}
+
+type Greeter_ChatServer interface {
+	Send(*HelloReply) error
+	Recv() (*HelloRequest, error)
+	grpc.ServerStream
+}
+
+func RegisterGreeterServer(s grpc.ServiceRegistrar, srv GreeterServer) {
+	s.RegisterService(&Greeter_ServiceDesc, srv)
+}
+
+type GreeterClient interface {
+	SayHello(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (*HelloReply, error)
+}
+
+type greeterClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func (c *greeterClient) SayHello(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (*HelloReply, error) {
+	return nil, nil
+}
+
+var Greeter_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "helloworld.Greeter",
+	HandlerType: (*GreeterServer)(nil),
+}
+`,
+		"kitex_gen/echo/echo.go": `package echo
+
+import "context"
+
+type Request struct{ Msg string }
+
+type Response struct{ Msg string }
+
+type EchoService interface {
+	Echo(ctx context.Context, req *Request) (r *Response, err error)
+}
+`,
+		"kitex_gen/echo/echoservice/server.go": `package echoservice
+
+import (
+	"context"
+
+	callopt "github.com/cloudwego/kitex/client/callopt"
+	server "github.com/cloudwego/kitex/server"
+
+	echo "example.com/app/kitex_gen/echo"
+)
+
+func NewServer(handler echo.EchoService, opts ...server.Option) server.Server {
+	return nil
+}
+
+// kClient 生成的客户端，方法名与服务相同但多了callOptions参数
+type kClient struct{}
+
+func (p *kClient) Echo(ctx context.Context, req *echo.Request, callOptions ...callopt.Option) (r *echo.Response, err error) {
+	return nil, nil
+}
+
+func serviceInfo() {
+	serviceName := "EchoService"
+	_ = serviceName
+}
+`,
+		"server/greeter.go": `package server
+
+import (
+	"context"
+
+	"example.com/app/pb"
+)
+
+type greeter struct {
+	pb.UnimplementedGreeterServer
+}
+
+func NewGreeter() *greeter { return &greeter{} }
+
+func (g *greeter) SayHello(ctx context.Context, req *pb.HelloRequest) (*pb.HelloReply, error) {
+	return nil, nil
+}
+
+func Register(s *grpc.Server) {
+	impl := NewGreeter()
+	pb.RegisterGreeterServer(s, impl)
+}
+`,
+		"handler.go": `package main
+
+import (
+	"context"
+
+	"example.com/app/kitex_gen/echo"
+)
+
+type EchoServiceImpl struct{}
+
+func (s *EchoServiceImpl) Echo(ctx context.Context, req *echo.Request) (*echo.Response, error) {
+	return nil, nil
+}
+`,
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	services, err := DiscoverRPCServices(modInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 2 || services[0].Name != "EchoService" || services[1].Name != "helloworld.Greeter" {
+		t.Fatalf("服务发现错误: %+v", services)
+	}
+	greeter := services[1]
+	if greeter.Framework != RPCFrameworkGRPC || len(greeter.Methods) != 3 || len(greeter.Registrations) != 1 {
+		t.Fatalf("gRPC服务错误: %+v", greeter)
+	}
+	if registration := greeter.Registrations[0]; registration.Impl != "example.com/app/server.greeter" || registration.Register != "example.com/app/server.Register" || registration.Line != 21 {
+		t.Errorf("注册调用错误: %+v", registration)
+	}
+	service, method := FindRPCMethod(services, "/helloworld.Greeter/SayHello")
+	if service != greeter || method.Request != "example.com/app/pb.HelloRequest" || method.Response != "example.com/app/pb.HelloReply" || method.RequestInfo == nil {
+		t.Fatalf("SayHello解析错误: %+v", method)
+	}
+	if len(method.Impls) != 1 || method.Impls[0].Func != "example.com/app/server.greeter.SayHello" || method.Impls[0].Unimplemented {
+		t.Errorf("SayHello实现错误: %+v", method.Impls)
+	}
+	_, method = FindRPCMethod(services, "Greeter.ListHello")
+	if method.Stream != RPCStreamServer || method.Request != "example.com/app/pb.HelloRequest" || method.Response != "example.com/app/pb.HelloReply" {
+		t.Errorf("ListHello解析错误: %+v", method)
+	}
+	if len(method.Impls) != 1 || !method.Impls[0].Unimplemented {
+		t.Errorf("ListHello应由Unimplemented类型实现: %+v", method.Impls)
+	}
+	_, method = FindRPCMethod(services, "Greeter.Chat")
+	if method.Stream != RPCStreamBidi || method.Request != "example.com/app/pb.HelloRequest" || method.Response != "example.com/app/pb.HelloReply" {
+		t.Errorf("Chat解析错误: %+v", method)
+	}
+
+	echoService := services[0]
+	if echoService.Framework != RPCFrameworkKitex || len(echoService.Registrations) != 0 {
+		t.Fatalf("kitex服务错误: %+v", echoService)
+	}
+	_, method = FindRPCMethod(services, "EchoService.Echo")
+	if method.FullName != "EchoService.Echo" || method.ResponseInfo == nil || len(method.Impls) != 1 {
+		t.Fatalf("Echo解析错误: %+v", method)
+	}
+	if impl := method.Impls[0]; impl.Func != "example.com/app.EchoServiceImpl.Echo" || !impl.Inferred {
+		t.Errorf("Echo实现错误: %+v", impl)
+	}
+}
@@ -0,0 +1,534 @@
+package service
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+)
+
+const (
+	SQLLibraryDatabaseSQL = "database/sql"
+	SQLLibrarySqlx        = "sqlx"
+	SQLLibraryGorm        = "gorm"
+)
+
+// sqlImportLibraries 库的导入路径
+var sqlImportLibraries = map[string]string{
+	"database/sql":            SQLLibraryDatabaseSQL,
+	"github.com/jmoiron/sqlx": SQLLibrarySqlx,
+	"gorm.io/gorm":            SQLLibraryGorm,
+	"github.com/jinzhu/gorm":  SQLLibraryGorm,
+}
+
+// sqlMethods 执行SQL的方法及SQL参数的位置，sqlx的包级函数比同名方法多一个Queryer/Execer参数
+var sqlMethods = map[string]map[string]int{
+	SQLLibraryDatabaseSQL: {
+		"Query": 0, "QueryContext": 1, "QueryRow": 0, "QueryRowContext": 1,
+		"Exec": 0, "ExecContext": 1, "Prepare": 0, "PrepareContext": 1,
+	},
+	SQLLibrarySqlx: {
+		"Queryx": 0, "QueryxContext": 1, "QueryRowx": 0, "QueryRowxContext": 1,
+		"Get": 1, "GetContext": 2, "Select": 1, "SelectContext": 2,
+		"MustExec": 0, "MustExecContext": 1, "NamedExec": 0, "NamedExecContext": 1,
+		"NamedQuery": 0, "NamedQueryContext": 1, "Preparex": 0, "PreparexContext": 1,
+		"PrepareNamed": 0, "PrepareNamedContext": 1,
+	},
+	SQLLibraryGorm: {"Raw": 0, "Exec": 0},
+}
+
+// sqlStatementKeywords 可以作为SQL语句开头的关键字
+var sqlStatementKeywords = map[string]bool{
+	"SELECT": true, "INSERT": true, "UPDATE": true, "DELETE": true, "REPLACE": true, "MERGE": true, "UPSERT": true,
+	"WITH": true, "CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true,
+	"SHOW": true, "DESCRIBE": true, "DESC": true, "EXPLAIN": true, "CALL": true, "EXEC": true, "EXECUTE": true,
+	"GRANT": true, "REVOKE": true, "SET": true, "BEGIN": true, "START": true, "COMMIT": true, "ROLLBACK": true,
+	"SAVEPOINT": true, "LOCK": true, "UNLOCK": true, "VACUUM": true, "ANALYZE": true, "PRAGMA": true, "USE": true, "COPY": true,
+}
+
+// sqlReservedWords 不会作为表名的关键字
+var sqlReservedWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true,
+	"FULL": true, "OUTER": true, "CROSS": true, "NATURAL": true, "ON": true, "USING": true, "AS": true,
+	"GROUP": true, "ORDER": true, "BY": true, "HAVING": true, "LIMIT": true, "OFFSET": true, "UNION": true,
+	"EXCEPT": true, "INTERSECT": true, "INTO": true, "VALUES": true, "VALUE": true, "SET": true, "UPDATE": true,
+	"DELETE": true, "INSERT": true, "TABLE": true, "IF": true, "NOT": true, "EXISTS": true, "LATERAL": true,
+	"ONLY": true, "AND": true, "OR": true, "WINDOW": true, "RETURNING": true, "FOR": true,
+	"DEFAULT": true, "DUAL": true, "IGNORE": true, "LOW_PRIORITY": true, "HIGH_PRIORITY": true,
+	"DELAYED": true, "QUICK": true, "TEMPORARY": true, "TEMP": true, "UNLOGGED": true, "WITH": true,
+}
+
+var sprintfVerbRegexp = regexp.MustCompile(`%[-+# 0-9.*]*[a-zA-Z%]`)
+
+// sqlFromFuncs 参数中包含FROM关键字的函数，其中的FROM后不是表名
+var sqlFromFuncs = map[string]bool{"EXTRACT": true, "SUBSTRING": true, "TRIM": true, "POSITION": true, "OVERLAY": true}
+
+// SQLQuery 传给数据库调用的SQL
+type SQLQuery struct {
+	Library   string       `json:"library"`        // database/sql、sqlx或gorm，按文件的导入推断
+	Call      string       `json:"call"`           // 执行SQL的调用，如db.QueryContext
+	SQL       string       `json:"sql"`            // 常量折叠后的SQL，无法计算的部分为<表达式>，fmt.Sprintf保留格式串
+	Resolved  bool         `json:"resolved"`       // SQL是否完全由字面量和常量组成
+	Statement string       `json:"statement"`      // 语句类型，如SELECT、INSERT，WITH语句取主语句类型
+	Tables    []string     `json:"tables"`         // 小写的表名，按出现顺序去重
+	Pkg       string       `json:"pkg"`            // 所在包
+	Func      string       `json:"func,omitempty"` // 所在函数，包级变量初始化时为空
+	RFilePath string       `json:"file"`           // 所在文件
+	Line      int          `json:"line"`           // 行号
+	Column    int          `json:"column"`         // 列号
+	FuncInfo  *vs.FuncInfo `json:"-"`
+}
+
+// TableUsage 一张表在代码中的使用情况
+type TableUsage struct {
+	Table      string      `json:"table"`      // 表名
+	Statements []string    `json:"statements"` // 涉及的语句类型
+	Funcs      []string    `json:"funcs"`      // 使用该表的函数，包路径.函数名
+	Queries    []*SQLQuery `json:"queries"`    // 使用该表的SQL
+}
+
+// sqlValue 局部变量的字符串值
+type sqlValue struct {
+	value    string
+	resolved bool
+}
+
+type sqlExtractor struct {
+	modInfo   *ModuleInfo
+	fileSet   *token.FileSet
+	consts    stringConsts
+	fileFuncs map[string][]*vs.FuncInfo
+	queries   []*SQLQuery
+}
+
+type sqlFile struct {
+	*moduleGoFile
+	libraries []string // 文件导入的数据库库，更具体的库在前，最后总是database/sql
+}
+
+// ExtractSQLQueries 提取模块内传给database/sql、sqlx以及gorm Raw/Exec的SQL，支持字面量、常量、拼接和局部变量的常量折叠，
+// 只记录以SQL关键字开头的字符串，并解析语句类型和表名
+func ExtractSQLQueries(modInfo *ModuleInfo) ([]*SQLQuery, error) {
+	e := &sqlExtractor{
+		modInfo:   modInfo,
+		fileSet:   token.NewFileSet(),
+		consts:    make(stringConsts),
+		fileFuncs: moduleFileFuncs(modInfo),
+		queries:   make([]*SQLQuery, 0),
+	}
+	goFiles, err := parseModuleGoFiles(modInfo, e.fileSet)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*sqlFile, 0, len(goFiles))
+	for _, goFile := range goFiles {
+		file := &sqlFile{moduleGoFile: goFile}
+		for _, importSpec := range goFile.file.Imports {
+			importPath := strings.Trim(importSpec.Path.Value, `"`)
+			if library, ok := sqlImportLibraries[importPath]; ok && library != SQLLibraryDatabaseSQL {
+				file.libraries = append(file.libraries, library)
+			}
+		}
+		// sqlx和gorm的对象仍然可以调用database/sql的方法
+		file.libraries = append(file.libraries, SQLLibraryDatabaseSQL)
+		for _, decl := range goFile.file.Decls {
+			if genDecl, ok := decl.(*ast.GenDecl); ok {
+				e.consts.collect(goFile.pkg, genDecl)
+			}
+		}
+		files = append(files, file)
+	}
+	for _, file := range files {
+		for _, decl := range file.file.Decls {
+			e.extractDecl(file, decl)
+		}
+	}
+	sort.SliceStable(e.queries, func(i, j int) bool {
+		if e.queries[i].RFilePath != e.queries[j].RFilePath {
+			return e.queries[i].RFilePath < e.queries[j].RFilePath
+		}
+		return e.queries[i].Line < e.queries[j].Line
+	})
+	return e.queries, nil
+}
+
+// extractDecl 按源码顺序遍历声明，记录局部字符串变量的值并识别SQL调用
+func (e *sqlExtractor) extractDecl(file *sqlFile, decl ast.Decl) {
+	locals := make(map[string]sqlValue)
+	ast.Inspect(decl, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) != len(n.Rhs) {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				value, resolved := e.eval(file, n.Rhs[i], locals)
+				if n.Tok == token.ADD_ASSIGN {
+					prev := locals[ident.Name]
+					value, resolved = prev.value+value, prev.resolved && resolved
+				}
+				locals[ident.Name] = sqlValue{value: value, resolved: resolved}
+			}
+		case *ast.ValueSpec:
+			if _, isFunc := decl.(*ast.FuncDecl); isFunc && len(n.Names) == len(n.Values) {
+				for i, name := range n.Names {
+					value, resolved := e.eval(file, n.Values[i], locals)
+					locals[name.Name] = sqlValue{value: value, resolved: resolved}
+				}
+			}
+		case *ast.CallExpr:
+			e.extractCall(file, n, locals)
+		}
+		return true
+	})
+}
+
+// extractCall 识别执行SQL的调用
+func (e *sqlExtractor) extractCall(file *sqlFile, call *ast.CallExpr, locals map[string]sqlValue) {
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+	library, index := "", 0
+	for _, fileLibrary := range file.libraries {
+		if methodIndex, ok := sqlMethods[fileLibrary][selector.Sel.Name]; ok {
+			library, index = fileLibrary, methodIndex
+			break
+		}
+	}
+	if library == "" {
+		return
+	}
+	if ident, ok := selector.X.(*ast.Ident); ok {
+		if importPath, isImport := file.imports[ident.Name]; isImport {
+			if sqlImportLibraries[importPath] != SQLLibrarySqlx {
+				return
+			}
+			index++
+		}
+	}
+	if index >= len(call.Args) {
+		return
+	}
+	sql, resolved := e.eval(file, call.Args[index], locals)
+	statement, tables := ParseSQLStatement(sql)
+	if statement == "" {
+		return
+	}
+	position := e.fileSet.Position(call.Pos())
+	query := &SQLQuery{
+		Library:   library,
+		Call:      types.ExprString(call.Fun),
+		SQL:       sql,
+		Resolved:  resolved,
+		Statement: statement,
+		Tables:    tables,
+		Pkg:       file.pkg,
+		RFilePath: file.rFilePath,
+		Line:      position.Line,
+		Column:    position.Column,
+		FuncInfo:  LocateFuncInfo(e.fileFuncs[file.rFilePath], position.Line, position.Column),
+	}
+	if query.FuncInfo != nil {
+		query.Func = skeletonDisplayName(query.FuncInfo)
+	}
+	e.queries = append(e.queries, query)
+}
+
+// eval 计算SQL表达式，在包级常量的基础上支持局部变量、fmt.Sprintf的格式串以及Rebind等返回SQL本身的调用
+func (e *sqlExtractor) eval(file *sqlFile, expr ast.Expr, locals map[string]sqlValue) (string, bool) {
+	switch x := expr.(type) {
+	case *ast.Ident:
+		if local, ok := locals[x.Name]; ok {
+			return local.value, local.resolved
+		}
+	case *ast.ParenExpr:
+		return e.eval(file, x.X, locals)
+	case *ast.BinaryExpr:
+		if x.Op == token.ADD {
+			left, leftOK := e.eval(file, x.X, locals)
+			right, rightOK := e.eval(file, x.Y, locals)
+			return left + right, leftOK && rightOK
+		}
+	case *ast.CallExpr:
+		selector, ok := x.Fun.(*ast.SelectorExpr)
+		if !ok || len(x.Args) == 0 {
+			break
+		}
+		switch selector.Sel.Name {
+		case "Sprintf":
+			format, _ := e.eval(file, x.Args[0], locals)
+			return sprintfPlaceholders(format, x.Args[1:]), false
+		case "Rebind", "TrimSpace":
+			return e.eval(file, x.Args[len(x.Args)-1], locals)
+		}
+	}
+	return e.consts.eval(expr, file.pkg, file.imports)
+}
+
+// sprintfPlaceholders 将格式串中的动词替换为<参数表达式>，避免将动词误识别为表名
+func sprintfPlaceholders(format string, args []ast.Expr) string {
+	index := 0
+	return sprintfVerbRegexp.ReplaceAllStringFunc(format, func(verb string) string {
+		if verb == "%%" {
+			return "%"
+		}
+		placeholder := "<" + verb + ">"
+		if index < len(args) {
+			placeholder = "<" + types.ExprString(args[index]) + ">"
+		}
+		index++
+		return placeholder
+	})
+}
+
+// sqlToken SQL词法单元
+type sqlToken struct {
+	text   string
+	word   bool // 标识符或关键字
+	quoted bool // 带引号的标识符
+}
+
+// tokenizeSQL 切分SQL，跳过注释和字符串字面量
+func tokenizeSQL(sql string) []sqlToken {
+	tokens := make([]sqlToken, 0)
+	runes := []rune(sql)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-', r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case r == '\'':
+			for i++; i < len(runes) && runes[i] != '\''; i++ {
+			}
+			i++
+			tokens = append(tokens, sqlToken{text: "''"})
+		case r == '`' || r == '"' || r == '[':
+			closing := r
+			if r == '[' {
+				closing = ']'
+			}
+			start := i + 1
+			for i++; i < len(runes) && runes[i] != closing; i++ {
+			}
+			text := string(runes[start:min(i, len(runes))])
+			i++
+			tokens = appendSQLWord(tokens, text, true)
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || runes[i] == '.' || runes[i] == '$' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = appendSQLWord(tokens, string(runes[start:i]), false)
+		default:
+			tokens = append(tokens, sqlToken{text: string(r)})
+			i++
+		}
+	}
+	return tokens
+}
+
+// appendSQLWord 追加标识符，schema.`table`、`schema`.`table`等带引号的限定名合并为一个标识符
+func appendSQLWord(tokens []sqlToken, text string, quoted bool) []sqlToken {
+	n := len(tokens)
+	switch {
+	case n >= 2 && tokens[n-1].text == "." && tokens[n-2].word && (quoted || tokens[n-2].quoted):
+		tokens[n-2].text += "." + text
+		tokens[n-2].quoted = true
+		return tokens[:n-1]
+	case n >= 1 && tokens[n-1].word && strings.HasSuffix(tokens[n-1].text, ".") && quoted:
+		tokens[n-1].text += text
+		tokens[n-1].quoted = true
+		return tokens
+	case n >= 1 && tokens[n-1].quoted && strings.HasPrefix(text, "."):
+		tokens[n-1].text += text
+		return tokens
+	}
+	return append(tokens, sqlToken{text: text, word: true, quoted: quoted})
+}
+
+// ParseSQLStatement 轻量解析SQL，返回大写的语句类型和小写的表名，不是SQL时语句类型为空
+func ParseSQLStatement(sql string) (string, []string) {
+	tokens := tokenizeSQL(sql)
+	if len(tokens) == 0 || !tokens[0].word || !sqlStatementKeywords[strings.ToUpper(tokens[0].text)] {
+		return "", nil
+	}
+	upper := func(i int) string {
+		if i < 0 || i >= len(tokens) || !tokens[i].word || tokens[i].quoted {
+			return ""
+		}
+		return strings.ToUpper(tokens[i].text)
+	}
+	statement := upper(0)
+	cteNames := make(map[string]bool)
+	if statement == "WITH" {
+		depth := 0
+		expectName := true
+		for i := 1; i < len(tokens); i++ {
+			switch tokens[i].text {
+			case "(":
+				depth++
+				continue
+			case ")":
+				depth--
+				continue
+			case ",":
+				if depth == 0 {
+					expectName = true
+				}
+				continue
+			}
+			if depth != 0 || !tokens[i].word {
+				continue
+			}
+			keyword := upper(i)
+			if keyword == "RECURSIVE" {
+				continue
+			}
+			if expectName {
+				cteNames[strings.ToLower(tokens[i].text)] = true
+				expectName = false
+				continue
+			}
+			if keyword == "SELECT" || keyword == "INSERT" || keyword == "UPDATE" || keyword == "DELETE" || keyword == "MERGE" {
+				statement = keyword
+				break
+			}
+		}
+	}
+	tables := make([]string, 0)
+	seen := make(map[string]bool)
+	addTable := func(i int) bool {
+		if i >= len(tokens) || !tokens[i].word || !tokens[i].quoted && sqlReservedWords[upper(i)] {
+			return false
+		}
+		name := strings.ToLower(tokens[i].text)
+		if cteNames[name] || strings.HasSuffix(name, ".") {
+			return false
+		}
+		if !seen[name] {
+			seen[name] = true
+			tables = append(tables, name)
+		}
+		return true
+	}
+	// skipModifiers 跳过表名前的修饰关键字
+	skipModifiers := func(i int) int {
+		for {
+			switch upper(i) {
+			case "IF", "NOT", "EXISTS", "ONLY", "LATERAL", "TEMPORARY", "TEMP", "IGNORE", "LOW_PRIORITY", "QUICK":
+				i++
+			default:
+				return i
+			}
+		}
+	}
+	parenFuncs := make([]string, 0) // 每层括号前的函数名
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].text {
+		case "(":
+			parenFuncs = append(parenFuncs, upper(i-1))
+			continue
+		case ")":
+			if len(parenFuncs) > 0 {
+				parenFuncs = parenFuncs[:len(parenFuncs)-1]
+			}
+			continue
+		}
+		keyword := upper(i)
+		switch keyword {
+		case "FROM":
+			if len(parenFuncs) > 0 && sqlFromFuncs[parenFuncs[len(parenFuncs)-1]] || upper(i-1) == "DISTINCT" && upper(i-2) == "IS" {
+				continue
+			}
+			// FROM a, b AS x, c
+			for j := skipModifiers(i + 1); j < len(tokens); {
+				if j+1 < len(tokens) && tokens[j+1].text == "(" || !addTable(j) {
+					break
+				}
+				j++
+				if upper(j) == "AS" {
+					j++
+				}
+				if j < len(tokens) && tokens[j].word && !sqlReservedWords[upper(j)] {
+					j++
+				}
+				if j >= len(tokens) || tokens[j].text != "," {
+					break
+				}
+				j = skipModifiers(j + 1)
+			}
+		case "JOIN":
+			if j := skipModifiers(i + 1); j+1 >= len(tokens) || tokens[j+1].text != "(" {
+				addTable(j)
+			}
+		case "INTO", "TABLE", "TRUNCATE":
+			addTable(skipModifiers(i + 1))
+		case "UPDATE":
+			// 排除SELECT ... FOR UPDATE、ON DUPLICATE KEY UPDATE以及ON CONFLICT DO UPDATE
+			if previous := upper(i - 1); previous != "FOR" && previous != "KEY" && previous != "DO" {
+				addTable(skipModifiers(i + 1))
+			}
+		case "USING":
+			if statement == "DELETE" {
+				addTable(i + 1)
+			}
+		case "ON":
+			// CREATE INDEX idx ON table
+			if statement == "CREATE" && (upper(i-2) == "INDEX" || upper(i-3) == "INDEX") {
+				addTable(skipModifiers(i + 1))
+			}
+		}
+	}
+	return statement, tables
+}
+
+// BuildTableUsage 汇总每张表被哪些函数以哪些语句使用，用于评估表结构变更的影响
+func BuildTableUsage(queries []*SQLQuery) []*TableUsage {
+	usages := make(map[string]*TableUsage)
+	statements := make(map[string]map[string]bool)
+	funcs := make(map[string]map[string]bool)
+	for _, query := range queries {
+		for _, table := range query.Tables {
+			usage, ok := usages[table]
+			if !ok {
+				usage = &TableUsage{Table: table}
+				usages[table] = usage
+				statements[table] = make(map[string]bool)
+				funcs[table] = make(map[string]bool)
+			}
+			usage.Queries = append(usage.Queries, query)
+			statements[table][query.Statement] = true
+			if query.Func != "" {
+				funcs[table][query.Pkg+"."+query.Func] = true
+			}
+		}
+	}
+	result := make([]*TableUsage, 0, len(usages))
+	for _, table := range sortedMapKeys(usages) {
+		usage := usages[table]
+		usage.Statements = sortedMapKeys(statements[table])
+		usage.Funcs = sortedMapKeys(funcs[table])
+		result = append(result, usage)
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSQLStatement(t *testing.T) {
+	cases := []struct {
+		sql       string
+		statement string
+		tables    string
+	}{
+		{"SELECT u.id, o.total FROM users u JOIN `shop`.`orders` AS o ON o.user_id = u.id", "SELECT", "users,shop.orders"},
+		{"select * from a, b x, c where a.id = b.id -- from d", "SELECT", "a,b,c"},
+		{"SELECT EXTRACT(YEAR FROM created_at) FROM events WHERE id IN (SELECT event_id FROM tags)", "SELECT", "events,tags"},
+		{"INSERT INTO users (name) VALUES ('from x') ON DUPLICATE KEY UPDATE name = VALUES(name)", "INSERT", "users"},
+		{"UPDATE accounts SET balance = balance - ? WHERE id = ?", "UPDATE", "accounts"},
+		{"DELETE FROM sessions USING users WHERE sessions.user_id = users.id", "DELETE", "sessions,users"},
+		{"WITH recent AS (SELECT * FROM orders) SELECT * FROM recent JOIN users ON true", "SELECT", "orders,users"},
+		{"CREATE TABLE IF NOT EXISTS \"audit_log\" (id int)", "CREATE", "audit_log"},
+		{"CREATE INDEX idx_user ON users (email)", "CREATE", "users"},
+		{"SELECT * FROM users FOR UPDATE", "SELECT", "users"},
+		{"hello world", "", ""},
+	}
+	for _, c := range cases {
+		statement, tables := ParseSQLStatement(c.sql)
+		if statement != c.statement || strings.Join(tables, ",") != c.tables {
+			t.Errorf("%s: 解析结果 %s %v，期望 %s %s", c.sql, statement, tables, c.statement, c.tables)
+		}
+	}
+}
+
+func TestExtractSQLQueries(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"store/user.go": `package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+const userColumns = "id, name"
+
+const selectUsers = "SELECT " + userColumns + " FROM users"
+
+type Store struct {
+	db *sql.DB
+}
+
+func (s *Store) List(ctx context.Context, active bool) error {
+	query := selectUsers
+	if active {
+		query += " WHERE active = 1"
+	}
+	_, err := s.db.QueryContext(ctx, query)
+	return err
+}
+
+func (s *Store) Archive(table string) error {
+	_, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s_archive WHERE id < ?", table), 10)
+	return err
+}
+
+func (s *Store) Ping() error {
+	_, err := s.db.Exec(s.ping())
+	return err
+}
+
+func (s *Store) ping() string { return "SELECT 1" }
+`,
+		"store/order.go": `package store
+
+import (
+	"github.com/jmoiron/sqlx"
+	"gorm.io/gorm"
+)
+
+func Orders(db *sqlx.DB, g *gorm.DB) {
+	var ids []int
+	_ = db.Select(&ids, db.Rebind("SELECT id FROM orders WHERE user_id = ?"), 1)
+	_ = sqlx.Get(db, &ids, "SELECT count(*) FROM order_items")
+	g.Raw("UPDATE orders SET paid = true")
+	g.Where("name = ?", "x")
+}
+`,
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries, err := ExtractSQLQueries(modInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 5 {
+		t.Fatalf("期望5条SQL，实际%d条", len(queries))
+	}
+	orders, items, raw, list, archive := queries[0], queries[1], queries[2], queries[3], queries[4]
+	if orders.Library != SQLLibrarySqlx || orders.Func != "Orders" || orders.Statement != "SELECT" || orders.Tables[0] != "orders" || !orders.Resolved {
+		t.Errorf("sqlx Select解析错误: %+v", orders)
+	}
+	if items.Call != "sqlx.Get" || items.Tables[0] != "order_items" {
+		t.Errorf("sqlx包级函数解析错误: %+v", items)
+	}
+	if raw.Library != SQLLibraryGorm || raw.Statement != "UPDATE" {
+		t.Errorf("gorm Raw解析错误: %+v", raw)
+	}
+	if list.SQL != "SELECT id, name FROM users WHERE active = 1" || !list.Resolved || list.Func != "Store.List" || list.RFilePath != "store/user.go" || list.Line != 22 {
+		t.Errorf("常量折叠错误: %+v", list)
+	}
+	if archive.SQL != "DELETE FROM <table>_archive WHERE id < ?" || archive.Resolved || len(archive.Tables) != 0 {
+		t.Errorf("fmt.Sprintf解析错误: %+v", archive)
+	}
+
+	usage := BuildTableUsage(queries)
+	if len(usage) != 3 || usage[0].Table != "order_items" || usage[1].Table != "orders" || strings.Join(usage[1].Statements, ",") != "SELECT,UPDATE" {
+		t.Fatalf("表使用汇总错误: %+v", usage)
+	}
+	if strings.Join(usage[2].Funcs, ",") != "example.com/app/store.Store.List" {
+		t.Errorf("表使用函数错误: %v", usage[2].Funcs)
+	}
+}
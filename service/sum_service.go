@@ -0,0 +1,334 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+const (
+	SumIssueMissing       = "missing"        // 缺少依赖go.mod的校验和
+	SumIssueMissingZip    = "missing_zip"    // 直接依赖缺少源码压缩包的校验和
+	SumIssueStale         = "stale"          // 不再被依赖的条目
+	SumIssueConflict      = "conflict"       // 同一条目出现多个不同的校验和
+	SumIssueMismatch      = "mismatch"       // 与模块缓存中的内容不一致
+	SumIssueVendorMissing = "vendor_missing" // vendor目录中缺少依赖
+)
+
+// SumEntry go.sum中的一条记录
+type SumEntry struct {
+	Path    string // 模块路径
+	Version string // 模块版本
+	GoMod   bool   // 是否为go.mod文件的校验和
+	Hash    string // h1校验和
+	Line    int    // 在go.sum中的行号
+}
+
+// SumFinding go.sum检查发现的问题
+type SumFinding struct {
+	Kind     string // 问题类型
+	Path     string // 模块路径
+	Version  string // 模块版本
+	GoMod    bool   // 是否针对go.mod文件
+	Line     int    // go.sum中的行号，缺失类问题为0
+	Expected string // go.sum中记录的校验和
+	Actual   string // 实际计算出的校验和
+	Message  string // 问题描述
+}
+
+// SumReport 单个模块的go.sum检查报告
+type SumReport struct {
+	Module   string
+	Dir      string
+	Verified int // 成功校验的条目数
+	Findings []*SumFinding
+}
+
+// SumVerifyConfig go.sum检查配置
+type SumVerifyConfig struct {
+	ModCacheDir string // 模块缓存目录，默认为GOMODCACHE
+}
+
+// ParseGoSum 解析go.sum文件
+func ParseGoSum(path string) ([]SumEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseGoSum(file)
+}
+
+func parseGoSum(r io.Reader) ([]SumEntry, error) {
+	entries := make([]SumEntry, 0)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("go.sum第%d行格式错误: %s", lineNum, scanner.Text())
+		}
+		version, isGoMod := strings.CutSuffix(fields[1], "/go.mod")
+		entries = append(entries, SumEntry{
+			Path:    fields[0],
+			Version: version,
+			GoMod:   isGoMod,
+			Hash:    fields[2],
+			Line:    lineNum,
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// VerifyRepoGoSum 检查仓库内每个模块的go.sum
+func VerifyRepoGoSum(modules []*ModuleInfo, cfg *SumVerifyConfig) []*SumReport {
+	reports := make([]*SumReport, 0, len(modules))
+	for _, modInfo := range modules {
+		if modInfo.ThirdParty {
+			continue
+		}
+		reports = append(reports, VerifyGoSum(modInfo, cfg))
+	}
+	return reports
+}
+
+// VerifyGoSum 将go.sum与require交叉校验，并与模块缓存中的压缩包和go.mod比对校验和；
+// vendor目录中的代码经过裁剪无法还原h1校验和，只检查依赖是否存在
+func VerifyGoSum(modInfo *ModuleInfo, cfg *SumVerifyConfig) *SumReport {
+	if cfg == nil {
+		cfg = &SumVerifyConfig{}
+	}
+	modCacheDir := cfg.ModCacheDir
+	if modCacheDir == "" {
+		modCacheDir = ResolveModCacheDir()
+	}
+	report := &SumReport{Module: modInfo.Path, Dir: modInfo.Dir}
+	addFinding := func(finding *SumFinding) {
+		report.Findings = append(report.Findings, finding)
+	}
+	// 生效的依赖版本，本地路径替换的依赖不需要校验和
+	required := make(map[string]string)
+	direct := make(map[string]bool)
+	for _, req := range modInfo.Requires {
+		path, version := req.Path, req.Version
+		if replace := findReplaceRule(modInfo.Replaces, req); replace != nil {
+			if modfile.IsDirectoryPath(replace.NewPath) {
+				continue
+			}
+			path, version = replace.NewPath, replace.NewVersion
+		}
+		required[path] = version
+		direct[path] = !req.Indirect
+	}
+	// 只有require中的模块都能在模块缓存中找到go.mod时，才能判断间接出现在依赖图中的条目是否多余
+	graphVersions, graphPaths, graphComplete := sumModuleGraph(modInfo, modCacheDir)
+	entryMap := make(map[string]*SumEntry)
+	for i := range modInfo.Sums {
+		entry := &modInfo.Sums[i]
+		key := sumEntryKey(entry.Path, entry.Version, entry.GoMod)
+		if existing, ok := entryMap[key]; ok {
+			if existing.Hash != entry.Hash {
+				addFinding(&SumFinding{
+					Kind:     SumIssueConflict,
+					Path:     entry.Path,
+					Version:  entry.Version,
+					GoMod:    entry.GoMod,
+					Line:     entry.Line,
+					Expected: existing.Hash,
+					Actual:   entry.Hash,
+					Message:  fmt.Sprintf("与第%d行的校验和冲突", existing.Line),
+				})
+			}
+			continue
+		}
+		entryMap[key] = entry
+		version, isRequired := required[entry.Path]
+		if !isRequired {
+			// 整洁的go.sum会保留只经由其他依赖的go.mod到达的模块，go.mod条目按版本、源码条目按路径在依赖图中查找
+			reachable := graphPaths[entry.Path]
+			if entry.GoMod {
+				reachable = graphVersions[entry.Path+"@"+entry.Version]
+			}
+			if graphComplete && !reachable {
+				addFinding(&SumFinding{
+					Kind:    SumIssueStale,
+					Path:    entry.Path,
+					Version: entry.Version,
+					GoMod:   entry.GoMod,
+					Line:    entry.Line,
+					Message: "条目不在模块依赖图中，可执行go mod tidy确认",
+				})
+			}
+			continue
+		}
+		if !entry.GoMod && version != entry.Version {
+			addFinding(&SumFinding{
+				Kind:    SumIssueStale,
+				Path:    entry.Path,
+				Version: entry.Version,
+				Line:    entry.Line,
+				Message: "源码条目的版本与require不一致，可执行go mod tidy确认",
+			})
+			continue
+		}
+		if version != entry.Version {
+			continue
+		}
+		actual, err := cachedModuleHash(modCacheDir, entry.Path, entry.Version, entry.GoMod)
+		if err != nil {
+			// 模块缓存中不存在时无法校验
+			continue
+		}
+		if actual != entry.Hash {
+			addFinding(&SumFinding{
+				Kind:     SumIssueMismatch,
+				Path:     entry.Path,
+				Version:  entry.Version,
+				GoMod:    entry.GoMod,
+				Line:     entry.Line,
+				Expected: entry.Hash,
+				Actual:   actual,
+				Message:  "校验和与模块缓存不一致",
+			})
+			continue
+		}
+		report.Verified++
+	}
+	vendorDir := filepath.Join(modInfo.Dir, "vendor")
+	_, vendorErr := os.Stat(vendorDir)
+	for _, path := range sortedMapKeys(required) {
+		version := required[path]
+		if _, ok := entryMap[sumEntryKey(path, version, true)]; !ok {
+			addFinding(&SumFinding{Kind: SumIssueMissing, Path: path, Version: version, GoMod: true, Message: "缺少go.mod校验和"})
+		}
+		if _, ok := entryMap[sumEntryKey(path, version, false)]; !ok && direct[path] {
+			addFinding(&SumFinding{Kind: SumIssueMissingZip, Path: path, Version: version, Message: "直接依赖缺少源码校验和"})
+		}
+		if vendorErr == nil && direct[path] {
+			if _, err := os.Stat(filepath.Join(vendorDir, filepath.FromSlash(path))); err != nil {
+				addFinding(&SumFinding{Kind: SumIssueVendorMissing, Path: path, Version: version, Message: "vendor目录中缺少该依赖"})
+			}
+		}
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		if report.Findings[i].Path != report.Findings[j].Path {
+			return report.Findings[i].Path < report.Findings[j].Path
+		}
+		return report.Findings[i].Kind < report.Findings[j].Kind
+	})
+	return report
+}
+
+// sumModuleGraph 从require出发，读取模块缓存中各版本的go.mod遍历模块依赖图，replace按主模块的规则生效；
+// 返回出现过的模块版本（路径@版本）和模块路径，有go.mod无法读取时complete为false
+func sumModuleGraph(modInfo *ModuleInfo, modCacheDir string) (map[string]bool, map[string]bool, bool) {
+	versions := make(map[string]bool)
+	paths := make(map[string]bool)
+	complete := true
+	visitedDirs := make(map[string]bool)
+	var visit func(req Dependency)
+	visitFile := func(data []byte, name string) {
+		modFile, err := modfile.ParseLax(name, data, nil)
+		if err != nil {
+			complete = false
+			return
+		}
+		for _, require := range modFile.Require {
+			visit(Dependency{Path: require.Mod.Path, Version: require.Mod.Version})
+		}
+	}
+	visit = func(req Dependency) {
+		if replace := findReplaceRule(modInfo.Replaces, req); replace != nil {
+			if modfile.IsDirectoryPath(replace.NewPath) {
+				dir := filepath.Join(modInfo.Dir, filepath.FromSlash(replace.NewPath))
+				if filepath.IsAbs(replace.NewPath) {
+					dir = replace.NewPath
+				}
+				if visitedDirs[dir] {
+					return
+				}
+				visitedDirs[dir] = true
+				data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+				if err != nil {
+					complete = false
+					return
+				}
+				visitFile(data, filepath.Join(dir, "go.mod"))
+				return
+			}
+			req = Dependency{Path: replace.NewPath, Version: replace.NewVersion}
+		}
+		key := req.Path + "@" + req.Version
+		if versions[key] {
+			return
+		}
+		versions[key] = true
+		paths[req.Path] = true
+		modPath, err := cachedModFilePath(modCacheDir, req.Path, req.Version)
+		if err != nil {
+			complete = false
+			return
+		}
+		data, err := os.ReadFile(modPath)
+		if err != nil {
+			complete = false
+			return
+		}
+		visitFile(data, modPath)
+	}
+	for _, req := range modInfo.Requires {
+		visit(req)
+	}
+	return versions, paths, complete
+}
+
+// cachedModFilePath 返回模块缓存下载目录中某个版本go.mod的路径
+func cachedModFilePath(modCacheDir, path, version string) (string, error) {
+	escapedPath, err := module.EscapePath(path)
+	if err != nil {
+		return "", err
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(modCacheDir, "cache", "download", filepath.FromSlash(escapedPath), "@v", escapedVersion+".mod"), nil
+}
+
+// cachedModuleHash 计算模块缓存下载目录中压缩包或go.mod的h1校验和
+func cachedModuleHash(modCacheDir, path, version string, goMod bool) (string, error) {
+	modPath, err := cachedModFilePath(modCacheDir, path, version)
+	if err != nil {
+		return "", err
+	}
+	if !goMod {
+		return dirhash.HashZip(strings.TrimSuffix(modPath, ".mod")+".zip", dirhash.Hash1)
+	}
+	data, err := os.ReadFile(modPath)
+	if err != nil {
+		return "", err
+	}
+	return dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+}
+
+func sumEntryKey(path, version string, goMod bool) string {
+	if goMod {
+		return path + " " + version + "/go.mod"
+	}
+	return path + " " + version
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+func TestVerifyGoSum(t *testing.T) {
+	modCacheDir := t.TempDir()
+	downloadDir := filepath.Join(modCacheDir, "cache", "download", "example.com", "good", "@v")
+	if err := os.MkdirAll(downloadDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	goodSrc := writeTestModule(t, map[string]string{
+		"go.mod":  "module example.com/good\n",
+		"good.go": "package good\n",
+	})
+	zipFile, err := os.Create(filepath.Join(downloadDir, "v1.0.0.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := modzip.CreateFromDir(zipFile, module.Version{Path: "example.com/good", Version: "v1.0.0"}, goodSrc); err != nil {
+		t.Fatal(err)
+	}
+	zipFile.Close()
+	// good的go.mod依赖trans，trans只经由good的go.mod到达，其go.mod条目不应视为多余
+	cachedMods := map[string]string{
+		"example.com/good/@v/v1.0.0.mod":  "module example.com/good\n\nrequire example.com/trans v1.2.0\n",
+		"example.com/trans/@v/v1.2.0.mod": "module example.com/trans\n",
+		"example.com/nosum/@v/v0.1.0.mod": "module example.com/nosum\n",
+	}
+	for name, content := range cachedMods {
+		modPath := filepath.Join(modCacheDir, "cache", "download", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(modPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(modPath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	zipHash, err := cachedModuleHash(modCacheDir, "example.com/good", "v1.0.0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": `module example.com/app
+
+go 1.21
+
+require (
+	example.com/good v1.0.0
+	example.com/nosum v0.1.0
+)
+`,
+		"go.sum": "example.com/good v1.0.0 " + zipHash + "\n" +
+			"example.com/good v1.0.0/go.mod h1:tampered=\n" +
+			"example.com/good v0.9.0/go.mod h1:old=\n" +
+			"example.com/good v0.9.0 h1:oldzip=\n" +
+			"example.com/gone v1.0.0/go.mod h1:gone=\n" +
+			"example.com/gone v1.0.0/go.mod h1:other=\n" +
+			"example.com/trans v1.2.0/go.mod h1:trans=\n" +
+			"example.com/trans v1.1.0/go.mod h1:oldtrans=\n",
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modInfo.Sums) != 8 || !modInfo.Sums[1].GoMod || modInfo.Sums[1].Line != 2 {
+		t.Fatalf("unexpected go.sum entries: %+v", modInfo.Sums)
+	}
+	report := VerifyGoSum(modInfo, &SumVerifyConfig{ModCacheDir: modCacheDir})
+	if report.Verified != 1 {
+		t.Errorf("expected the zip hash to be verified, got %d", report.Verified)
+	}
+	kinds := make(map[string]int)
+	for _, finding := range report.Findings {
+		kinds[finding.Kind+" "+finding.Path]++
+	}
+	expected := map[string]int{
+		SumIssueMismatch + " example.com/good":    1,
+		SumIssueStale + " example.com/good":       1,
+		SumIssueStale + " example.com/gone":       1,
+		SumIssueStale + " example.com/trans":      1,
+		SumIssueConflict + " example.com/gone":    1,
+		SumIssueMissing + " example.com/nosum":    1,
+		SumIssueMissingZip + " example.com/nosum": 1,
+	}
+	for key, count := range expected {
+		if kinds[key] != count {
+			t.Errorf("%s: expected %d findings, got %d", key, count, kinds[key])
+		}
+	}
+	if len(report.Findings) != len(expected) {
+		t.Errorf("unexpected findings: %v", kinds)
+	}
+	for _, finding := range report.Findings {
+		if finding.Kind == SumIssueStale && finding.Path == "example.com/trans" && finding.Version != "v1.1.0" {
+			t.Errorf("transitive go.mod entry reported as stale: %+v", finding)
+		}
+	}
+	// 模块缓存中缺少go.mod时无法遍历依赖图，不在require中的条目不报告多余
+	incomplete := VerifyGoSum(modInfo, &SumVerifyConfig{ModCacheDir: t.TempDir()})
+	for _, finding := range incomplete.Findings {
+		if finding.Kind == SumIssueStale && finding.Path != "example.com/good" {
+			t.Errorf("unexpected stale finding without module graph: %+v", finding)
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package service
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+)
+
+// TestGenConfig 测试骨架生成配置
+type TestGenConfig struct {
+	Packages []string // 需要生成的包路径，为空时处理模块内所有包
+	Funcs    []string // 需要生成的函数，格式为Func或Type.Method，为空时处理所有函数
+	Exported bool     // 是否只为导出的函数和方法生成
+}
+
+// TestSkeleton 为一个源文件生成的测试骨架
+type TestSkeleton struct {
+	Pkg      string   // 包路径
+	FilePath string   // 生成文件相对模块根目录的路径
+	Source   string   // 对应的源文件
+	Funcs    []string // 生成了测试的函数
+	Content  []byte   // gofmt格式化后的文件内容
+}
+
+// sourceFileInfo 源文件的包名、导入和函数声明，用于还原类型的源码写法
+type sourceFileInfo struct {
+	pkgName string
+	aliases map[string]string        // 导入路径 -> 包名
+	decls   map[string]*ast.FuncDecl // 测试名后缀 -> 函数声明
+	structs map[string]bool          // 文件中声明的结构体类型
+}
+
+// skeletonVar 测试表中的一个参数、字段或返回值
+type skeletonVar struct {
+	Name     string
+	Type     string // 表字段使用的类型
+	Variadic bool
+}
+
+// GenerateTestSkeletons 按FuncInfo的签名生成表驱动测试骨架，已有同名Test函数的函数会被跳过
+func GenerateTestSkeletons(modInfo *ModuleInfo, cfg *TestGenConfig) ([]*TestSkeleton, error) {
+	if cfg == nil {
+		cfg = &TestGenConfig{}
+	}
+	wantPkgs := make(map[string]bool)
+	for _, pkg := range cfg.Packages {
+		wantPkgs[pkg] = true
+	}
+	wantFuncs := make(map[string]bool)
+	for _, name := range cfg.Funcs {
+		wantFuncs[name] = true
+	}
+	fileCache := make(map[string]*sourceFileInfo)
+	loadFile := func(rFilePath string) (*sourceFileInfo, error) {
+		if info, ok := fileCache[rFilePath]; ok {
+			return info, nil
+		}
+		info, err := parseSourceFileInfo(filepath.Join(modInfo.Dir, rFilePath))
+		if err != nil {
+			return nil, err
+		}
+		fileCache[rFilePath] = info
+		return info, nil
+	}
+	skeletons := make([]*TestSkeleton, 0)
+	for _, pkg := range sortedMapKeys(modInfo.PkgFuncMap) {
+		if len(wantPkgs) > 0 && !wantPkgs[pkg] {
+			continue
+		}
+		// 已有的测试函数，包括外部测试包中的
+		existing := make(map[string]bool)
+		existingFiles := make(map[string]bool)
+		for _, testPkg := range []string{pkg, pkg + "_test"} {
+			for _, funcInfo := range modInfo.PkgTestFuncMap[testPkg] {
+				existingFiles[filepath.ToSlash(funcInfo.RFilePath)] = true
+				if funcInfo.Receiver == nil && strings.HasPrefix(funcInfo.Name, "Test") {
+					existing[funcInfo.Name] = true
+				}
+			}
+		}
+		fileFuncs := make(map[string][]*vs.FuncInfo)
+		for _, funcInfo := range modInfo.PkgFuncMap[pkg] {
+			if strings.Contains(funcInfo.Name, "$") {
+				continue
+			}
+			if funcInfo.Receiver == nil && (funcInfo.Name == "init" || funcInfo.Name == "main" || funcInfo.Name == "_") {
+				continue
+			}
+			testSuffix := skeletonTestSuffix(funcInfo)
+			if existing["Test"+testSuffix] || (len(wantFuncs) > 0 && !wantFuncs[skeletonDisplayName(funcInfo)]) {
+				continue
+			}
+			if cfg.Exported && (!ast.IsExported(funcInfo.Name) || (funcInfo.Receiver != nil && !ast.IsExported(receiverTypeName(funcInfo.Receiver)))) {
+				continue
+			}
+			fileFuncs[funcInfo.RFilePath] = append(fileFuncs[funcInfo.RFilePath], funcInfo)
+		}
+		for _, rFilePath := range sortedMapKeys(fileFuncs) {
+			skeleton, err := buildTestSkeleton(modInfo, pkg, rFilePath, fileFuncs[rFilePath], existingFiles, loadFile)
+			if err != nil {
+				return nil, err
+			}
+			if skeleton != nil {
+				skeletons = append(skeletons, skeleton)
+			}
+		}
+	}
+	return skeletons, nil
+}
+
+// WriteTestSkeletons 将测试骨架写入模块目录，不覆盖已有文件
+func WriteTestSkeletons(modInfo *ModuleInfo, skeletons []*TestSkeleton) error {
+	for _, skeleton := range skeletons {
+		path := filepath.Join(modInfo.Dir, filepath.FromSlash(skeleton.FilePath))
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return fmt.Errorf("创建测试文件 %s 失败: %w", skeleton.FilePath, err)
+		}
+		_, err = file.Write(skeleton.Content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("写入测试文件 %s 失败: %w", skeleton.FilePath, err)
+		}
+	}
+	return nil
+}
+
+func buildTestSkeleton(modInfo *ModuleInfo, pkg, rFilePath string, funcInfos []*vs.FuncInfo, existingFiles map[string]bool,
+	loadFile func(rFilePath string) (*sourceFileInfo, error)) (*TestSkeleton, error) {
+	srcInfo, err := loadFile(rFilePath)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(funcInfos, func(i, j int) bool {
+		return funcInfos[i].StartPosition.OffSet < funcInfos[j].StartPosition.OffSet
+	})
+	imports := make(map[string]string)
+	var body strings.Builder
+	generated := make([]string, 0)
+	for _, funcInfo := range funcInfos {
+		testSuffix := skeletonTestSuffix(funcInfo)
+		// 泛型函数无法确定类型实参，跳过
+		if decl := srcInfo.decls[testSuffix]; decl == nil || decl.Type.TypeParams != nil || isGenericReceiver(decl) {
+			continue
+		}
+		var fields []skeletonVar
+		var structInit, receiverType string
+		if funcInfo.Receiver != nil {
+			receiverType = receiverTypeName(funcInfo.Receiver)
+			structInfo, structFile, err := findReceiverStruct(modInfo.PkgStructMap[pkg], receiverType, loadFile)
+			if err != nil {
+				return nil, err
+			}
+			if structInfo != nil {
+				for _, field := range structInfo.Fields {
+					name := field.Name
+					if name == "_" {
+						// 嵌入字段以类型名作为字段名
+						name = field.BaseType[strings.LastIndex(field.BaseType, ".")+1:]
+					}
+					fields = append(fields, skeletonVar{Name: name, Type: localTypeString(field.Type, structFile.aliases, imports)})
+				}
+				structInit = receiverType + "{\n"
+				for _, field := range fields {
+					structInit += fmt.Sprintf("%s: tt.fields.%s,\n", field.Name, field.Name)
+				}
+				structInit += "}"
+				if strings.HasPrefix(funcInfo.Receiver.Type, "*") {
+					structInit = "&" + structInit
+				}
+			}
+		}
+		params := make([]skeletonVar, 0, len(funcInfo.Params))
+		for i, param := range funcInfo.Params {
+			name := param.Name
+			if name == "_" || name == "" {
+				name = fmt.Sprintf("arg%d", i)
+			}
+			paramType := localTypeString(param.Type, srcInfo.aliases, imports)
+			variadic := strings.HasPrefix(paramType, "...")
+			if variadic {
+				paramType = "[]" + strings.TrimPrefix(paramType, "...")
+			}
+			params = append(params, skeletonVar{Name: name, Type: paramType, Variadic: variadic})
+		}
+		results := make([]skeletonVar, 0, len(funcInfo.Results))
+		wantErr := false
+		for i, result := range funcInfo.Results {
+			resultType := localTypeString(result.Type, srcInfo.aliases, imports)
+			if i == len(funcInfo.Results)-1 && resultType == "error" {
+				wantErr = true
+				break
+			}
+			name := "want"
+			if len(results) > 0 {
+				name = fmt.Sprintf("want%d", len(results))
+			}
+			results = append(results, skeletonVar{Name: name, Type: resultType})
+		}
+		writeTestFunc(&body, funcInfo, testSuffix, receiverType, structInit, fields, params, results, wantErr)
+		generated = append(generated, skeletonDisplayName(funcInfo))
+	}
+	if len(generated) == 0 {
+		return nil, nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "package %s\n\nimport (\n", srcInfo.pkgName)
+	if strings.Contains(body.String(), "reflect.DeepEqual") {
+		imports["reflect"] = "reflect"
+	}
+	imports["testing"] = "testing"
+	for _, path := range sortedMapKeys(imports) {
+		if alias := imports[path]; alias != path[strings.LastIndex(path, "/")+1:] {
+			fmt.Fprintf(&sb, "%s %q\n", alias, path)
+		} else {
+			fmt.Fprintf(&sb, "%q\n", path)
+		}
+	}
+	sb.WriteString(")\n\n")
+	sb.WriteString(body.String())
+	content, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return nil, fmt.Errorf("格式化 %s 的测试骨架失败: %w", rFilePath, err)
+	}
+	// 已有对应的测试文件时另起一个文件，避免覆盖
+	testFile := strings.TrimSuffix(filepath.ToSlash(rFilePath), ".go") + "_test.go"
+	if _, err := os.Stat(filepath.Join(modInfo.Dir, filepath.FromSlash(testFile))); err == nil || existingFiles[testFile] {
+		testFile = strings.TrimSuffix(filepath.ToSlash(rFilePath), ".go") + "_skeleton_test.go"
+	}
+	return &TestSkeleton{
+		Pkg:      pkg,
+		FilePath: testFile,
+		Source:   rFilePath,
+		Funcs:    generated,
+		Content:  content,
+	}, nil
+}
+
+func writeTestFunc(sb *strings.Builder, funcInfo *vs.FuncInfo, testSuffix, receiverType, structInit string, fields, params, results []skeletonVar, wantErr bool) {
+	fmt.Fprintf(sb, "func Test%s(t *testing.T) {\n", testSuffix)
+	writeTypeDecl := func(name string, vars []skeletonVar) {
+		fmt.Fprintf(sb, "type %s struct {\n", name)
+		for _, v := range vars {
+			fmt.Fprintf(sb, "%s %s\n", v.Name, v.Type)
+		}
+		sb.WriteString("}\n")
+	}
+	if structInit != "" {
+		writeTypeDecl("fields", fields)
+	}
+	if len(params) > 0 {
+		writeTypeDecl("args", params)
+	}
+	sb.WriteString("tests := []struct {\nname string\n")
+	if structInit != "" {
+		sb.WriteString("fields fields\n")
+	} else if receiverType != "" {
+		fmt.Fprintf(sb, "receiver %s\n", strings.TrimPrefix(receiverType, "*"))
+	}
+	if len(params) > 0 {
+		sb.WriteString("args args\n")
+	}
+	for _, result := range results {
+		fmt.Fprintf(sb, "%s %s\n", result.Name, result.Type)
+	}
+	if wantErr {
+		sb.WriteString("wantErr bool\n")
+	}
+	sb.WriteString("}{\n{name: \"zero values\"},\n// TODO: 补充测试用例\n}\n")
+	sb.WriteString("for _, tt := range tests {\nt.Run(tt.name, func(t *testing.T) {\n")
+	callee := funcInfo.Name
+	if structInit != "" {
+		fmt.Fprintf(sb, "r := %s\n", structInit)
+		callee = "r." + funcInfo.Name
+	} else if receiverType != "" {
+		callee = "tt.receiver." + funcInfo.Name
+	}
+	args := make([]string, 0, len(params))
+	for _, param := range params {
+		arg := "tt.args." + param.Name
+		if param.Variadic {
+			arg += "..."
+		}
+		args = append(args, arg)
+	}
+	call := fmt.Sprintf("%s(%s)", callee, strings.Join(args, ", "))
+	gots := make([]string, 0, len(results)+1)
+	for i := range results {
+		if i == 0 {
+			gots = append(gots, "got")
+		} else {
+			gots = append(gots, fmt.Sprintf("got%d", i))
+		}
+	}
+	if wantErr {
+		gots = append(gots, "err")
+	}
+	if len(gots) == 0 {
+		sb.WriteString(call + "\n")
+	} else {
+		fmt.Fprintf(sb, "%s := %s\n", strings.Join(gots, ", "), call)
+	}
+	displayName := skeletonDisplayName(funcInfo)
+	if wantErr {
+		fmt.Fprintf(sb, "if (err != nil) != tt.wantErr {\nt.Fatalf(\"%s() error = %%v, wantErr %%v\", err, tt.wantErr)\n}\n", displayName)
+	}
+	for i, result := range results {
+		fmt.Fprintf(sb, "if !reflect.DeepEqual(%s, tt.%s) {\nt.Errorf(\"%s() %s = %%v, want %%v\", %s, tt.%s)\n}\n",
+			gots[i], result.Name, displayName, gots[i], gots[i], result.Name)
+	}
+	sb.WriteString("})\n}\n}\n\n")
+}
+
+// parseSourceFileInfo 解析源文件的包名、导入别名和函数声明
+func parseSourceFileInfo(filePath string) (*sourceFileInfo, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), filePath, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("解析文件 %s 失败: %w", filePath, err)
+	}
+	info := &sourceFileInfo{
+		pkgName: file.Name.Name,
+		aliases: make(map[string]string),
+		decls:   make(map[string]*ast.FuncDecl),
+		structs: make(map[string]bool),
+	}
+	for _, importSpec := range file.Imports {
+		path := strings.Trim(importSpec.Path.Value, `"`)
+		// 与FileFuncVisitor一致，未指定别名时取路径最后一段
+		name := path[strings.LastIndex(path, "/")+1:]
+		if importSpec.Name != nil {
+			name = importSpec.Name.Name
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		info.aliases[path] = name
+	}
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.TYPE {
+			for _, spec := range genDecl.Specs {
+				if typeSpec, ok := spec.(*ast.TypeSpec); ok && typeSpec.TypeParams == nil {
+					if _, ok := typeSpec.Type.(*ast.StructType); ok {
+						info.structs[typeSpec.Name.Name] = true
+					}
+				}
+			}
+		}
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		suffix := funcDecl.Name.Name
+		if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
+			suffix = recvExprName(funcDecl.Recv.List[0].Type) + "_" + suffix
+		}
+		info.decls[suffix] = funcDecl
+	}
+	return info, nil
+}
+
+func recvExprName(expr ast.Expr) string {
+	switch n := expr.(type) {
+	case *ast.StarExpr:
+		return recvExprName(n.X)
+	case *ast.ParenExpr:
+		return recvExprName(n.X)
+	case *ast.IndexExpr:
+		return recvExprName(n.X)
+	case *ast.IndexListExpr:
+		return recvExprName(n.X)
+	case *ast.Ident:
+		return n.Name
+	default:
+		return ""
+	}
+}
+
+func isGenericReceiver(decl *ast.FuncDecl) bool {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return false
+	}
+	expr := decl.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch expr.(type) {
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return true
+	}
+	return false
+}
+
+// skeletonTestSuffix 返回函数对应测试名中Test之后的部分，方法为Type_Method
+func skeletonTestSuffix(funcInfo *vs.FuncInfo) string {
+	if funcInfo.Receiver != nil {
+		return receiverTypeName(funcInfo.Receiver) + "_" + funcInfo.Name
+	}
+	return funcInfo.Name
+}
+
+// skeletonDisplayName 返回函数的展示名，方法为Type.Method
+func skeletonDisplayName(funcInfo *vs.FuncInfo) string {
+	if funcInfo.Receiver != nil {
+		return receiverTypeName(funcInfo.Receiver) + "." + funcInfo.Name
+	}
+	return funcInfo.Name
+}
+
+// receiverTypeName 返回接收者的类型名，不含指针和类型参数
+func receiverTypeName(receiver *vs.VarInfo) string {
+	name := strings.TrimPrefix(receiver.BaseType, "*")
+	if index := strings.Index(name, "["); index >= 0 {
+		name = name[:index]
+	}
+	return name
+}
+
+// findReceiverStruct 查找接收者对应的结构体定义，非结构体类型返回nil
+func findReceiverStruct(structInfos []*vs.StructInfo, name string,
+	loadFile func(rFilePath string) (*sourceFileInfo, error)) (*vs.StructInfo, *sourceFileInfo, error) {
+	for _, structInfo := range structInfos {
+		if structInfo.Name != name || strings.HasSuffix(structInfo.RFilePath, "_test.go") {
+			continue
+		}
+		structFile, err := loadFile(structInfo.RFilePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if structFile.structs[name] {
+			return structInfo, structFile, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// localTypeString 将VarInfo中带完整导入路径的类型还原为源文件中的写法，并记录需要的导入
+func localTypeString(typeStr string, aliases map[string]string, imports map[string]string) string {
+	paths := sortedMapKeys(aliases)
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i]) > len(paths[j])
+	})
+	for _, path := range paths {
+		alias := aliases[path]
+		typeStr = strings.ReplaceAll(typeStr, path+".", alias+".")
+	}
+	// 记录类型中通过别名引用的包
+	for _, path := range paths {
+		alias := aliases[path]
+		for start := 0; ; {
+			index := strings.Index(typeStr[start:], alias+".")
+			if index < 0 {
+				break
+			}
+			index += start
+			if index == 0 || !isIdentByte(typeStr[index-1]) && typeStr[index-1] != '.' {
+				imports[path] = alias
+				break
+			}
+			start = index + len(alias) + 1
+		}
+	}
+	return typeStr
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
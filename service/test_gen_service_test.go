@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testGenCalc = `package calc
+
+import (
+	"context"
+	stdio "io"
+	"strings"
+)
+
+type Base struct{}
+
+type Calc struct {
+	Base
+	name   string
+	writer stdio.Writer
+}
+
+type Names []string
+
+func Add(a, b int) int { return a + b }
+
+func Join(sep string, parts ...string) string { return strings.Join(parts, sep) }
+
+func (c *Calc) Run(ctx context.Context, r stdio.Reader) ([]byte, int, error) { return nil, 0, nil }
+
+func (n Names) Len() int { return len(n) }
+
+func Map[T any](items []T) []T { return items }
+
+func Tested() {}
+
+func init() {}
+`
+
+func TestGenerateTestSkeletons(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod":            "module example.com/app\n\ngo 1.21\n",
+		"calc/calc.go":      testGenCalc,
+		"calc/calc_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestTested(t *testing.T) {}\n",
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	skeletons, err := GenerateTestSkeletons(modInfo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skeletons) != 1 {
+		t.Fatalf("expected 1 skeleton, got %d", len(skeletons))
+	}
+	skeleton := skeletons[0]
+	if skeleton.FilePath != "calc/calc_skeleton_test.go" {
+		t.Errorf("unexpected skeleton file: %s", skeleton.FilePath)
+	}
+	if strings.Join(skeleton.Funcs, ",") != "Add,Join,Calc.Run,Names.Len" {
+		t.Errorf("unexpected generated funcs: %v", skeleton.Funcs)
+	}
+	content := string(skeleton.Content)
+	for _, want := range []string{`stdio "io"`, "func TestCalc_Run(t *testing.T)", "writer stdio.Writer", "parts []string",
+		"Join(tt.args.sep, tt.args.parts...)", "got, got1, err := r.Run(tt.args.ctx, tt.args.r)", "tt.receiver.Len()"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("skeleton missing %q:\n%s", want, content)
+		}
+	}
+	if err := WriteTestSkeletons(modInfo, skeletons); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteTestSkeletons(modInfo, skeletons); err == nil {
+		t.Error("expected error when overwriting existing skeleton")
+	}
+	// 生成的测试需要能通过类型检查
+	data, err := os.ReadFile(filepath.Join(dir, "calc", "calc_skeleton_test.go"))
+	if err != nil || string(data) != content {
+		t.Fatalf("skeleton not written: %v", err)
+	}
+	pkgs, err := LoadPackages(context.Background(), &LoadConfig{RepoPath: dir, PkgPath: "./calc", LoadEnum: LoadSpecificPkg, Tests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			t.Errorf("generated skeleton does not compile: %v", pkg.Errors)
+		}
+	}
+	if modInfo, err = ParseModule(dir); err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := GenerateTestSkeletons(modInfo, &TestGenConfig{Funcs: []string{"Calc.Run"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 0 {
+		t.Errorf("existing skeleton tests should be skipped, got %v", filtered[0].Funcs)
+	}
+}
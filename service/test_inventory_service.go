@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const (
+	TestKindTest      = "test"      // TestXxx(t *testing.T)
+	TestKindBenchmark = "benchmark" // BenchmarkXxx(b *testing.B)
+	TestKindFuzz      = "fuzz"      // FuzzXxx(f *testing.F)
+	TestKindExample   = "example"   // ExampleXxx()
+	TestKindMain      = "main"      // TestMain(m *testing.M)
+
+	TestLinkName = "name" // 通过命名约定关联
+	TestLinkCall = "call" // 通过调用图关联
+)
+
+// TestCase 测试文件中的一个测试函数
+type TestCase struct {
+	Name      string
+	Kind      string
+	Pkg       string   // 测试所在的包，外部测试包以_test结尾
+	TargetPkg string   // 被测试的包
+	External  bool     // 是否属于外部测试包
+	RFilePath string   // 相对模块根目录的文件路径
+	Line      int      // 起始行
+	SubTests  []string // 以字面量命名的子测试，嵌套子测试用/连接
+	Targets   []*TestTarget
+	FuncInfo  *vs.FuncInfo
+}
+
+// TestTarget 测试关联的被测函数
+type TestTarget struct {
+	Pkg  string
+	Name string // 函数名，方法为Type.Method
+	Via  string // 关联方式
+}
+
+// TestInventory 模块的测试清单
+type TestInventory struct {
+	Module  string
+	Tests   []*TestCase
+	Helpers []*vs.FuncInfo // 测试文件中不属于任何测试类型的函数
+}
+
+// testPrefixes 测试函数前缀及对应的类型和参数类型
+var testPrefixes = []struct {
+	prefix    string
+	kind      string
+	paramType string
+}{
+	{"Test", TestKindTest, "*testing.T"},
+	{"Benchmark", TestKindBenchmark, "*testing.B"},
+	{"Fuzz", TestKindFuzz, "*testing.F"},
+	{"Example", TestKindExample, ""},
+}
+
+// BuildTestInventory 对模块测试文件中的函数分类，并按命名约定关联被测函数
+func BuildTestInventory(modInfo *ModuleInfo) *TestInventory {
+	inventory := &TestInventory{Module: modInfo.Path}
+	for _, testPkg := range sortedMapKeys(modInfo.PkgTestFuncMap) {
+		targetPkg, external := strings.CutSuffix(testPkg, "_test")
+		for _, funcInfo := range modInfo.PkgTestFuncMap[testPkg] {
+			if strings.Contains(funcInfo.Name, "$") {
+				continue
+			}
+			kind := classifyTestFunc(funcInfo)
+			if kind == "" {
+				inventory.Helpers = append(inventory.Helpers, funcInfo)
+				continue
+			}
+			testCase := &TestCase{
+				Name:      funcInfo.Name,
+				Kind:      kind,
+				Pkg:       testPkg,
+				TargetPkg: targetPkg,
+				External:  external,
+				RFilePath: funcInfo.RFilePath,
+				Line:      funcInfo.StartPosition.Line,
+				SubTests:  literalSubTests(funcInfo.Content),
+				FuncInfo:  funcInfo,
+			}
+			if kind != TestKindMain {
+				testCase.Targets = nameLinkedTargets(modInfo, targetPkg, funcInfo.Name)
+			}
+			inventory.Tests = append(inventory.Tests, testCase)
+		}
+	}
+	sort.SliceStable(inventory.Tests, func(i, j int) bool {
+		if inventory.Tests[i].Pkg != inventory.Tests[j].Pkg {
+			return inventory.Tests[i].Pkg < inventory.Tests[j].Pkg
+		}
+		if inventory.Tests[i].RFilePath != inventory.Tests[j].RFilePath {
+			return inventory.Tests[i].RFilePath < inventory.Tests[j].RFilePath
+		}
+		return inventory.Tests[i].Line < inventory.Tests[j].Line
+	})
+	return inventory
+}
+
+// classifyTestFunc 按go test的规则判断测试函数类型，不是测试函数时返回空
+func classifyTestFunc(funcInfo *vs.FuncInfo) string {
+	if funcInfo.Receiver != nil {
+		return ""
+	}
+	if funcInfo.Name == "TestMain" {
+		if len(funcInfo.Params) == 1 && funcInfo.Params[0].Type == "*testing.M" && len(funcInfo.Results) == 0 {
+			return TestKindMain
+		}
+		return ""
+	}
+	for _, testPrefix := range testPrefixes {
+		suffix, ok := strings.CutPrefix(funcInfo.Name, testPrefix.prefix)
+		if !ok || !isTestSuffix(suffix, testPrefix.kind) || len(funcInfo.Results) != 0 {
+			continue
+		}
+		if testPrefix.paramType == "" {
+			if len(funcInfo.Params) == 0 {
+				return testPrefix.kind
+			}
+		} else if len(funcInfo.Params) == 1 && funcInfo.Params[0].Type == testPrefix.paramType {
+			return testPrefix.kind
+		}
+	}
+	return ""
+}
+
+// isTestSuffix 前缀之后为空或不以小写字母开头，Example允许以_开头
+func isTestSuffix(suffix, kind string) bool {
+	if suffix == "" {
+		return true
+	}
+	if kind == TestKindExample && strings.HasPrefix(suffix, "_") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(suffix)
+	return !unicode.IsLower(r)
+}
+
+// literalSubTests 提取以字符串字面量命名的t.Run/b.Run子测试
+func literalSubTests(content string) []string {
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "", chunkParsePrefix+content, parser.SkipObjectResolution)
+	if err != nil || len(file.Decls) == 0 {
+		return nil
+	}
+	funcDecl, ok := file.Decls[0].(*ast.FuncDecl)
+	if !ok || funcDecl.Body == nil {
+		return nil
+	}
+	subTests := make([]string, 0)
+	var visit func(node ast.Node, prefix string)
+	visit = func(node ast.Node, prefix string) {
+		ast.Inspect(node, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 2 {
+				return true
+			}
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || selector.Sel.Name != "Run" {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			name, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return true
+			}
+			// 与testing包一致，空白字符替换为下划线
+			name = prefix + strings.Join(strings.Fields(name), "_")
+			subTests = append(subTests, name)
+			if funcLit, ok := call.Args[1].(*ast.FuncLit); ok {
+				visit(funcLit.Body, name+"/")
+				return false
+			}
+			return true
+		})
+	}
+	visit(funcDecl.Body, "")
+	return subTests
+}
+
+// nameLinkedTargets 按命名约定查找被测函数：TestFoo对应Foo，TestFoo_Bar对应Foo.Bar或Foo
+func nameLinkedTargets(modInfo *ModuleInfo, targetPkg, testName string) []*TestTarget {
+	suffix := testName
+	for _, testPrefix := range testPrefixes {
+		if rest, ok := strings.CutPrefix(testName, testPrefix.prefix); ok {
+			suffix = rest
+			break
+		}
+	}
+	suffix = strings.TrimLeft(suffix, "_")
+	if suffix == "" {
+		return nil
+	}
+	funcs := make(map[string]bool)
+	for _, funcInfo := range modInfo.PkgFuncMap[targetPkg] {
+		if !strings.Contains(funcInfo.Name, "$") {
+			funcs[skeletonDisplayName(funcInfo)] = true
+		}
+	}
+	parts := strings.Split(suffix, "_")
+	candidates := make([]string, 0, 4)
+	if len(parts) >= 2 {
+		candidates = append(candidates, parts[0]+"."+parts[1])
+	}
+	candidates = append(candidates, parts[0])
+	targets := make([]*TestTarget, 0)
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		// 未导出的函数通常以首字母大写的形式出现在测试名中
+		lowered := strings.ToLower(candidate[:1]) + candidate[1:]
+		for _, name := range []string{candidate, lowered} {
+			if funcs[name] {
+				return append(targets, &TestTarget{Pkg: targetPkg, Name: name, Via: TestLinkName})
+			}
+		}
+	}
+	return targets
+}
+
+// LinkTestsByCallGraph 基于CHA调用图将测试关联到其调用的模块内函数，测试文件中的辅助函数会被穿透
+func LinkTestsByCallGraph(ctx context.Context, modInfo *ModuleInfo, inventory *TestInventory) error {
+	pkgs, err := LoadPackages(ctx, &LoadConfig{RepoPath: modInfo.Dir, LoadEnum: LoadCurrentRepo, Tests: true})
+	if err != nil {
+		return err
+	}
+	prog, ssaPkgs := ssautil.AllPackages(pkgs, ssa.InstantiateGenerics)
+	// 只构建模块自身的包，依赖包和生成的测试主包与关联无关
+	for i, ssaPkg := range ssaPkgs {
+		if ssaPkg != nil && !strings.HasSuffix(pkgs[i].ID, ".test") {
+			ssaPkg.Build()
+		}
+	}
+	graph := cha.CallGraph(prog)
+	// 测试变体包中的函数按包路径和名称索引
+	testFuncs := make(map[string]*ssa.Function)
+	for fn := range graph.Nodes {
+		if fn == nil || fn.Pkg == nil || fn.Parent() != nil || !isTestFile(prog.Fset, fn) {
+			continue
+		}
+		testFuncs[fn.Pkg.Pkg.Path()+"."+fn.Name()] = fn
+	}
+	for _, testCase := range inventory.Tests {
+		if testCase.Kind == TestKindMain {
+			continue
+		}
+		fn, ok := testFuncs[testCase.Pkg+"."+testCase.Name]
+		if !ok {
+			continue
+		}
+		linked := make(map[string]bool)
+		for _, target := range testCase.Targets {
+			linked[target.Pkg+"."+target.Name] = true
+		}
+		visited := map[*ssa.Function]bool{fn: true}
+		queue := []*ssa.Function{fn}
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, edge := range graph.Nodes[cur].Out {
+				callee := edge.Callee.Func
+				if callee == nil || visited[callee] || callee.Synthetic != "" {
+					continue
+				}
+				visited[callee] = true
+				if isTestFile(prog.Fset, callee) {
+					queue = append(queue, callee)
+					continue
+				}
+				pkgPath, symbol := ssaSymbol(callee)
+				if !isModulePkg(modInfo.Path, pkgPath) || linked[pkgPath+"."+symbol] {
+					continue
+				}
+				linked[pkgPath+"."+symbol] = true
+				testCase.Targets = append(testCase.Targets, &TestTarget{Pkg: pkgPath, Name: symbol, Via: TestLinkCall})
+			}
+		}
+	}
+	return nil
+}
+
+// isTestFile 判断函数是否定义在测试文件中
+func isTestFile(fileSet *token.FileSet, fn *ssa.Function) bool {
+	if !fn.Pos().IsValid() {
+		return false
+	}
+	return strings.HasSuffix(fileSet.Position(fn.Pos()).Filename, "_test.go")
+}
+
+// TestsForFunc 返回关联到指定函数的测试
+func (inv *TestInventory) TestsForFunc(pkg, name string) []*TestCase {
+	result := make([]*TestCase, 0)
+	for _, testCase := range inv.Tests {
+		for _, target := range testCase.Targets {
+			if target.Pkg == pkg && target.Name == name {
+				result = append(result, testCase)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBuildTestInventory(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"calc/calc.go": `package calc
+
+type Calc struct{}
+
+func (c *Calc) Add(a, b int) int { return a + b }
+
+func parse(s string) int { return len(s) }
+
+func Sum(values ...int) int {
+	total := 0
+	for _, v := range values {
+		total = new(Calc).Add(total, v)
+	}
+	return total
+}
+`,
+		"calc/calc_test.go": `package calc
+
+import "testing"
+
+func TestMain(m *testing.M) { m.Run() }
+
+func TestCalc_Add(t *testing.T) {
+	t.Run("small numbers", func(t *testing.T) {
+		t.Run("zero", func(t *testing.T) {})
+	})
+	for _, name := range []string{"a"} {
+		t.Run(name, func(t *testing.T) {})
+	}
+}
+
+func TestParse(t *testing.T) { parse("x") }
+
+func Testlower(t *testing.T) {}
+
+func Test__Sum(t *testing.T) {}
+
+func Test_(t *testing.T) {}
+
+func BenchmarkSum(b *testing.B) { b.Run("big", func(b *testing.B) {}) }
+
+func FuzzParse(f *testing.F) {}
+
+func helper(t *testing.T) int { return Sum(1, 2) }
+`,
+		"calc/example_test.go": `package calc_test
+
+import (
+	"fmt"
+	"testing"
+
+	"example.com/app/calc"
+)
+
+func ExampleSum() { fmt.Println(calc.Sum(1)) }
+
+func Example_usage() { total() }
+
+func total() int { return calc.Sum(2) }
+
+func TestTotal(t *testing.T) { total() }
+`,
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, funcInfo := range modInfo.PkgFuncMap["example.com/app/calc"] {
+		if strings.HasSuffix(funcInfo.RFilePath, "_test.go") {
+			t.Errorf("test function %s mixed into PkgFuncMap", funcInfo.Name)
+		}
+	}
+	if len(modInfo.PkgTestFuncMap["example.com/app/calc_test"]) != 4 {
+		t.Errorf("expected external test package to be separated: %v", sortedMapKeys(modInfo.PkgTestFuncMap))
+	}
+	inventory := BuildTestInventory(modInfo)
+	tests := make(map[string]*TestCase)
+	for _, testCase := range inventory.Tests {
+		tests[testCase.Name] = testCase
+	}
+	kinds := map[string]string{
+		"TestMain":      TestKindMain,
+		"TestCalc_Add":  TestKindTest,
+		"TestParse":     TestKindTest,
+		"BenchmarkSum":  TestKindBenchmark,
+		"FuzzParse":     TestKindFuzz,
+		"ExampleSum":    TestKindExample,
+		"Example_usage": TestKindExample,
+	}
+	for name, kind := range kinds {
+		if tests[name] == nil || tests[name].Kind != kind {
+			t.Errorf("%s: expected kind %s, got %+v", name, kind, tests[name])
+		}
+	}
+	if _, ok := tests["Testlower"]; ok {
+		t.Error("Testlower is not a test function")
+	}
+	if len(inventory.Helpers) != 3 {
+		t.Errorf("expected 3 helpers, got %d", len(inventory.Helpers))
+	}
+	if got := strings.Join(tests["TestCalc_Add"].SubTests, ","); got != "small_numbers,small_numbers/zero" {
+		t.Errorf("unexpected subtests: %s", got)
+	}
+	if !tests["ExampleSum"].External || tests["ExampleSum"].TargetPkg != "example.com/app/calc" {
+		t.Errorf("unexpected external example: %+v", tests["ExampleSum"])
+	}
+	expectTarget := func(testName, target, via string) {
+		t.Helper()
+		for _, testTarget := range tests[testName].Targets {
+			if testTarget.Name == target && testTarget.Via == via {
+				return
+			}
+		}
+		t.Errorf("%s: expected target %s via %s, got %+v", testName, target, via, tests[testName].Targets)
+	}
+	expectTarget("TestCalc_Add", "Calc.Add", TestLinkName)
+	expectTarget("TestParse", "parse", TestLinkName)
+	expectTarget("BenchmarkSum", "Sum", TestLinkName)
+	expectTarget("Test__Sum", "Sum", TestLinkName)
+	if len(tests["Test_"].Targets) != 0 {
+		t.Errorf("Test_ should not link to any target: %+v", tests["Test_"].Targets)
+	}
+	if err := LinkTestsByCallGraph(context.Background(), modInfo, inventory); err != nil {
+		t.Fatal(err)
+	}
+	expectTarget("Example_usage", "Sum", TestLinkCall)
+	expectTarget("TestParse", "parse", TestLinkName)
+	expectTarget("TestTotal", "Sum", TestLinkCall)
+	// ExampleSum、BenchmarkSum、Test__Sum按命名关联，Example_usage和TestTotal经辅助函数按调用关联
+	if len(inventory.TestsForFunc("example.com/app/calc", "Sum")) != 5 {
+		t.Errorf("unexpected tests for Sum: %d", len(inventory.TestsForFunc("example.com/app/calc", "Sum")))
+	}
+}
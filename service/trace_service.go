@@ -0,0 +1,318 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+)
+
+// DefaultTraceContextLines 源码片段默认在出错行上下各保留的行数
+const DefaultTraceContextLines = 3
+
+var (
+	// goroutineHeaderRe 匹配 goroutine 1 [running]: ，GOTRACEBACK=crash时中间还有gp=、m=等信息
+	goroutineHeaderRe = regexp.MustCompile(`goroutine (\d+)[^\[]*\[([^\]]*)\]:`)
+	// traceFileLineRe 匹配帧的文件行 /path/to/file.go:12 +0x1d
+	traceFileLineRe = regexp.MustCompile(`^(\S+):(\d+)(?: \+0x[0-9a-fA-F]+)?`)
+	// logFileRefRe 匹配普通日志中的 file.go:12 引用
+	logFileRefRe = regexp.MustCompile(`([\w.\-/\\]+\.go):(\d+)`)
+)
+
+// TraceFrame 堆栈中的一帧及其映射到的源码信息
+type TraceFrame struct {
+	Func      string       `json:"func,omitempty"`     // 运行时函数名，如 pkg.(*T).Method.func1
+	Args      string       `json:"args,omitempty"`     // 参数
+	File      string       `json:"file,omitempty"`     // 堆栈中的文件路径
+	Line      int          `json:"line,omitempty"`     // 行号
+	Pkg       string       `json:"pkg,omitempty"`      // 包路径
+	Symbol    string       `json:"symbol,omitempty"`   // 模块内的函数名，方法为Type.Method，匿名函数为Method$1
+	RFilePath string       `json:"rel_file,omitempty"` // 相对模块根目录的文件路径
+	Snippet   string       `json:"snippet,omitempty"`  // 出错行附近的源码
+	Owners    []string     `json:"owners,omitempty"`   // CODEOWNERS中的负责人
+	Link      string       `json:"link,omitempty"`     // 源码链接
+	FuncInfo  *vs.FuncInfo `json:"-"`
+}
+
+// TraceGoroutine 一个goroutine的调用栈
+type TraceGoroutine struct {
+	ID        int           `json:"id"`                   // goroutine编号
+	State     string        `json:"state"`                // 状态，如 running、chan receive, 5 minutes
+	Frames    []*TraceFrame `json:"frames"`               // 从栈顶开始的帧
+	CreatedBy *TraceFrame   `json:"created_by,omitempty"` // 创建该goroutine的位置
+}
+
+// StackTrace 解析后的panic堆栈或goroutine dump
+type StackTrace struct {
+	Message    string            `json:"message,omitempty"`    // panic或fatal error信息
+	Goroutines []*TraceGoroutine `json:"goroutines,omitempty"` // 所有goroutine
+	Refs       []*TraceFrame     `json:"refs,omitempty"`       // 普通日志行中的 file.go:line 引用
+}
+
+// TraceConfig 堆栈符号化配置
+type TraceConfig struct {
+	SourceURL    string // 源码链接模板，{file}和{line}会被替换；为空时链接到本地文件
+	ContextLines int    // 源码片段上下各保留的行数，为0时使用默认值，小于0时不输出片段
+}
+
+// ParseStackTrace 解析panic堆栈、goroutine dump或包含文件行号的日志；
+// 日志系统中以\n转义成单行的堆栈会先还原
+func ParseStackTrace(text string) *StackTrace {
+	if !strings.Contains(text, "\n") && strings.Contains(text, `\n`) {
+		text = strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(text)
+	}
+	trace := &StackTrace{}
+	var goroutine *TraceGoroutine
+	var pending *TraceFrame
+	inMessage := false
+	messages := make([]string, 0)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := goroutineHeaderRe.FindStringSubmatch(line); match != nil {
+			id, _ := strconv.Atoi(match[1])
+			goroutine = &TraceGoroutine{ID: id, State: match[2]}
+			trace.Goroutines = append(trace.Goroutines, goroutine)
+			pending, inMessage = nil, false
+			continue
+		}
+		if goroutine == nil {
+			if index := panicMessageIndex(line); index >= 0 && !inMessage {
+				inMessage = true
+				line = line[index:]
+			}
+			if inMessage {
+				if line == "" {
+					inMessage = false
+				} else {
+					messages = append(messages, line)
+				}
+				continue
+			}
+			for _, match := range logFileRefRe.FindAllStringSubmatch(line, -1) {
+				lineNo, _ := strconv.Atoi(match[2])
+				trace.Refs = append(trace.Refs, &TraceFrame{File: match[1], Line: lineNo})
+			}
+			continue
+		}
+		switch {
+		case line == "":
+			goroutine, pending = nil, nil
+		case pending != nil && traceFileLineRe.MatchString(line):
+			match := traceFileLineRe.FindStringSubmatch(line)
+			pending.File = match[1]
+			pending.Line, _ = strconv.Atoi(match[2])
+			pending = nil
+		case strings.HasPrefix(line, "created by "):
+			name := strings.TrimPrefix(line, "created by ")
+			if index := strings.Index(name, " in goroutine "); index >= 0 {
+				name = name[:index]
+			}
+			pending = &TraceFrame{Func: name}
+			goroutine.CreatedBy = pending
+		case strings.HasSuffix(line, ")") && strings.Contains(line, "("):
+			index := strings.LastIndex(line, "(")
+			pending = &TraceFrame{Func: line[:index], Args: line[index+1 : len(line)-1]}
+			goroutine.Frames = append(goroutine.Frames, pending)
+		default:
+			// ...additional frames elided... 等提示
+			pending = nil
+		}
+	}
+	trace.Message = strings.Join(messages, "\n")
+	return trace
+}
+
+// panicMessageIndex 返回行中panic或fatal error信息的起始位置，行首可能带有日志前缀
+func panicMessageIndex(line string) int {
+	for _, prefix := range []string{"panic: ", "fatal error: "} {
+		if index := strings.Index(line, prefix); index >= 0 {
+			return index
+		}
+	}
+	return -1
+}
+
+// TraceSymbolizer 将堆栈中的帧映射到模块中的函数，并补充源码片段、负责人和链接；
+// 实现了http.Handler，POST堆栈文本即可得到符号化结果
+type TraceSymbolizer struct {
+	modDir     string
+	cfg        TraceConfig
+	symbolizer *funcSymbolizer
+	codeOwners *CodeOwners
+	mu         sync.Mutex
+	fileLines  map[string][]string
+}
+
+// NewTraceSymbolizer 创建堆栈符号化器，会从模块目录向上查找CODEOWNERS
+func NewTraceSymbolizer(modInfo *ModuleInfo, cfg *TraceConfig) (*TraceSymbolizer, error) {
+	modDir, err := filepath.Abs(modInfo.Dir)
+	if err != nil {
+		return nil, err
+	}
+	codeOwners, err := LoadCodeOwners(modDir)
+	if err != nil {
+		return nil, err
+	}
+	traceSymbolizer := &TraceSymbolizer{
+		modDir:     modDir,
+		symbolizer: newFuncSymbolizer(modInfo),
+		codeOwners: codeOwners,
+		fileLines:  make(map[string][]string),
+	}
+	if cfg != nil {
+		traceSymbolizer.cfg = *cfg
+	}
+	if traceSymbolizer.cfg.ContextLines == 0 {
+		traceSymbolizer.cfg.ContextLines = DefaultTraceContextLines
+	}
+	return traceSymbolizer, nil
+}
+
+// Symbolize 符号化堆栈中的所有帧
+func (t *TraceSymbolizer) Symbolize(trace *StackTrace) {
+	for _, goroutine := range trace.Goroutines {
+		for _, frame := range goroutine.Frames {
+			t.symbolizeFrame(frame)
+		}
+		if goroutine.CreatedBy != nil {
+			t.symbolizeFrame(goroutine.CreatedBy)
+		}
+	}
+	for _, frame := range trace.Refs {
+		t.symbolizeFrame(frame)
+	}
+}
+
+func (t *TraceSymbolizer) symbolizeFrame(frame *TraceFrame) {
+	frame.Pkg = runtimeFuncPkg(frame.Func)
+	funcInfo := t.symbolizer.locate(frame.Func, frame.File, frame.Line)
+	if funcInfo == nil {
+		return
+	}
+	frame.FuncInfo = funcInfo
+	frame.Pkg = funcInfo.Pkg
+	frame.Symbol = skeletonDisplayName(funcInfo)
+	frame.RFilePath = filepath.ToSlash(funcInfo.RFilePath)
+	line := frame.Line
+	if line <= 0 || line < funcInfo.StartPosition.Line || line > funcInfo.EndPosition.Line {
+		// 堆栈中的行号与当前源码不一致时定位到函数声明
+		line = funcInfo.StartPosition.Line
+	}
+	absPath := filepath.Join(t.modDir, filepath.FromSlash(frame.RFilePath))
+	frame.Owners = t.codeOwners.OwnersForFile(absPath)
+	if t.cfg.SourceURL != "" {
+		frame.Link = expandSourceURL(t.cfg.SourceURL, frame.RFilePath, line)
+	} else {
+		frame.Link = fmt.Sprintf("%s:%d", absPath, line)
+	}
+	if t.cfg.ContextLines > 0 {
+		frame.Snippet = t.snippet(absPath, line)
+	}
+}
+
+// snippet 读取出错行附近的源码，出错行以>标记
+func (t *TraceSymbolizer) snippet(absPath string, line int) string {
+	t.mu.Lock()
+	lines, ok := t.fileLines[absPath]
+	if !ok {
+		if content, err := os.ReadFile(absPath); err == nil {
+			lines = strings.Split(string(content), "\n")
+		}
+		t.fileLines[absPath] = lines
+	}
+	t.mu.Unlock()
+	if line > len(lines) {
+		return ""
+	}
+	start, end := max(line-t.cfg.ContextLines, 1), min(line+t.cfg.ContextLines, len(lines))
+	var builder strings.Builder
+	for i := start; i <= end; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&builder, "%s%5d  %s\n", marker, i, lines[i-1])
+	}
+	return builder.String()
+}
+
+// ServeHTTP 接收POST的堆栈文本，默认返回文本格式，format=json或Accept为application/json时返回JSON
+func (t *TraceSymbolizer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "请使用POST提交堆栈文本", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 16<<20))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("读取请求失败: %v", err), http.StatusBadRequest)
+		return
+	}
+	trace := ParseStackTrace(string(body))
+	t.Symbolize(trace)
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(trace)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	WriteStackTraceText(w, trace)
+}
+
+// WriteStackTraceText 以文本格式输出符号化后的堆栈
+func WriteStackTraceText(w io.Writer, trace *StackTrace) error {
+	bw := bufio.NewWriter(w)
+	if trace.Message != "" {
+		fmt.Fprintf(bw, "%s\n\n", trace.Message)
+	}
+	for _, goroutine := range trace.Goroutines {
+		fmt.Fprintf(bw, "goroutine %d [%s]:\n", goroutine.ID, goroutine.State)
+		for _, frame := range goroutine.Frames {
+			writeTraceFrame(bw, frame, "")
+		}
+		if goroutine.CreatedBy != nil {
+			writeTraceFrame(bw, goroutine.CreatedBy, "created by ")
+		}
+		fmt.Fprintln(bw)
+	}
+	if len(trace.Refs) > 0 {
+		fmt.Fprintln(bw, "references:")
+		for _, frame := range trace.Refs {
+			writeTraceFrame(bw, frame, "")
+		}
+	}
+	return bw.Flush()
+}
+
+func writeTraceFrame(w io.Writer, frame *TraceFrame, prefix string) {
+	if frame.Func != "" {
+		fmt.Fprintf(w, "%s%s\n", prefix, frame.Func)
+	}
+	fmt.Fprintf(w, "\t%s:%d\n", frame.File, frame.Line)
+	if frame.Symbol == "" {
+		return
+	}
+	fmt.Fprintf(w, "\t=> %s.%s (%s)\n", frame.Pkg, frame.Symbol, frame.Link)
+	if len(frame.Owners) > 0 {
+		fmt.Fprintf(w, "\t   owners: %s\n", strings.Join(frame.Owners, " "))
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(frame.Snippet, "\n"), "\n") {
+		if line != "" {
+			fmt.Fprintf(w, "\t   %s\n", line)
+		}
+	}
+}
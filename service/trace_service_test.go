@@ -0,0 +1,152 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPanicTrace = `{"level":"error","caller":"calc/calc.go:12","msg":"boom"}
+2024/01/02 10:00:00 panic: runtime error: integer divide by zero [recovered]
+	panic: runtime error: integer divide by zero
+
+goroutine 7 [running]:
+example.com/app/calc.(*Calc).Div.func1.1(...)
+	/build/app/calc/calc.go:9
+example.com/app/calc.(*Calc).Div.func1(0x1)
+	/build/app/calc/calc.go:10 +0x1d
+example.com/app/calc.each({0xc000012345, 0x1, 0x1}, 0xc00001)
+	/build/app/calc/calc.go:17 +0x3a
+example.com/app/calc.(*Calc).Div(0x0, {0xc000012345, 0x1, 0x1}, 0x0)
+	/build/app/calc/calc.go:7 +0x5c
+runtime.gopanic({0x1, 0x2})
+	/usr/local/go/src/runtime/panic.go:770 +0x132
+main.main()
+	/build/app/main.go:6 +0x25
+created by example.com/app/server.Start in goroutine 1
+	/build/app/server/server.go:4 +0x1f
+
+goroutine 1 [chan receive, 5 minutes]:
+main.main()
+	/build/app/main.go:7 +0x3
+`
+
+func TestSymbolizeStackTrace(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod":           "module example.com/app\n\ngo 1.21\n",
+		"CODEOWNERS":       "* @team-app\n/calc/ @team-calc\n",
+		"main.go":          "package main\n\nimport \"example.com/app/calc\"\n\nfunc main() {\n\tnew(calc.Calc).Div(nil, 0)\n\tselect {}\n}\n",
+		"server/server.go": "package server\n\nfunc Start() {\n\tgo func() {}()\n}\n",
+		"calc/calc.go": `package calc
+
+type Calc struct{}
+
+func (c *Calc) Div(values []int, d int) []int {
+	result := make([]int, 0)
+	each(values, func(v int) {
+		func() {
+			result = append(result, v/d)
+		}()
+	})
+	return result
+}
+
+func each(values []int, fn func(int)) {
+	for _, v := range values {
+		fn(v)
+	}
+}
+`,
+	})
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 编译器按嵌套层级为匿名函数编号
+	symbolizer := newFuncSymbolizer(modInfo)
+	for name, expected := range map[string]string{
+		"example.com/app/calc.(*Calc).Div.func1.1":    "Div$2",
+		"example.com/app/calc.(*Calc).Div.func1":      "Div$1",
+		"example.com/app/calc.(*Calc).Div.deferwrap1": "Div",
+		"example.com/app/calc.(*Calc).Div.func3":      "Div",
+		"example.com/app/calc.each":                   "each",
+	} {
+		funcInfo, _ := symbolizer.locateByName(name)
+		if funcInfo == nil || funcInfo.Name != expected {
+			t.Errorf("%s: expected %s, got %+v", name, expected, funcInfo)
+		}
+	}
+
+	trace := ParseStackTrace(testPanicTrace)
+	if trace.Message != "panic: runtime error: integer divide by zero [recovered]\npanic: runtime error: integer divide by zero" {
+		t.Errorf("unexpected message: %q", trace.Message)
+	}
+	if len(trace.Goroutines) != 2 || len(trace.Goroutines[0].Frames) != 6 || trace.Goroutines[1].State != "chan receive, 5 minutes" {
+		t.Fatalf("unexpected goroutines: %+v", trace.Goroutines)
+	}
+	if len(trace.Refs) != 1 || trace.Refs[0].File != "calc/calc.go" || trace.Refs[0].Line != 12 {
+		t.Errorf("unexpected refs: %+v", trace.Refs)
+	}
+	escaped := strings.NewReplacer("\n", `\n`, "\t", `\t`).Replace(testPanicTrace)
+	if got := ParseStackTrace(escaped); len(got.Goroutines) != 2 || len(got.Goroutines[0].Frames) != 6 {
+		t.Errorf("failed to parse escaped trace: %+v", got.Goroutines)
+	}
+
+	traceSymbolizer, err := NewTraceSymbolizer(modInfo, &TraceConfig{SourceURL: "https://git.example.com/app/blob/main/{file}#L{line}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	traceSymbolizer.Symbolize(trace)
+	frames := trace.Goroutines[0].Frames
+	symbols := []string{"Div$2", "Div$1", "each", "Calc.Div", "", "main"}
+	for i, symbol := range symbols {
+		if frames[i].Symbol != symbol {
+			t.Errorf("frame %d %s: expected symbol %q, got %q", i, frames[i].Func, symbol, frames[i].Symbol)
+		}
+	}
+	if frames[0].Link != "https://git.example.com/app/blob/main/calc/calc.go#L9" || strings.Join(frames[0].Owners, ",") != "@team-calc" {
+		t.Errorf("unexpected link or owners: %+v", frames[0])
+	}
+	if !strings.Contains(frames[0].Snippet, ">    9  \t\t\tresult = append(result, v/d)") {
+		t.Errorf("unexpected snippet:\n%s", frames[0].Snippet)
+	}
+	if frames[5].Pkg != "example.com/app" || strings.Join(frames[5].Owners, ",") != "@team-app" {
+		t.Errorf("unexpected main frame: %+v", frames[5])
+	}
+	if createdBy := trace.Goroutines[0].CreatedBy; createdBy == nil || createdBy.Symbol != "Start" || createdBy.Line != 4 {
+		t.Errorf("unexpected created by: %+v", createdBy)
+	}
+	if trace.Refs[0].Symbol != "Calc.Div" {
+		t.Errorf("unexpected ref symbol: %+v", trace.Refs[0])
+	}
+	var text strings.Builder
+	if err := WriteStackTraceText(&text, trace); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "=> example.com/app/calc.Div$2 (https://git.example.com/app/blob/main/calc/calc.go#L9)") {
+		t.Errorf("unexpected text output:\n%s", text.String())
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/symbolize?format=json", strings.NewReader(testPanicTrace))
+	recorder := httptest.NewRecorder()
+	traceSymbolizer.ServeHTTP(recorder, request)
+	var served StackTrace
+	if err := json.Unmarshal(recorder.Body.Bytes(), &served); err != nil {
+		t.Fatalf("invalid json response: %v\n%s", err, recorder.Body.String())
+	}
+	if len(served.Goroutines) != 2 || served.Goroutines[0].Frames[1].Symbol != "Div$1" {
+		t.Errorf("unexpected served trace: %s", recorder.Body.String())
+	}
+	recorder = httptest.NewRecorder()
+	traceSymbolizer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/symbolize", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", recorder.Code)
+	}
+}
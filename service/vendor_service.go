@@ -0,0 +1,280 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+)
+
+const (
+	VendorIssueMissing         = "missing"          // go.mod中的依赖未出现在modules.txt中
+	VendorIssueVersionMismatch = "version_mismatch" // 版本不一致
+	VendorIssueNotExplicit     = "not_explicit"     // go.mod中的依赖未标记为explicit
+	VendorIssueUnrequired      = "unrequired"       // modules.txt中标记explicit的模块不在go.mod中
+	VendorIssueReplace         = "replace_mismatch" // replace与modules.txt不一致
+	VendorIssuePackageMissing  = "package_missing"  // modules.txt中列出的包在vendor目录中不存在
+)
+
+// VendorModule vendor/modules.txt中的一个模块
+type VendorModule struct {
+	Path       string   // 模块路径
+	Version    string   // 模块版本，仅有替换规则时为空
+	NewPath    string   // 替换后的路径
+	NewVersion string   // 替换后的版本
+	Explicit   bool     // 是否在go.mod中直接require
+	GoVersion  string   // 模块go.mod中的go版本
+	Packages   []string // vendor中包含的包
+	Line       int      // 在modules.txt中的行号
+}
+
+// VendorInfo vendor/modules.txt的解析结果
+type VendorInfo struct {
+	Dir     string          // vendor目录
+	Modules []*VendorModule // 按出现顺序排列的模块
+}
+
+// VendorIssue modules.txt与go.mod的不一致
+type VendorIssue struct {
+	Kind    string // 问题类型
+	Path    string // 模块路径
+	Line    int    // modules.txt中的行号，无对应条目时为0
+	Message string // 问题描述
+}
+
+// ParseVendorModulesTxt 解析vendor/modules.txt
+func ParseVendorModulesTxt(path string) (*VendorInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info := &VendorInfo{Dir: filepath.Dir(path)}
+	var current *VendorModule
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "## "):
+			if current == nil {
+				return nil, fmt.Errorf("modules.txt第%d行的注解没有对应的模块", lineNum)
+			}
+			for _, annotation := range strings.Split(strings.TrimPrefix(line, "## "), ";") {
+				annotation = strings.TrimSpace(annotation)
+				if annotation == "explicit" {
+					current.Explicit = true
+				} else if goVersion, ok := strings.CutPrefix(annotation, "go "); ok {
+					current.GoVersion = goVersion
+				}
+			}
+		case strings.HasPrefix(line, "# "):
+			fields := strings.Fields(strings.TrimPrefix(line, "# "))
+			current = &VendorModule{Path: fields[0], Line: lineNum}
+			rest := fields[1:]
+			if len(rest) > 0 && rest[0] != "=>" {
+				current.Version, rest = rest[0], rest[1:]
+			}
+			if len(rest) > 0 {
+				if rest[0] != "=>" || len(rest) < 2 {
+					return nil, fmt.Errorf("modules.txt第%d行格式错误: %s", lineNum, line)
+				}
+				current.NewPath = rest[1]
+				if len(rest) > 2 {
+					current.NewVersion = rest[2]
+				}
+			}
+			info.Modules = append(info.Modules, current)
+		default:
+			if current == nil {
+				return nil, fmt.Errorf("modules.txt第%d行的包没有对应的模块", lineNum)
+			}
+			current.Packages = append(current.Packages, line)
+		}
+	}
+	return info, scanner.Err()
+}
+
+// ValidateVendor 检查vendor/modules.txt与go.mod中的require、replace是否一致
+func ValidateVendor(modInfo *ModuleInfo) []*VendorIssue {
+	issues := make([]*VendorIssue, 0)
+	if modInfo.Vendor == nil {
+		return issues
+	}
+	vendorMap := make(map[string]*VendorModule)
+	replaceOnly := make(map[string]*VendorModule)
+	for _, vendorModule := range modInfo.Vendor.Modules {
+		if vendorModule.Version == "" {
+			replaceOnly[vendorModule.Path] = vendorModule
+		} else {
+			vendorMap[vendorModule.Path] = vendorModule
+		}
+	}
+	requireMap := make(map[string]Dependency)
+	for _, req := range modInfo.Requires {
+		requireMap[req.Path] = req
+		vendorModule, ok := vendorMap[req.Path]
+		switch {
+		case !ok:
+			issues = append(issues, &VendorIssue{
+				Kind:    VendorIssueMissing,
+				Path:    req.Path,
+				Message: fmt.Sprintf("go.mod中require了%s@%s，但modules.txt中没有记录", req.Path, req.Version),
+			})
+			continue
+		case vendorModule.Version != req.Version:
+			issues = append(issues, &VendorIssue{
+				Kind:    VendorIssueVersionMismatch,
+				Path:    req.Path,
+				Line:    vendorModule.Line,
+				Message: fmt.Sprintf("go.mod中的版本为%s，modules.txt中为%s", req.Version, vendorModule.Version),
+			})
+		case !vendorModule.Explicit:
+			issues = append(issues, &VendorIssue{
+				Kind:    VendorIssueNotExplicit,
+				Path:    req.Path,
+				Line:    vendorModule.Line,
+				Message: "go.mod中直接require的模块在modules.txt中未标记为explicit",
+			})
+		}
+	}
+	for _, vendorModule := range modInfo.Vendor.Modules {
+		if vendorModule.Version != "" && vendorModule.Explicit {
+			if _, ok := requireMap[vendorModule.Path]; !ok {
+				issues = append(issues, &VendorIssue{
+					Kind:    VendorIssueUnrequired,
+					Path:    vendorModule.Path,
+					Line:    vendorModule.Line,
+					Message: "modules.txt中标记为explicit的模块不在go.mod的require中",
+				})
+			}
+		}
+		for _, pkg := range vendorModule.Packages {
+			if _, err := os.Stat(filepath.Join(modInfo.Vendor.Dir, filepath.FromSlash(pkg))); err != nil {
+				issues = append(issues, &VendorIssue{
+					Kind:    VendorIssuePackageMissing,
+					Path:    vendorModule.Path,
+					Line:    vendorModule.Line,
+					Message: fmt.Sprintf("包%s不存在于vendor目录中", pkg),
+				})
+			}
+		}
+	}
+	issues = append(issues, validateVendorReplaces(modInfo, vendorMap, replaceOnly)...)
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Path < issues[j].Path
+	})
+	return issues
+}
+
+// validateVendorReplaces 检查go.mod中的replace是否都反映在modules.txt中，反之亦然
+func validateVendorReplaces(modInfo *ModuleInfo, vendorMap, replaceOnly map[string]*VendorModule) []*VendorIssue {
+	issues := make([]*VendorIssue, 0)
+	replaced := make(map[string]bool)
+	for _, replace := range modInfo.Replaces {
+		replaced[replace.OldPath] = true
+		vendorModule := replaceOnly[replace.OldPath]
+		if replace.OldVersion != "" || vendorModule == nil {
+			vendorModule = vendorMap[replace.OldPath]
+			if vendorModule != nil && replace.OldVersion != "" && vendorModule.Version != replace.OldVersion {
+				// 针对其他版本的替换规则不生效
+				continue
+			}
+		}
+		if vendorModule == nil {
+			if _, required := findRequire(modInfo.Requires, replace.OldPath); required {
+				issues = append(issues, &VendorIssue{
+					Kind:    VendorIssueReplace,
+					Path:    replace.OldPath,
+					Message: fmt.Sprintf("go.mod中的replace %s => %s 未记录在modules.txt中", replace.OldPath, replace.NewPath),
+				})
+			}
+			continue
+		}
+		if vendorModule.NewPath != replace.NewPath || vendorModule.NewVersion != replace.NewVersion {
+			issues = append(issues, &VendorIssue{
+				Kind:    VendorIssueReplace,
+				Path:    replace.OldPath,
+				Line:    vendorModule.Line,
+				Message: fmt.Sprintf("go.mod中替换为%s %s，modules.txt中为%s %s", replace.NewPath, replace.NewVersion, vendorModule.NewPath, vendorModule.NewVersion),
+			})
+		}
+	}
+	for _, vendorModule := range modInfo.Vendor.Modules {
+		if vendorModule.NewPath != "" && !replaced[vendorModule.Path] {
+			issues = append(issues, &VendorIssue{
+				Kind:    VendorIssueReplace,
+				Path:    vendorModule.Path,
+				Line:    vendorModule.Line,
+				Message: "modules.txt中的替换规则在go.mod中不存在",
+			})
+		}
+	}
+	return issues
+}
+
+func findRequire(requires []Dependency, path string) (Dependency, bool) {
+	for _, req := range requires {
+		if req.Path == path {
+			return req, true
+		}
+	}
+	return Dependency{}, false
+}
+
+// AppendVendorModules 按modules.txt将vendor中的包归属到真实的依赖模块并解析
+func AppendVendorModules(modInfo *ModuleInfo) error {
+	if modInfo.Vendor == nil {
+		return nil
+	}
+	modInfo.VendorModules = make([]*ModuleInfo, 0)
+	for _, vendorModule := range modInfo.Vendor.Modules {
+		if len(vendorModule.Packages) == 0 {
+			continue
+		}
+		depInfo := &ModuleInfo{
+			Path:         vendorModule.Path,
+			Version:      vendorModule.Version,
+			Dir:          filepath.Join(modInfo.Vendor.Dir, filepath.FromSlash(vendorModule.Path)),
+			GoVersion:    vendorModule.GoVersion,
+			ThirdParty:   true,
+			PkgFuncMap:   make(map[string][]*vs.FuncInfo),
+			PkgVarMap:    make(map[string][]*vs.VarInfo),
+			PkgStructMap: make(map[string][]*vs.StructInfo),
+		}
+		// 子目录可能属于其他模块，只解析modules.txt中列出的包目录
+		for _, pkg := range vendorModule.Packages {
+			pkgDir := filepath.Join(modInfo.Vendor.Dir, filepath.FromSlash(pkg))
+			entries, err := os.ReadDir(pkgDir)
+			if err != nil {
+				return fmt.Errorf("读取vendor包 %s 失败: %w", pkg, err)
+			}
+			for _, entry := range entries {
+				if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") || strings.HasSuffix(entry.Name(), "_test.go") {
+					continue
+				}
+				filePath := filepath.Join(pkgDir, entry.Name())
+				rFilePath, err := filepath.Rel(depInfo.Dir, filePath)
+				if err != nil {
+					return err
+				}
+				fileFuncVisitor, err := ParseSingleFile(pkg, filepath.ToSlash(rFilePath), filePath)
+				if err != nil {
+					return fmt.Errorf("解析vendor文件 %s 失败: %w", filePath, err)
+				}
+				depInfo.PkgFuncMap[pkg] = append(depInfo.PkgFuncMap[pkg], fileFuncVisitor.FileFuncInfos...)
+				depInfo.PkgVarMap[pkg] = append(depInfo.PkgVarMap[pkg], fileFuncVisitor.FilePkgVars...)
+				depInfo.PkgStructMap[pkg] = append(depInfo.PkgStructMap[pkg], fileFuncVisitor.FileStructs...)
+			}
+		}
+		modInfo.VendorModules = append(modInfo.VendorModules, depInfo)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestVendorModules(t *testing.T) {
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": `module example.com/app
+
+go 1.21
+
+require (
+	example.com/lib v1.2.0
+	example.com/other v0.3.0
+	example.com/missing v1.0.0
+)
+
+replace example.com/other => ../other
+`,
+		"main.go": "package main\n\nimport \"example.com/lib/util\"\n\nfunc main() { util.Do() }\n",
+		"vendor/modules.txt": `# example.com/lib v1.1.0
+## explicit; go 1.20
+example.com/lib/util
+# example.com/other v0.3.0 => ../other
+example.com/other
+# example.com/stray v0.0.1
+## explicit
+example.com/stray/gone
+`,
+		"vendor/example.com/lib/util/util.go":        "package util\n\nfunc Do() {}\n",
+		"vendor/example.com/lib/util/inner/inner.go": "package inner\n\nfunc Skip() {}\n",
+		"vendor/example.com/other/other.go":          "package other\n\nfunc Other() {}\n",
+	})
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modInfo.Vendor == nil || len(modInfo.Vendor.Modules) != 3 {
+		t.Fatalf("unexpected vendor info: %+v", modInfo.Vendor)
+	}
+	lib := modInfo.Vendor.Modules[0]
+	if !lib.Explicit || lib.GoVersion != "1.20" || lib.Line != 1 || len(lib.Packages) != 1 {
+		t.Errorf("unexpected vendor module: %+v", lib)
+	}
+	if other := modInfo.Vendor.Modules[1]; other.NewPath != "../other" || other.Explicit {
+		t.Errorf("unexpected replaced vendor module: %+v", other)
+	}
+	for pkg := range modInfo.PkgFuncMap {
+		if pkg != "example.com/app" {
+			t.Errorf("vendored package %s parsed as own package", pkg)
+		}
+	}
+	kinds := make(map[string]bool)
+	for _, issue := range ValidateVendor(modInfo) {
+		kinds[issue.Kind+" "+issue.Path] = true
+	}
+	expected := []string{
+		VendorIssueVersionMismatch + " example.com/lib",
+		VendorIssueNotExplicit + " example.com/other",
+		VendorIssueMissing + " example.com/missing",
+		VendorIssueUnrequired + " example.com/stray",
+		VendorIssuePackageMissing + " example.com/stray",
+	}
+	for _, key := range expected {
+		if !kinds[key] {
+			t.Errorf("expected issue %s", key)
+		}
+	}
+	if len(kinds) != len(expected) {
+		t.Errorf("unexpected issues: %v", kinds)
+	}
+	if err := AppendVendorModules(modInfo); err == nil {
+		t.Fatal("expected error for missing vendored package")
+	}
+	modInfo.Vendor.Modules = modInfo.Vendor.Modules[:2]
+	if err := AppendVendorModules(modInfo); err != nil {
+		t.Fatal(err)
+	}
+	if len(modInfo.VendorModules) != 2 {
+		t.Fatalf("expected 2 vendored modules, got %d", len(modInfo.VendorModules))
+	}
+	libInfo := modInfo.VendorModules[0]
+	funcs := libInfo.PkgFuncMap["example.com/lib/util"]
+	if !libInfo.ThirdParty || libInfo.Version != "v1.1.0" || len(funcs) != 1 || funcs[0].Name != "Do" {
+		t.Errorf("unexpected vendored module info: %+v", libInfo)
+	}
+	if _, ok := libInfo.PkgFuncMap["example.com/lib/util/inner"]; ok {
+		t.Error("unlisted vendored package should not be parsed")
+	}
+}
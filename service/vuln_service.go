@@ -0,0 +1,513 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	vs "github.com/Silhouette-sophist/static_parser/visitor"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const (
+	// OSVStdlibModule OSV中标准库的模块名
+	OSVStdlibModule = "stdlib"
+
+	VulnLevelModule  = "module"  // 仅依赖了存在漏洞的模块
+	VulnLevelPackage = "package" // 导入了存在漏洞的包
+	VulnLevelSymbol  = "symbol"  // 存在漏洞的函数可从自有代码调用到
+)
+
+// OSVEntry OSV格式的漏洞条目，仅包含匹配所需字段
+type OSVEntry struct {
+	ID       string        `json:"id"`
+	Summary  string        `json:"summary"`
+	Details  string        `json:"details"`
+	Aliases  []string      `json:"aliases"`
+	Affected []OSVAffected `json:"affected"`
+}
+
+// OSVAffected 受影响的模块及版本范围
+type OSVAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges            []OSVRange `json:"ranges"`
+	EcosystemSpecific struct {
+		Imports []OSVImport `json:"imports"`
+	} `json:"ecosystem_specific"`
+}
+
+// OSVRange 版本区间，Go生态中的版本号不带v前缀
+type OSVRange struct {
+	Type   string `json:"type"`
+	Events []struct {
+		Introduced   string `json:"introduced,omitempty"`
+		Fixed        string `json:"fixed,omitempty"`
+		LastAffected string `json:"last_affected,omitempty"`
+	} `json:"events"`
+}
+
+// OSVImport 存在漏洞的包及函数，Symbols为空表示整个包
+type OSVImport struct {
+	Path    string   `json:"path"`
+	Symbols []string `json:"symbols"`
+}
+
+// VulnFinding 单个漏洞的匹配结果
+type VulnFinding struct {
+	OSV          *OSVEntry          // 漏洞条目
+	Module       string             // 受影响的模块
+	Version      string             // 当前依赖的版本
+	FixedVersion string             // 修复版本，未修复为空
+	Level        string             // 影响程度：module/package/symbol
+	Packages     []string           // 被自有代码导入的受影响包
+	Reachable    []*ReachableSymbol // 可从自有代码调用到的漏洞函数
+}
+
+// ReachableSymbol 可达的漏洞函数及调用链
+type ReachableSymbol struct {
+	Pkg       string       // 漏洞函数所在包
+	Symbol    string       // 漏洞函数名，方法为 Type.Method
+	CallStack []string     // 从自有函数到漏洞函数的调用链
+	Entry     *vs.FuncInfo // 调用链起点对应的自有函数
+}
+
+// VulnReport 模块的漏洞扫描报告
+type VulnReport struct {
+	Module    string
+	Findings  []*VulnFinding
+	Unmatched []*VulnUnmatched // 无法按版本匹配漏洞的依赖
+}
+
+// VulnUnmatched 无法确定版本、未参与漏洞匹配的依赖
+type VulnUnmatched struct {
+	Module  string // 依赖的模块
+	Version string // go.mod中的版本
+	Reason  string // 无法匹配的原因
+}
+
+// VulnScanConfig 漏洞扫描配置
+type VulnScanConfig struct {
+	SkipReachability bool // 跳过调用图分析，只做版本匹配
+}
+
+// LoadOSVDatabase 从本地目录或zip文件加载OSV漏洞条目，无法识别的json文件会被忽略
+func LoadOSVDatabase(path string) ([]*OSVEntry, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取漏洞库 %s 失败: %w", path, err)
+	}
+	entries := make([]*OSVEntry, 0)
+	addEntry := func(data []byte) {
+		entry := &OSVEntry{}
+		if err := json.Unmarshal(data, entry); err == nil && entry.ID != "" && len(entry.Affected) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	if !stat.IsDir() {
+		reader, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("打开漏洞库压缩包失败: %w", err)
+		}
+		defer reader.Close()
+		for _, file := range reader.File {
+			if !strings.HasSuffix(file.Name, ".json") {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("读取 %s 失败: %w", file.Name, err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, fmt.Errorf("读取 %s 失败: %w", file.Name, err)
+			}
+			addEntry(data)
+		}
+	} else {
+		err = filepath.WalkDir(path, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+				return nil
+			}
+			data, err := os.ReadFile(filePath)
+			if err != nil {
+				return err
+			}
+			addEntry(data)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("读取漏洞库目录失败: %w", err)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// ScanVulnerabilities 根据依赖版本匹配漏洞，并通过调用图判断漏洞函数是否可从自有代码调用到
+func ScanVulnerabilities(ctx context.Context, modInfo *ModuleInfo, entries []*OSVEntry, cfg *VulnScanConfig) (*VulnReport, error) {
+	if cfg == nil {
+		cfg = &VulnScanConfig{}
+	}
+	versions, unmatched := requiredVersions(modInfo)
+	report := &VulnReport{Module: modInfo.Path, Unmatched: unmatched}
+	for _, entry := range entries {
+		for _, affected := range entry.Affected {
+			if affected.Package.Ecosystem != "" && affected.Package.Ecosystem != "Go" {
+				continue
+			}
+			version, ok := versions[affected.Package.Name]
+			if !ok || !osvAffects(affected.Ranges, version) {
+				continue
+			}
+			report.Findings = append(report.Findings, &VulnFinding{
+				OSV:          entry,
+				Module:       affected.Package.Name,
+				Version:      version,
+				FixedVersion: osvFixedVersion(affected.Ranges, version),
+				Level:        VulnLevelModule,
+			})
+		}
+	}
+	if len(report.Findings) == 0 || cfg.SkipReachability {
+		return report, nil
+	}
+	analysis, err := newReachabilityAnalysis(ctx, modInfo)
+	if err != nil {
+		return report, err
+	}
+	for _, finding := range report.Findings {
+		for _, affected := range finding.OSV.Affected {
+			if affected.Package.Name != finding.Module {
+				continue
+			}
+			for _, imp := range affected.EcosystemSpecific.Imports {
+				if !analysis.imported[imp.Path] {
+					continue
+				}
+				finding.Packages = append(finding.Packages, imp.Path)
+				finding.Level = VulnLevelPackage
+				finding.Reachable = append(finding.Reachable, analysis.reachableSymbols(imp)...)
+			}
+		}
+		if len(finding.Reachable) > 0 {
+			finding.Level = VulnLevelSymbol
+		}
+	}
+	return report, nil
+}
+
+// requiredVersions 返回依赖模块实际使用的版本，标准库使用toolchain或go指令的版本；
+// 替换为本地目录或其他模块的依赖以及无法识别的Go版本作为无法匹配的依赖返回
+func requiredVersions(modInfo *ModuleInfo) (map[string]string, []*VulnUnmatched) {
+	versions := make(map[string]string)
+	unmatched := make([]*VulnUnmatched, 0)
+	for _, req := range modInfo.Requires {
+		version := req.Version
+		if replace := findReplaceRule(modInfo.Replaces, req); replace != nil {
+			if modfile.IsDirectoryPath(replace.NewPath) {
+				unmatched = append(unmatched, &VulnUnmatched{Module: req.Path, Version: req.Version, Reason: "替换为本地目录 " + replace.NewPath})
+				continue
+			}
+			if replace.NewPath != req.Path {
+				unmatched = append(unmatched, &VulnUnmatched{Module: req.Path, Version: req.Version, Reason: "替换为其他模块 " + replace.NewPath + "@" + replace.NewVersion})
+				continue
+			}
+			version = replace.NewVersion
+		}
+		versions[req.Path] = version
+	}
+	goVersion := modInfo.Toolchain
+	if goVersion == "" && modInfo.GoVersion != "" {
+		goVersion = "go" + modInfo.GoVersion
+	}
+	if goVersion != "" {
+		if version := goVersionToSemver(goVersion); version != "" {
+			versions[OSVStdlibModule] = version
+		} else {
+			unmatched = append(unmatched, &VulnUnmatched{Module: OSVStdlibModule, Version: goVersion, Reason: "无法识别的Go版本"})
+		}
+	}
+	return versions, unmatched
+}
+
+// goVersionToSemver 将go1.21、go1.21.3、go1.21rc2形式的Go版本转换为semver，如v1.21.0-rc.2，无法识别时返回空
+func goVersionToSemver(goVersion string) string {
+	version := strings.TrimPrefix(goVersion, "go")
+	prerelease := ""
+	for _, tag := range []string{"rc", "beta", "alpha"} {
+		if index := strings.Index(version, tag); index >= 0 {
+			prerelease = "-" + tag + "." + version[index+len(tag):]
+			version = version[:index]
+			break
+		}
+	}
+	parts := strings.Split(version, ".")
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	result := "v" + strings.Join(parts, ".") + prerelease
+	if !semver.IsValid(result) || semver.Canonical(result) != result {
+		return ""
+	}
+	return result
+}
+
+// osvAffects 判断版本是否落在任一SEMVER区间内
+func osvAffects(ranges []OSVRange, version string) bool {
+	for _, r := range ranges {
+		if r.Type != "SEMVER" {
+			continue
+		}
+		affected := false
+		for _, event := range sortedOSVEvents(r) {
+			switch {
+			case event.kind == "introduced" && (event.version == "" || semver.Compare(version, event.version) >= 0):
+				affected = true
+			case event.kind == "fixed" && semver.Compare(version, event.version) >= 0:
+				affected = false
+			case event.kind == "last_affected" && semver.Compare(version, event.version) > 0:
+				affected = false
+			}
+		}
+		if affected {
+			return true
+		}
+	}
+	return false
+}
+
+// osvFixedVersion 返回当前版本之后最近的修复版本
+func osvFixedVersion(ranges []OSVRange, version string) string {
+	fixed := ""
+	for _, r := range ranges {
+		for _, event := range sortedOSVEvents(r) {
+			if event.kind == "fixed" && semver.Compare(event.version, version) > 0 && (fixed == "" || semver.Compare(event.version, fixed) < 0) {
+				fixed = event.version
+			}
+		}
+	}
+	return fixed
+}
+
+type osvEvent struct {
+	kind    string
+	version string // 带v前缀，introduced为0时为空
+}
+
+func sortedOSVEvents(r OSVRange) []osvEvent {
+	events := make([]osvEvent, 0, len(r.Events))
+	toSemver := func(v string) string {
+		if v == "0" {
+			return ""
+		}
+		return "v" + strings.TrimPrefix(v, "v")
+	}
+	for _, event := range r.Events {
+		switch {
+		case event.Introduced != "":
+			events = append(events, osvEvent{kind: "introduced", version: toSemver(event.Introduced)})
+		case event.Fixed != "":
+			events = append(events, osvEvent{kind: "fixed", version: toSemver(event.Fixed)})
+		case event.LastAffected != "":
+			events = append(events, osvEvent{kind: "last_affected", version: toSemver(event.LastAffected)})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return semver.Compare(events[i].version, events[j].version) < 0
+	})
+	return events
+}
+
+// reachabilityAnalysis 基于CHA调用图的可达性分析结果
+type reachabilityAnalysis struct {
+	modInfo  *ModuleInfo
+	imported map[string]bool                     // 自有代码直接或间接导入的包
+	parent   map[*ssa.Function]*ssa.Function     // 从自有函数出发的BFS前驱
+	reached  map[string]map[string]*ssa.Function // 包路径 -> 函数符号 -> 可达函数
+	graph    *callgraph.Graph
+}
+
+func newReachabilityAnalysis(ctx context.Context, modInfo *ModuleInfo) (*reachabilityAnalysis, error) {
+	pkgs, err := LoadPackages(ctx, &LoadConfig{RepoPath: modInfo.Dir, LoadEnum: LoadCurrentRepo})
+	if err != nil {
+		return nil, err
+	}
+	analysis := &reachabilityAnalysis{
+		modInfo:  modInfo,
+		imported: make(map[string]bool),
+		parent:   make(map[*ssa.Function]*ssa.Function),
+		reached:  make(map[string]map[string]*ssa.Function),
+	}
+	for _, pkg := range pkgs {
+		for _, dep := range packagesClosure(pkg) {
+			analysis.imported[dep] = true
+		}
+	}
+	prog, _ := ssautil.AllPackages(pkgs, ssa.InstantiateGenerics)
+	prog.Build()
+	analysis.graph = cha.CallGraph(prog)
+	// 优先从没有被自有代码调用的入口函数出发，使调用链尽量从入口开始
+	entries, others := make([]*ssa.Function, 0), make([]*ssa.Function, 0)
+	for fn, node := range analysis.graph.Nodes {
+		if fn == nil || fn.Pkg == nil || !isModulePkg(modInfo.Path, fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		calledByOwn := false
+		for _, edge := range node.In {
+			caller := edge.Caller.Func
+			if caller != nil && caller != fn && caller.Pkg != nil && isModulePkg(modInfo.Path, caller.Pkg.Pkg.Path()) {
+				calledByOwn = true
+				break
+			}
+		}
+		if calledByOwn {
+			others = append(others, fn)
+		} else {
+			entries = append(entries, fn)
+		}
+	}
+	// 按名字排序保证调用链稳定
+	byName := func(fns []*ssa.Function) {
+		sort.Slice(fns, func(i, j int) bool {
+			return fns[i].String() < fns[j].String()
+		})
+	}
+	byName(entries)
+	byName(others)
+	visited := make(map[*ssa.Function]bool)
+	for _, roots := range [][]*ssa.Function{entries, others} {
+		queue := make([]*ssa.Function, 0)
+		for _, fn := range roots {
+			if !visited[fn] {
+				visited[fn] = true
+				queue = append(queue, fn)
+			}
+		}
+		for len(queue) > 0 {
+			fn := queue[0]
+			queue = queue[1:]
+			if pkgPath, symbol := ssaSymbol(fn); pkgPath != "" {
+				if analysis.reached[pkgPath] == nil {
+					analysis.reached[pkgPath] = make(map[string]*ssa.Function)
+				}
+				if _, ok := analysis.reached[pkgPath][symbol]; !ok {
+					analysis.reached[pkgPath][symbol] = fn
+				}
+			}
+			for _, edge := range analysis.graph.Nodes[fn].Out {
+				callee := edge.Callee.Func
+				if callee == nil || visited[callee] {
+					continue
+				}
+				visited[callee] = true
+				analysis.parent[callee] = fn
+				queue = append(queue, callee)
+			}
+		}
+	}
+	return analysis, nil
+}
+
+// reachableSymbols 返回OSV中列出的、从自有代码可达的函数
+func (a *reachabilityAnalysis) reachableSymbols(imp OSVImport) []*ReachableSymbol {
+	symbols := imp.Symbols
+	if len(symbols) == 0 {
+		for symbol := range a.reached[imp.Path] {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+	}
+	result := make([]*ReachableSymbol, 0)
+	for _, symbol := range symbols {
+		fn, ok := a.reached[imp.Path][symbol]
+		if !ok {
+			continue
+		}
+		stack := make([]string, 0)
+		var entry *ssa.Function
+		for cur := fn; cur != nil; cur = a.parent[cur] {
+			stack = append([]string{cur.String()}, stack...)
+			entry = cur
+		}
+		result = append(result, &ReachableSymbol{
+			Pkg:       imp.Path,
+			Symbol:    symbol,
+			CallStack: stack,
+			Entry:     a.entryFuncInfo(entry),
+		})
+	}
+	return result
+}
+
+// entryFuncInfo 将调用链起点的SSA函数映射回解析得到的FuncInfo
+func (a *reachabilityAnalysis) entryFuncInfo(fn *ssa.Function) *vs.FuncInfo {
+	for fn.Parent() != nil {
+		fn = fn.Parent()
+	}
+	pkgPath, symbol := ssaSymbol(fn)
+	recvType, name, isMethod := strings.Cut(symbol, ".")
+	if !isMethod {
+		recvType, name = "", symbol
+	}
+	for _, funcInfo := range a.modInfo.PkgFuncMap[pkgPath] {
+		if funcInfo.Name != name {
+			continue
+		}
+		if (funcInfo.Receiver == nil) == (recvType == "") && (funcInfo.Receiver == nil || funcInfo.Receiver.BaseType == recvType) {
+			return funcInfo
+		}
+	}
+	return nil
+}
+
+// ssaSymbol 返回函数所在包和OSV格式的符号名，闭包归属到外层函数
+func ssaSymbol(fn *ssa.Function) (string, string) {
+	for fn.Parent() != nil {
+		fn = fn.Parent()
+	}
+	if fn.Pkg == nil {
+		if fn.Origin() != nil {
+			fn = fn.Origin()
+		}
+		if fn.Pkg == nil {
+			return "", ""
+		}
+	}
+	name := fn.Name()
+	if recv := fn.Signature.Recv(); recv != nil {
+		recvType := recv.Type()
+		if ptr, ok := recvType.(*types.Pointer); ok {
+			recvType = ptr.Elem()
+		}
+		if named, ok := recvType.(*types.Named); ok {
+			name = named.Obj().Name() + "." + name
+		}
+	}
+	return fn.Pkg.Pkg.Path(), name
+}
+
+func isModulePkg(modPath, pkgPath string) bool {
+	return pkgPath == modPath || strings.HasPrefix(pkgPath, modPath+"/")
+}
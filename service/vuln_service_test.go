@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScanVulnerabilities(t *testing.T) {
+	t.Setenv("GOFLAGS", "-mod=vendor")
+	dir := writeTestModule(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n\nrequire example.com/vuln v1.0.0\n",
+		"app.go": `package app
+
+import "example.com/vuln"
+
+func Run() {
+	helper()
+}
+
+func helper() {
+	vuln.Bad()
+}
+`,
+		"vendor/modules.txt":              "# example.com/vuln v1.0.0\n## explicit; go 1.21\nexample.com/vuln\n",
+		"vendor/example.com/vuln/vuln.go": "package vuln\n\nfunc Bad() {}\n\nfunc Unused() {}\n",
+		"vendor/example.com/vuln/go.mod":  "module example.com/vuln\n",
+		"osv/GO-2024-0001.json": `{"id":"GO-2024-0001","summary":"bad is bad","affected":[{"package":{"ecosystem":"Go","name":"example.com/vuln"},
+			"ranges":[{"type":"SEMVER","events":[{"introduced":"0"},{"fixed":"1.0.2"}]}],
+			"ecosystem_specific":{"imports":[{"path":"example.com/vuln","symbols":["Bad","Unused"]}]}}]}`,
+		"osv/GO-2024-0002.json": `{"id":"GO-2024-0002","affected":[{"package":{"ecosystem":"Go","name":"example.com/vuln"},
+			"ranges":[{"type":"SEMVER","events":[{"introduced":"0.1.0"},{"fixed":"0.9.0"}]}]}]}`,
+		"osv/GO-2024-0003.json": `{"id":"GO-2024-0003","affected":[{"package":{"ecosystem":"Go","name":"example.com/other"},
+			"ranges":[{"type":"SEMVER","events":[{"introduced":"0"}]}]}]}`,
+		"osv/index/db.json": `{"modified":"2024-01-01T00:00:00Z"}`,
+	})
+	entries, err := LoadOSVDatabase(dir + "/osv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 osv entries, got %d", len(entries))
+	}
+	modInfo, err := ParseModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := ScanVulnerabilities(context.Background(), modInfo, entries, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Findings) != 1 {
+		t.Fatalf("expected one finding, got %d", len(report.Findings))
+	}
+	finding := report.Findings[0]
+	if finding.OSV.ID != "GO-2024-0001" || finding.FixedVersion != "v1.0.2" || finding.Level != VulnLevelSymbol {
+		t.Errorf("unexpected finding: %+v", finding)
+	}
+	if len(finding.Reachable) != 1 || finding.Reachable[0].Symbol != "Bad" {
+		t.Fatalf("unexpected reachable symbols: %+v", finding.Reachable)
+	}
+	reachable := finding.Reachable[0]
+	if reachable.Entry == nil || reachable.Entry.Name != "Run" || len(reachable.CallStack) != 3 {
+		t.Errorf("unexpected call stack: %v entry %+v", reachable.CallStack, reachable.Entry)
+	}
+}
+
+func TestRequiredVersions(t *testing.T) {
+	for goVersion, want := range map[string]string{
+		"go1.21":      "v1.21.0",
+		"go1.21.3":    "v1.21.3",
+		"go1.21rc2":   "v1.21.0-rc.2",
+		"go1.22beta1": "v1.22.0-beta.1",
+		"gotip":       "",
+	} {
+		if got := goVersionToSemver(goVersion); got != want {
+			t.Errorf("goVersionToSemver(%s) = %q, want %q", goVersion, got, want)
+		}
+	}
+	versions, unmatched := requiredVersions(&ModuleInfo{
+		GoVersion: "1.21",
+		Toolchain: "go1.21rc2",
+		Requires: []Dependency{
+			{Path: "example.com/dep", Version: "v1.0.0"},
+			{Path: "example.com/forked", Version: "v1.0.0"},
+			{Path: "example.com/local", Version: "v0.0.0"},
+		},
+		Replaces: []ReplaceRule{
+			{OldPath: "example.com/forked", NewPath: "github.com/fork/forked", NewVersion: "v1.0.1"},
+			{OldPath: "example.com/local", NewPath: "../local"},
+		},
+	})
+	if len(versions) != 2 || versions["example.com/dep"] != "v1.0.0" || versions[OSVStdlibModule] != "v1.21.0-rc.2" {
+		t.Errorf("unexpected versions: %v", versions)
+	}
+	if len(unmatched) != 2 || unmatched[0].Module != "example.com/forked" || unmatched[1].Module != "example.com/local" {
+		t.Errorf("replaced deps should be reported as unmatched: %+v", unmatched)
+	}
+	if _, unmatched := requiredVersions(&ModuleInfo{Toolchain: "gotip"}); len(unmatched) != 1 || unmatched[0].Module != OSVStdlibModule {
+		t.Errorf("invalid go version should be reported as unmatched: %+v", unmatched)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"sort"
 	"strings"
 )
 
@@ -41,6 +42,8 @@ type FileFuncVisitor struct {
 
 type FuncInfo struct {
 	BaseAstInfo
+	Doc           string   // 函数注释
+	Imports       []string // 函数中引用到的导入包路径
 	Receiver      *VarInfo
 	Params        []*VarInfo
 	Results       []*VarInfo
@@ -51,14 +54,22 @@ type FuncInfo struct {
 
 type VarInfo struct {
 	BaseAstInfo
-	Type     string
-	Value    string
-	BaseType string
+	Type          string
+	Value         string
+	BaseType      string
+	Doc           string           // 包级常量和变量的注释
+	IsConst       bool             // 是否为常量
+	StartPosition *BaseAstPosition // 包级常量和变量的声明位置
 }
 
 type StructInfo struct {
 	BaseAstInfo
+	Doc           string   // 类型注释
+	Imports       []string // 类型定义中引用到的导入包路径
 	Fields        []*VarInfo
+	IsInterface   bool        // 是否为接口类型
+	Methods       []*FuncInfo // 接口中声明的方法，含完整签名
+	Embeds        []*VarInfo  // 接口中嵌入的其他接口或类型约束
 	StartPosition *BaseAstPosition
 	EndPosition   *BaseAstPosition
 }
@@ -89,7 +100,12 @@ func (f *FileFuncVisitor) Visit(node ast.Node) (w ast.Visitor) {
 		} else if n.Tok == token.CONST || n.Tok == token.VAR {
 			for _, spec := range n.Specs {
 				if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+					doc := valueSpec.Doc
+					if doc == nil && len(n.Specs) == 1 {
+						doc = n.Doc
+					}
 					for _, name := range valueSpec.Names {
+						position := f.FileSet.Position(name.Pos())
 						varInfo := &VarInfo{
 							BaseAstInfo: BaseAstInfo{
 								Name:      name.Name,
@@ -97,7 +113,15 @@ func (f *FileFuncVisitor) Visit(node ast.Node) (w ast.Visitor) {
 								Pkg:       f.Pkg,
 								Content:   string(f.FileBytes[valueSpec.Pos()-1 : valueSpec.End()-1]),
 							},
-							Type: f.parseExprTypeInfo(valueSpec.Type),
+							Type:    f.parseExprTypeInfo(valueSpec.Type),
+							Doc:     doc.Text(),
+							IsConst: n.Tok == token.CONST,
+							StartPosition: &BaseAstPosition{
+								RFilePath: f.RFilePath,
+								OffSet:    position.Offset,
+								Line:      position.Line,
+								Column:    position.Column,
+							},
 						}
 						f.FilePkgVars = append(f.FilePkgVars, varInfo)
 					}
@@ -108,6 +132,10 @@ func (f *FileFuncVisitor) Visit(node ast.Node) (w ast.Visitor) {
 			endPosition := f.FileSet.Position(n.End())
 			for _, spec := range n.Specs {
 				if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+					doc := typeSpec.Doc
+					if doc == nil {
+						doc = n.Doc
+					}
 					structInfo := &StructInfo{
 						BaseAstInfo: BaseAstInfo{
 							Name:      typeSpec.Name.Name,
@@ -115,6 +143,8 @@ func (f *FileFuncVisitor) Visit(node ast.Node) (w ast.Visitor) {
 							Pkg:       f.Pkg,
 							Content:   string(f.FileBytes[n.Pos()-1 : n.End()-1]),
 						},
+						Doc:     doc.Text(),
+						Imports: f.collectUsedImports(typeSpec),
 						StartPosition: &BaseAstPosition{
 							RFilePath: f.RFilePath,
 							OffSet:    startPosition.Offset,
@@ -132,6 +162,9 @@ func (f *FileFuncVisitor) Visit(node ast.Node) (w ast.Visitor) {
 						f.handleFileList(structType.Fields.List, func(varInfo *VarInfo) {
 							structInfo.Fields = append(structInfo.Fields, varInfo)
 						})
+					} else if interfaceType, ok := typeSpec.Type.(*ast.InterfaceType); ok {
+						structInfo.IsInterface = true
+						f.parseInterfaceMethods(interfaceType, structInfo)
 					}
 					f.FileStructs = append(f.FileStructs, structInfo)
 				}
@@ -179,6 +212,7 @@ func (f *FileFuncVisitor) parseAnonymousFuncInfo(funcLit *ast.FuncLit, parentFun
 			Column:    endPosition.Column,
 		},
 	}
+	funcInfo.Imports = f.collectUsedImports(funcLit)
 	if funcLit.Type.Params != nil {
 		f.handleFileList(funcLit.Type.Params.List, func(varInfo *VarInfo) {
 			funcInfo.Params = append(funcInfo.Params, varInfo)
@@ -202,6 +236,8 @@ func (f *FileFuncVisitor) parseNameFuncInfo(funcDecl *ast.FuncDecl) *FuncInfo {
 			Pkg:       f.Pkg,
 			Content:   string(f.FileBytes[startPosition.Offset:endPosition.Offset]),
 		},
+		Doc:     funcDecl.Doc.Text(),
+		Imports: f.collectUsedImports(funcDecl),
 		StartPosition: &BaseAstPosition{
 			RFilePath: f.RFilePath,
 			OffSet:    startPosition.Offset,
@@ -233,6 +269,78 @@ func (f *FileFuncVisitor) parseNameFuncInfo(funcDecl *ast.FuncDecl) *FuncInfo {
 	return funcInfo
 }
 
+// parseInterfaceMethods 解析接口中声明的方法和嵌入的类型
+func (f *FileFuncVisitor) parseInterfaceMethods(interfaceType *ast.InterfaceType, structInfo *StructInfo) {
+	for _, field := range interfaceType.Methods.List {
+		funcType, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			f.handleFileList([]*ast.Field{field}, func(varInfo *VarInfo) {
+				structInfo.Embeds = append(structInfo.Embeds, varInfo)
+			})
+			continue
+		}
+		startPosition := f.FileSet.Position(field.Pos())
+		endPosition := f.FileSet.Position(field.End())
+		methodInfo := &FuncInfo{
+			BaseAstInfo: BaseAstInfo{
+				Name:      field.Names[0].Name,
+				RFilePath: f.RFilePath,
+				Pkg:       f.Pkg,
+				Content:   string(f.FileBytes[startPosition.Offset:endPosition.Offset]),
+			},
+			Doc:     field.Doc.Text(),
+			Imports: f.collectUsedImports(field),
+			StartPosition: &BaseAstPosition{
+				RFilePath: f.RFilePath,
+				OffSet:    startPosition.Offset,
+				Line:      startPosition.Line,
+				Column:    startPosition.Column,
+			},
+			EndPosition: &BaseAstPosition{
+				RFilePath: f.RFilePath,
+				OffSet:    endPosition.Offset,
+				Line:      endPosition.Line,
+				Column:    endPosition.Column,
+			},
+		}
+		if funcType.Params != nil {
+			f.handleFileList(funcType.Params.List, func(varInfo *VarInfo) {
+				methodInfo.Params = append(methodInfo.Params, varInfo)
+			})
+		}
+		if funcType.Results != nil {
+			f.handleFileList(funcType.Results.List, func(varInfo *VarInfo) {
+				methodInfo.Results = append(methodInfo.Results, varInfo)
+			})
+		}
+		structInfo.Methods = append(structInfo.Methods, methodInfo)
+	}
+}
+
+// collectUsedImports 收集节点中通过 pkg.Sel 形式引用到的导入包路径
+func (f *FileFuncVisitor) collectUsedImports(node ast.Node) []string {
+	seen := make(map[string]bool)
+	imports := make([]string, 0)
+	ast.Inspect(node, func(n ast.Node) bool {
+		selectorExpr, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		// 局部变量会被解析出Obj，包名则不会
+		ident, ok := selectorExpr.X.(*ast.Ident)
+		if !ok || ident.Obj != nil {
+			return true
+		}
+		if pkgPath, ok := f.ImportPkgMap[ident.Name]; ok && !seen[pkgPath] {
+			seen[pkgPath] = true
+			imports = append(imports, pkgPath)
+		}
+		return true
+	})
+	sort.Strings(imports)
+	return imports
+}
+
 func (f *FileFuncVisitor) handleFileList(list []*ast.Field, handleFunc func(varInfo *VarInfo)) {
 	for _, field := range list {
 		baseTypeInfo := f.parseExprBaseType(field.Type)
@@ -291,16 +399,47 @@ func (f *FileFuncVisitor) parseExprTypeInfo(expr ast.Expr) string {
 	case *ast.StarExpr:
 		return "*" + f.parseExprTypeInfo(n.X)
 	case *ast.ArrayType:
+		if n.Len != nil {
+			return "[" + f.nodeSource(n.Len) + "]" + f.parseExprTypeInfo(n.Elt)
+		}
 		return "[]" + f.parseExprTypeInfo(n.Elt)
 	case *ast.MapType:
 		return "map[" + f.parseExprTypeInfo(n.Key) + "]" + f.parseExprTypeInfo(n.Value)
-	case *ast.FuncType:
-		return string(f.FileBytes[n.Pos()-1 : n.End()])
+	case *ast.ChanType:
+		switch n.Dir {
+		case ast.SEND:
+			return "chan<- " + f.parseExprTypeInfo(n.Value)
+		case ast.RECV:
+			return "<-chan " + f.parseExprTypeInfo(n.Value)
+		default:
+			return "chan " + f.parseExprTypeInfo(n.Value)
+		}
+	case *ast.Ellipsis:
+		return "..." + f.parseExprTypeInfo(n.Elt)
+	case *ast.ParenExpr:
+		return "(" + f.parseExprTypeInfo(n.X) + ")"
+	case *ast.IndexExpr:
+		return f.parseExprTypeInfo(n.X) + "[" + f.parseExprTypeInfo(n.Index) + "]"
+	case *ast.IndexListExpr:
+		indices := make([]string, 0, len(n.Indices))
+		for _, index := range n.Indices {
+			indices = append(indices, f.parseExprTypeInfo(index))
+		}
+		return f.parseExprTypeInfo(n.X) + "[" + strings.Join(indices, ", ") + "]"
+	case *ast.FuncType, *ast.InterfaceType, *ast.StructType:
+		return f.nodeSource(n)
 	default:
 		return ""
 	}
 }
 
+// nodeSource 返回节点对应的源码文本
+func (f *FileFuncVisitor) nodeSource(node ast.Node) string {
+	start := f.FileSet.Position(node.Pos()).Offset
+	end := f.FileSet.Position(node.End()).Offset
+	return string(f.FileBytes[start:end])
+}
+
 func (f *FileFuncVisitor) parseExprBaseType(expr ast.Expr) string {
 	switch n := expr.(type) {
 	case *ast.Ident:
@@ -313,8 +452,18 @@ func (f *FileFuncVisitor) parseExprBaseType(expr ast.Expr) string {
 		return f.parseExprBaseType(n.Elt)
 	case *ast.MapType:
 		return f.parseExprBaseType(n.Value)
+	case *ast.ChanType:
+		return f.parseExprBaseType(n.Value)
+	case *ast.Ellipsis:
+		return f.parseExprBaseType(n.Elt)
+	case *ast.ParenExpr:
+		return f.parseExprBaseType(n.X)
+	case *ast.IndexExpr:
+		return f.parseExprBaseType(n.X)
+	case *ast.IndexListExpr:
+		return f.parseExprBaseType(n.X)
 	case *ast.FuncType:
-		return string(f.FileBytes[n.Pos()-1 : n.End()])
+		return f.nodeSource(n)
 	default:
 		return ""
 	}